@@ -0,0 +1,306 @@
+package cbackend
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/printer"
+	"github.com/gearsdatapacks/libra/text"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// An alternative to the LLVM backend, which emits a single C11 source file
+// for a lowered package. This is mostly useful for debugging, and for
+// platforms which have a C compiler but aren't supported by LLVM.
+
+type generator struct {
+	// Type definitions, which must come before anything that uses them
+	typeDefs bytes.Buffer
+	// Prototypes for every function, so they can be called in any order
+	prototypes bytes.Buffer
+	globals    bytes.Buffer
+	functions  bytes.Buffer
+
+	namedTypes []namedType
+	typeNames  map[string]int
+	// The C names of functions, which may differ from their Libra names
+	fnNames map[string]string
+	fn      *fnContext
+
+	diagnostics diagnostics.Manager
+	// The location of the statement being compiled, where errors are reported
+	location text.Location
+}
+
+type fnContext struct {
+	// The C names of variables in the current function, as
+	// variables in the same function may shadow each other
+	variables map[string]string
+	declared  map[string]bool
+}
+
+const mainName = "libra_main"
+
+// Compiles a lowered package to C. Anything the C backend doesn't support
+// is reported as an internal compiler error, and the function or global
+// containing it is left out.
+func Compile(pkg *ir.LoweredPackage, diagnostics diagnostics.Manager) (string, diagnostics.Manager) {
+	g := &generator{
+		typeNames:   map[string]int{},
+		fnNames:     map[string]string{},
+		diagnostics: diagnostics,
+	}
+
+	hasMain := false
+	registered := map[*ir.FunctionDeclaration]bool{}
+	for _, kv := range printer.SortMap(pkg.Modules) {
+		for _, fn := range kv.Value.Functions {
+			registered[fn] = g.registerFn(kv.Value.Name, fn)
+			if fn.Name == "main" {
+				hasMain = true
+			}
+		}
+	}
+
+	for _, kv := range printer.SortMap(pkg.Modules) {
+		for _, global := range kv.Value.Globals {
+			g.compileGlobal(global)
+		}
+	}
+
+	for _, kv := range printer.SortMap(pkg.Modules) {
+		for _, fn := range kv.Value.Functions {
+			if registered[fn] {
+				g.compileFn(fn)
+			}
+		}
+	}
+
+	var output bytes.Buffer
+	output.WriteString("// Generated by the Libra compiler\n")
+	output.WriteString("#include <math.h>\n")
+	output.WriteString("#include <stdbool.h>\n")
+	output.WriteString("#include <stdint.h>\n")
+
+	if hasMain {
+		fmt.Fprintf(&g.functions, "int main(void) {\n\t%s();\n\treturn 0;\n}\n", mainName)
+	}
+
+	for _, section := range []*bytes.Buffer{&g.typeDefs, &g.prototypes, &g.globals, &g.functions} {
+		if section.Len() != 0 {
+			output.WriteByte('\n')
+			output.Write(bytes.TrimRight(section.Bytes(), "\n"))
+			output.WriteByte('\n')
+		}
+	}
+
+	return output.String(), g.diagnostics
+}
+
+// Recovers from an error in compiling part of the program, reporting
+// it and removing any code generated for that part from the buffer
+func (g *generator) recoverInto(buffer *bytes.Buffer, length int) {
+	if err := recover(); err != nil {
+		g.reportInternalError(err)
+		buffer.Truncate(length)
+		g.fn = nil
+	}
+}
+
+// Declares a function, returning whether it could be declared
+func (g *generator) registerFn(module string, fn *ir.FunctionDeclaration) (registered bool) {
+	g.location = fn.Location
+	defer g.recoverInto(&g.prototypes, g.prototypes.Len())
+
+	name := mangle(module, fn.Name)
+	if fn.Extern != nil {
+		name = *fn.Extern
+	} else if fn.Name == "main" {
+		// C's `main` has a different signature, so it calls ours instead
+		name = mainName
+	}
+	g.fnNames[fn.Name] = name
+
+	fmt.Fprintf(&g.prototypes, "%s;\n", g.signature(fn, name))
+	return true
+}
+
+// Libra functions are given C names prefixed with their module, so they
+// don't clash with each other or with functions from the C library
+func mangle(module, name string) string {
+	var prefix strings.Builder
+	for _, char := range path.Base(module) {
+		if char == '_' || (char >= 'a' && char <= 'z') ||
+			(char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') {
+			prefix.WriteRune(char)
+		} else {
+			prefix.WriteByte('_')
+		}
+	}
+
+	// The module of the main file is usually `.`
+	if trimmed := strings.Trim(prefix.String(), "_"); trimmed != "" {
+		return "libra_" + trimmed + "_" + name
+	}
+	return "libra_" + name
+}
+
+// Functions which aren't exported are only visible in the file
+func (g *generator) signature(fn *ir.FunctionDeclaration, name string) string {
+	static := ""
+	if fn.Extern == nil && !fn.Exported {
+		static = "static "
+	}
+
+	params := make([]string, 0, len(fn.Parameters))
+	for i, param := range fn.Parameters {
+		params = append(params, g.declaration(fn.Type.Parameters[i], identifier(param)))
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+
+	return static + g.declaration(
+		fn.Type.ReturnType,
+		fmt.Sprintf("%s(%s)", name, strings.Join(params, ", ")),
+	)
+}
+
+// Global variables are only visible in the file, as every
+// module is compiled into the same one
+func (g *generator) compileGlobal(global *ir.VariableDeclaration) {
+	g.location = global.Location
+	defer g.recoverInto(&g.globals, g.globals.Len())
+
+	declaration := g.declaration(global.Symbol.Type, identifier(global.Symbol.Name))
+	if global.Value == nil {
+		fmt.Fprintf(&g.globals, "static %s;\n", declaration)
+	} else {
+		fmt.Fprintf(&g.globals, "static %s = %s;\n", declaration, g.compileExpression(global.Value))
+	}
+}
+
+func (g *generator) compileFn(fn *ir.FunctionDeclaration) {
+	if fn.Extern != nil {
+		return
+	}
+
+	g.location = fn.Location
+	defer g.recoverInto(&g.functions, g.functions.Len())
+
+	g.fn = &fnContext{
+		variables: map[string]string{},
+		declared:  map[string]bool{},
+	}
+	for _, param := range fn.Parameters {
+		g.declareVariable(param)
+	}
+
+	fmt.Fprintf(&g.functions, "%s {\n", g.signature(fn, g.fnNames[fn.Name]))
+	for _, stmt := range fn.Body.Statements {
+		g.compileStatement(stmt)
+	}
+	g.functions.WriteString("}\n\n")
+
+	g.fn = nil
+}
+
+// Gives a variable a C name, which is different from any other variable
+// declared in the current function
+func (g *generator) declareVariable(name string) string {
+	cName := identifier(name)
+	for i := 1; g.fn.declared[cName]; i++ {
+		cName = fmt.Sprintf("%s_%d", identifier(name), i)
+	}
+	g.fn.declared[cName] = true
+	g.fn.variables[name] = cName
+	return cName
+}
+
+func (g *generator) line(format string, args ...any) {
+	g.functions.WriteByte('\t')
+	fmt.Fprintf(&g.functions, format, args...)
+	g.functions.WriteByte('\n')
+}
+
+func (g *generator) compileStatement(statement ir.Statement) {
+	if location := statement.GetLocation(); location.File != nil {
+		g.location = location
+	}
+
+	switch stmt := statement.(type) {
+	case *ir.VariableDeclaration:
+		var value string
+		if stmt.Value != nil {
+			value = g.compileExpression(stmt.Value)
+		}
+		name := g.declareVariable(stmt.Symbol.Name)
+		if stmt.Value == nil {
+			g.line("%s;", g.declaration(stmt.Symbol.Type, name))
+		} else {
+			g.line("%s = %s;", g.declaration(stmt.Symbol.Type, name), value)
+		}
+
+	case *ir.ReturnStatement:
+		if stmt.Value == nil {
+			g.line("return;")
+		} else {
+			g.line("return %s;", g.compileExpression(stmt.Value))
+		}
+
+	case *ir.Label:
+		// Labels must be followed by a statement, so an empty one is added
+		fmt.Fprintf(&g.functions, "%s:;\n", stmt.Name)
+	case *ir.Goto:
+		g.line("goto %s;", stmt.Label)
+	case *ir.GotoIf:
+		g.line("if (%s) goto %s;", g.compileExpression(stmt.Condition), stmt.Label)
+	case *ir.GotoUnless:
+		g.line("if (!%s) goto %s;", g.operand(stmt.Condition), stmt.Label)
+	case *ir.Branch:
+		g.line(
+			"if (%s) goto %s; else goto %s;",
+			g.compileExpression(stmt.Condition),
+			stmt.IfLabel,
+			stmt.ElseLabel,
+		)
+
+	case ir.Expression:
+		g.line("%s;", g.compileExpression(stmt))
+	default:
+		panic("Unreachable")
+	}
+}
+
+// Identifiers which would be valid in Libra, but are reserved in C
+var reserved = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true,
+	"continue": true, "default": true, "do": true, "double": true, "else": true,
+	"enum": true, "extern": true, "float": true, "for": true, "goto": true,
+	"if": true, "inline": true, "int": true, "long": true, "register": true,
+	"restrict": true, "return": true, "short": true, "signed": true,
+	"sizeof": true, "static": true, "struct": true, "switch": true,
+	"typedef": true, "union": true, "unsigned": true, "void": true,
+	"volatile": true, "while": true, "bool": true, "true": true, "false": true,
+	"NULL": true,
+}
+
+func identifier(name string) string {
+	if reserved[name] {
+		return name + "_"
+	}
+	return name
+}
+
+// Declares a value of the given type, such as a variable or a parameter
+func (g *generator) declaration(ty types.Type, name string) string {
+	typeName := g.typeName(ty)
+	if strings.HasSuffix(typeName, "*") {
+		return typeName + name
+	}
+	return typeName + " " + name
+}
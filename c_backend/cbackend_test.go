@@ -0,0 +1,152 @@
+package cbackend_test
+
+import (
+	"strings"
+	"testing"
+
+	cbackend "github.com/gearsdatapacks/libra/c_backend"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/symbols"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+func TestCBackend(t *testing.T) {
+	utils.MatchCSnaps(t,
+		`mut condition = true
+if condition {
+	let a = 10
+} else {
+	let b = 20
+}`,
+		`mut i = 0
+while i < 10 {
+	i++
+}`,
+		`fn add(a, b: i32): i32 {
+	mut result = a
+	mut counter = b
+	while true {
+		if counter == 0 {
+			return result
+		}
+		result++
+		counter--
+	}
+}`,
+		`let greeting = "Hello, \"world\"!\n"`,
+		"mut a = 1.5; let b = a * 2; let c: u64 = 9000000000",
+		`struct Point { x, y: f32 }
+fn length_squared(p: *Point): f32 {
+	return p.x * p.x + p.y * p.y
+}
+let point = Point { x: 3, y: 4 }
+let length = length_squared(&point)`,
+		`@untagged
+union Bits { int: i32, float: f32 }
+let bits: Bits = 1.5 -> f32
+let int = bits.int`,
+		`@extern print_int
+fn print(int: i32)
+fn main() {
+	print(1)
+}`,
+	)
+}
+
+const printC = `#include <stdint.h>
+#include <stdio.h>
+
+void print_int(int32_t value) {
+	printf("%d\n", value);
+}
+`
+
+func TestRunCBackend(t *testing.T) {
+	output := utils.RunCBackend(t, `@extern
+fn print_int(i: i32)
+
+fn add(a, b: i32): i32 {
+	mut result = a
+	mut counter = b
+	while true {
+		if counter == 0 {
+			return result
+		}
+		result++
+		counter--
+	}
+}
+
+mut i = 0
+while i < 3 {
+	print_int(add(i, 10))
+	i++
+}`, printC)
+	utils.AssertEq(t, output, "10\n11\n12\n")
+
+	output = utils.RunCBackend(t, `@extern
+fn print_int(i: i32)
+
+struct Rect { width, height: i32 }
+
+fn area(rect: Rect): i32 {
+	return rect.width * rect.height
+}
+
+mut rect = Rect { width: 3, height: 4 }
+print_int(area(rect))
+rect.width = rect.height * 2 + 2
+print_int(area(rect))`, printC)
+	utils.AssertEq(t, output, "12\n40\n")
+}
+
+func TestNameClashes(t *testing.T) {
+	// `sqrt` is also declared by the C library
+	output := utils.RunCBackend(t, `@extern
+fn print_int(i: i32)
+
+fn sqrt(x: i32): i32 {
+	return x - 6
+}
+
+pub fn double(x: i32): i32 {
+	return x * 2
+}
+
+print_int(sqrt(8))
+print_int(double(8))`, printC)
+	utils.AssertEq(t, output, "2\n16\n")
+}
+
+func TestUnsupported(t *testing.T) {
+	utils.MatchCErrors(t,
+		`mut a = "a"; let ab = a + "b"`,
+		"mut base = 2; let squared = base ** 2",
+		`fn _power(x: i32): i32 {
+	return x ** 2
+}
+
+fn _double(x: i32): i32 {
+	return x * 2
+}`,
+	)
+}
+
+func TestGlobals(t *testing.T) {
+	counter := &symbols.Variable{Name: "counter", IsMut: true, Type: types.I32}
+	ratio := &symbols.Variable{Name: "double", IsMut: true, Type: types.Float(64)}
+	pkg := &ir.LoweredPackage{Modules: map[string]*ir.LoweredModule{
+		"test": {
+			Name: "test",
+			Globals: []*ir.VariableDeclaration{
+				{Symbol: counter, Value: &ir.IntegerLiteral{Value: 10, DataType: types.I32}},
+				{Symbol: ratio},
+			},
+		},
+	}}
+
+	output, diags := cbackend.Compile(pkg, nil)
+	utils.AssertEq(t, len(diags), 0)
+	utils.Assert(t, strings.Contains(output, "static int32_t counter = 10;\nstatic double double_;\n"), output)
+}
@@ -0,0 +1,25 @@
+package cbackend
+
+import (
+	"fmt"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+)
+
+// An error in generating C code. These are caused by bugs or missing
+// features in the C backend, not by the program being compiled, so
+// they are reported as internal compiler errors.
+type internalError string
+
+func unsupported(feature string) internalError {
+	return internalError(fmt.Sprintf("C code generation for %s is not implemented yet", feature))
+}
+
+// Reports an error at the location of the statement currently being compiled
+func (g *generator) reportInternalError(err any) {
+	message, ok := err.(internalError)
+	if !ok {
+		message = internalError(fmt.Sprint(err))
+	}
+	g.diagnostics.Report(diagnostics.InternalError(g.location, string(message)))
+}
@@ -0,0 +1,366 @@
+package cbackend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+func (g *generator) compileExpression(expression ir.Expression) string {
+	switch expr := expression.(type) {
+	case *ir.ArrayExpression:
+		elements := make([]string, 0, len(expr.Elements))
+		for _, element := range expr.Elements {
+			elements = append(elements, g.compileExpression(element))
+		}
+		return fmt.Sprintf("(%s){{%s}}", g.typeName(expr.DataType), strings.Join(elements, ", "))
+
+	case *ir.Assignment:
+		return fmt.Sprintf(
+			"%s = %s",
+			g.compileExpression(expr.Assignee),
+			g.compileExpression(expr.Value),
+		)
+
+	case *ir.BinaryExpression:
+		return g.compileBinaryExpression(expr)
+	case *ir.BooleanLiteral:
+		return strconv.FormatBool(expr.Value)
+	case *ir.Conversion:
+		// Numbers can't be formatted without allocating a string at runtime
+		_, isNumber := types.Unwrap(expr.Expression.Type()).(types.Numeric)
+		if isNumber && types.Unwrap(expr.To) == types.String {
+			panic(unsupported("converting numbers to strings"))
+		}
+		return fmt.Sprintf("(%s)%s", g.typeName(expr.To), g.operand(expr.Expression))
+	case *ir.DerefExpression:
+		return "*" + g.operand(expr.Value)
+	case *ir.FloatLiteral:
+		return floatLiteral(expr.Value, expr.Type())
+
+	case *ir.FunctionCall:
+		args := make([]string, 0, len(expr.Arguments))
+		for _, arg := range expr.Arguments {
+			args = append(args, g.compileExpression(arg))
+		}
+		return fmt.Sprintf("%s(%s)", g.operand(expr.Function), strings.Join(args, ", "))
+
+	case *ir.FunctionExpression:
+		panic(unsupported("function expressions"))
+
+	case *ir.IndexExpression:
+		if _, ok := types.Unwrap(expr.Left.Type()).(*types.ArrayType); ok {
+			return fmt.Sprintf(
+				"%s.items[%s]",
+				g.operand(expr.Left),
+				g.compileExpression(expr.Index),
+			)
+		}
+		panic(unsupported(fmt.Sprintf("indexing %s", expr.Left.Type().String())))
+
+	case *ir.IntegerLiteral:
+		if bitWidth(expr.Type()) > 32 {
+			return fmt.Sprintf("INT64_C(%d)", expr.Value)
+		}
+		return strconv.FormatInt(expr.Value, 10)
+	case *ir.UintLiteral:
+		if bitWidth(expr.Type()) > 32 {
+			return fmt.Sprintf("UINT64_C(%d)", expr.Value)
+		}
+		return strconv.FormatUint(expr.Value, 10) + "u"
+
+	case *ir.MapExpression:
+		panic(unsupported("map expressions"))
+
+	case *ir.MemberExpression:
+		if expr.Left.Type() == types.RuntimeType {
+			panic(unsupported(fmt.Sprintf("members of %s", expr.Left.Type().String())))
+		}
+		// Members of pointers are accessed through the pointer
+		access := "."
+		if _, ok := types.Unwrap(expr.Left.Type()).(*types.Pointer); ok {
+			access = "->"
+		}
+		member := identifier(expr.Member)
+		if _, err := strconv.Atoi(expr.Member); err == nil {
+			member = "_" + expr.Member
+		}
+		return g.operand(expr.Left) + access + member
+
+	case *ir.RefExpression:
+		if isLValue(expr.Value) || isCompoundLiteral(expr.Value) {
+			return "&" + g.operand(expr.Value)
+		}
+		// Compound literals are lvalues, so they can be used to
+		// create a pointer to a temporary value
+		return fmt.Sprintf(
+			"&(%s){%s}",
+			g.typeName(expr.Value.Type()),
+			g.compileExpression(expr.Value),
+		)
+
+	case *ir.StringLiteral:
+		return stringLiteral(expr.Value)
+
+	case *ir.StructExpression:
+		structType := types.Unwrap(expr.Struct).(*types.Struct)
+		fields := make([]string, 0, len(expr.Fields))
+		for _, name := range structType.FieldOrder {
+			fields = append(fields, fmt.Sprintf(
+				".%s = %s",
+				identifier(name),
+				g.compileExpression(expr.Fields[name]),
+			))
+		}
+		return fmt.Sprintf("(%s){%s}", g.typeName(expr.Struct), strings.Join(fields, ", "))
+
+	case *ir.TupleExpression:
+		return g.compileAggregate(expr.DataType, expr.Values)
+	case *ir.TupleStructExpression:
+		return g.compileAggregate(expr.Struct, expr.Fields)
+
+	case *ir.TypeCheck:
+		panic(unsupported("type checks"))
+	case *ir.TypeExpression:
+		panic(unsupported("type expressions"))
+
+	case *ir.UnaryExpression:
+		return g.compileUnaryExpression(expr)
+	case *ir.VariableExpression:
+		// Globals can be used outside of a function, in the values of other globals
+		if g.fn != nil {
+			if name, ok := g.fn.variables[expr.Symbol.Name]; ok {
+				return name
+			}
+		}
+		if name, ok := g.fnNames[expr.Symbol.Name]; ok {
+			return name
+		}
+		return identifier(expr.Symbol.Name)
+
+	case *ir.BitCast:
+		return g.compileBitCast(expr)
+	default:
+		panic(fmt.Sprintf("Unexpected expression type: %T", expression))
+	}
+}
+
+// Compiles an expression which is used as part of a larger one,
+// adding parentheses if they might be needed
+func (g *generator) operand(expr ir.Expression) string {
+	switch expr.(type) {
+	case *ir.BinaryExpression, *ir.Assignment, *ir.Conversion,
+		*ir.UnaryExpression, *ir.DerefExpression, *ir.RefExpression:
+		return "(" + g.compileExpression(expr) + ")"
+	default:
+		return g.compileExpression(expr)
+	}
+}
+
+func isLValue(expr ir.Expression) bool {
+	switch expr := expr.(type) {
+	case *ir.VariableExpression, *ir.DerefExpression:
+		return true
+	case *ir.MemberExpression:
+		return isLValue(expr.Left)
+	case *ir.IndexExpression:
+		return isLValue(expr.Left)
+	default:
+		return false
+	}
+}
+
+// Expressions which are compiled to compound literals
+func isCompoundLiteral(expr ir.Expression) bool {
+	switch expr.(type) {
+	case *ir.ArrayExpression, *ir.StructExpression,
+		*ir.TupleExpression, *ir.TupleStructExpression:
+		return true
+	default:
+		return false
+	}
+}
+
+func (g *generator) compileAggregate(ty types.Type, fields []ir.Expression) string {
+	values := make([]string, 0, len(fields))
+	for _, field := range fields {
+		values = append(values, g.compileExpression(field))
+	}
+	return fmt.Sprintf("(%s){%s}", g.typeName(ty), strings.Join(values, ", "))
+}
+
+// Untagged unions are C unions, so converting to or from one
+// just uses the member with the right type
+func (g *generator) compileBitCast(expr *ir.BitCast) string {
+	if union, ok := types.Unwrap(expr.To).(*types.Union); ok && union.Untagged {
+		member := unionMember(union, expr.Value.Type())
+		return fmt.Sprintf(
+			"(%s){.%s = %s}",
+			g.typeName(expr.To),
+			member,
+			g.compileExpression(expr.Value),
+		)
+	}
+	if union, ok := types.Unwrap(expr.Value.Type()).(*types.Union); ok && union.Untagged {
+		return g.operand(expr.Value) + "." + unionMember(union, expr.To)
+	}
+	panic(unsupported(fmt.Sprintf(
+		"bit casts from %s to %s",
+		expr.Value.Type().String(),
+		expr.To.String(),
+	)))
+}
+
+func unionMember(union *types.Union, ty types.Type) string {
+	for _, name := range union.MemberNames() {
+		var member types.Type = union.Members[name]
+		if variant, ok := member.(*types.UnionVariant); ok {
+			member = variant.Type
+		}
+		if types.Match(member, ty) {
+			return identifier(name)
+		}
+	}
+	panic("Unreachable")
+}
+
+func (g *generator) compileBinaryExpression(binExpr *ir.BinaryExpression) string {
+	left := g.operand(binExpr.Left)
+	right := g.operand(binExpr.Right)
+
+	var op string
+	switch binExpr.Operator.Id {
+	case ir.AddFloat, ir.AddInt:
+		op = "+"
+	case ir.BitwiseAnd:
+		op = "&"
+	case ir.BitwiseXor:
+		op = "^"
+	case ir.BitwiseOr:
+		op = "|"
+	case ir.Concat:
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
+	case ir.Divide:
+		// Division always produces a float
+		return fmt.Sprintf("(%s)%s / %s", g.typeName(binExpr.Type()), left, right)
+	case ir.Equal:
+		op = "=="
+	case ir.Greater:
+		op = ">"
+	case ir.GreaterEq:
+		op = ">="
+	case ir.LeftShift:
+		op = "<<"
+	case ir.Less:
+		op = "<"
+	case ir.LessEq:
+		op = "<="
+	case ir.LogicalAnd:
+		op = "&&"
+	case ir.LogicalOr:
+		op = "||"
+	case ir.ModuloFloat:
+		return fmt.Sprintf("fmod(%s, %s)", left, right)
+	case ir.ModuloInt:
+		op = "%"
+	case ir.MultiplyFloat, ir.MultiplyInt:
+		op = "*"
+	case ir.NotEqual:
+		op = "!="
+	case ir.PowerFloat:
+		return fmt.Sprintf("pow(%s, %s)", left, right)
+	case ir.PowerInt:
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
+	case ir.ArithmeticRightShift:
+		op = ">>"
+	case ir.LogicalRightShift:
+		// Shifting an unsigned value fills the top bits with zeros
+		width := bitWidth(binExpr.Left.Type())
+		return fmt.Sprintf("(uint%d_t)%s >> %s", width, left, right)
+	case ir.SubtractFloat, ir.SubtractInt:
+		op = "-"
+	case ir.Union:
+		panic("Unreachable")
+	default:
+		panic("Unreachable")
+	}
+
+	return fmt.Sprintf("%s %s %s", left, op, right)
+}
+
+func (g *generator) compileUnaryExpression(unExpr *ir.UnaryExpression) string {
+	operand := g.operand(unExpr.Operand)
+
+	switch unExpr.Operator.Id {
+	case ir.BitwiseNot:
+		return "~" + operand
+	case ir.CrashError:
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
+	case ir.DecrementInt, ir.DecrementFloat:
+		return operand + "--"
+	case ir.IncrementInt, ir.IncrementFloat:
+		return operand + "++"
+	case ir.LogicalNot:
+		return "!" + operand
+	case ir.NegateFloat, ir.NegateInt:
+		return "-" + operand
+	case ir.PropagateError:
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
+	default:
+		panic("Unreachable")
+	}
+}
+
+func floatLiteral(value float64, ty types.Type) string {
+	if math.IsNaN(value) {
+		return "NAN"
+	}
+	if math.IsInf(value, 1) {
+		return "INFINITY"
+	}
+	if math.IsInf(value, -1) {
+		return "-INFINITY"
+	}
+
+	text := strconv.FormatFloat(value, 'g', -1, 64)
+	// Make sure the literal isn't parsed as an integer
+	if !strings.ContainsAny(text, ".e") {
+		text += ".0"
+	}
+	if bitWidth(ty) == 32 {
+		text += "f"
+	}
+	return text
+}
+
+func stringLiteral(value string) string {
+	var text strings.Builder
+	text.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch char := value[i]; char {
+		case '"', '\\':
+			text.WriteByte('\\')
+			text.WriteByte(char)
+		case '\n':
+			text.WriteString("\\n")
+		case '\t':
+			text.WriteString("\\t")
+		case '\r':
+			text.WriteString("\\r")
+		default:
+			if char >= ' ' && char <= '~' {
+				text.WriteByte(char)
+			} else {
+				// Octal escapes are at most three digits long, so unlike
+				// hex escapes they can't swallow the following characters
+				fmt.Fprintf(&text, "\\%03o", char)
+			}
+		}
+	}
+	text.WriteByte('"')
+	return text.String()
+}
@@ -0,0 +1,151 @@
+package cbackend
+
+import (
+	"fmt"
+
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+type namedType struct {
+	ty   types.Type
+	name string
+}
+
+// The name of the C type used to represent a type, emitting
+// a definition for it if it hasn't been used yet
+func (g *generator) typeName(ty types.Type) string {
+	if ty == types.Void {
+		return "void"
+	}
+
+	switch ty := types.Unwrap(ty).(type) {
+	case types.PrimaryType:
+		switch ty {
+		case types.Bool:
+			return "bool"
+		case types.String:
+			// TODO: Use proper strings, not cstrings
+			return "const char *"
+		}
+
+	case types.Numeric:
+		switch ty.Kind {
+		case types.NumInt:
+			return fmt.Sprintf("int%d_t", ty.BitWidth)
+		case types.NumUint:
+			return fmt.Sprintf("uint%d_t", ty.BitWidth)
+		case types.NumFloat:
+			switch ty.BitWidth {
+			case 32:
+				return "float"
+			case 64:
+				return "double"
+			}
+		}
+
+	case *types.Pointer:
+		name := g.typeName(ty.Underlying)
+		if name[len(name)-1] == '*' {
+			return name + "*"
+		}
+		return name + " *"
+
+	case *types.Enum:
+		return g.typeName(ty.Underlying)
+
+	case *types.Struct, *types.TupleStruct, *types.TupleType, *types.ArrayType:
+		return g.aggregateName(ty)
+
+	case *types.Union:
+		if ty.Untagged {
+			return g.aggregateName(ty)
+		}
+	}
+
+	panic(unsupported(fmt.Sprintf("values of type %s", ty.String())))
+}
+
+func (g *generator) aggregateName(ty types.Type) string {
+	for _, named := range g.namedTypes {
+		if types.Match(named.ty, ty) {
+			return named.name
+		}
+	}
+
+	var keyword, name string
+	var fields []string
+	var fieldTypes []types.Type
+
+	switch ty := ty.(type) {
+	case *types.Struct:
+		keyword, name = "struct", ty.Name
+		for _, field := range ty.FieldOrder {
+			fields = append(fields, identifier(field))
+			fieldTypes = append(fieldTypes, ty.Fields[field].Type)
+		}
+	case *types.TupleStruct:
+		keyword, name = "struct", ty.Name
+		fieldTypes = ty.Types
+	case *types.TupleType:
+		keyword, name = "struct", "tuple"
+		fieldTypes = ty.Types
+	case *types.Union:
+		keyword, name = "union", ty.Name
+		for _, member := range ty.MemberNames() {
+			var memberType types.Type = ty.Members[member]
+			if variant, ok := memberType.(*types.UnionVariant); ok {
+				memberType = variant.Type
+			}
+			fields = append(fields, identifier(member))
+			fieldTypes = append(fieldTypes, memberType)
+		}
+	case *types.ArrayType:
+		// Arrays are wrapped in a struct so they can be passed by value
+		keyword, name = "struct", "array"
+		fields = []string{fmt.Sprintf("items[%d]", ty.Length)}
+		fieldTypes = []types.Type{ty.ElemType}
+	}
+
+	// Unnamed types and types with the same name are given unique names
+	if count := g.typeNames[name]; count != 0 || name == "tuple" || name == "array" {
+		g.typeNames[name]++
+		name = fmt.Sprintf("%s%d", name, count)
+	} else {
+		g.typeNames[name]++
+	}
+	name = keyword + " " + name
+	// The type is registered before its fields are, so that
+	// it can contain pointers to itself
+	g.namedTypes = append(g.namedTypes, namedType{ty: ty, name: name})
+
+	// Field types are defined first, so that they are complete
+	// by the time this type's definition is reached
+	definitions := make([]string, 0, len(fieldTypes))
+	for i, fieldType := range fieldTypes {
+		field := fmt.Sprintf("_%d", i)
+		if fields != nil {
+			field = fields[i]
+		}
+		definitions = append(definitions, g.declaration(fieldType, field))
+	}
+
+	fmt.Fprintf(&g.typeDefs, "%s {\n", name)
+	for _, definition := range definitions {
+		fmt.Fprintf(&g.typeDefs, "\t%s;\n", definition)
+	}
+	g.typeDefs.WriteString("};\n\n")
+
+	return name
+}
+
+// The width of a numeric type, which doesn't depend on the
+// target, unlike the sizes of other types
+func bitWidth(ty types.Type) int {
+	switch ty := types.Unwrap(ty).(type) {
+	case types.Numeric:
+		return ty.BitWidth
+	case *types.Enum:
+		return bitWidth(ty.Underlying)
+	}
+	panic(fmt.Sprintf("%s is not a numeric type", ty.String()))
+}
@@ -0,0 +1,100 @@
+package codegen
+
+import (
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"tinygo.org/x/go-llvm"
+)
+
+func (c *compiler) compileFunctionCall(call *ir.FunctionCall, used bool) value {
+	callee := c.compileExpression(call.Function, true).toRValue(c)
+	args := make([]llvm.Value, 0, len(call.Arguments)+1)
+
+	var returnPointer llvm.Value
+	if call.Abi.Return.Kind == ir.PassIndirect {
+		returnPointer = c.builder.CreateAlloca(c.llvmType(call.ReturnType), "sret")
+		args = append(args, returnPointer)
+	}
+
+	for i, arg := range call.Arguments {
+		value := c.compileExpression(arg, true)
+
+		switch param := call.Abi.Parameters[i]; {
+		case param.Kind != ir.PassIndirect:
+			args = append(args, value.toRValue(c))
+		case param.ByVal:
+			// LLVM copies `byval` arguments itself
+			args = append(args, value.toRef(c))
+		default:
+			// The callee owns the copy, so it can modify it
+			args = append(args, llvmValue(value.toRValue(c)).toRef(c))
+		}
+	}
+
+	var name string
+	if call.Abi.Return.AbiType != types.Void && used {
+		name = "call_tmp"
+	}
+	result := c.builder.CreateCall(callee.GlobalValueType(), callee, args, name)
+	c.addAbiAttributes(call.Abi, result.AddCallSiteAttribute)
+
+	switch call.Abi.Return.Kind {
+	case ir.PassIndirect:
+		return stackVariable(returnPointer)
+	case ir.PassCoerced:
+		return c.bitCast(llvmValue(result), call.Abi.Return.AbiType, call.Abi.Return.Type)
+	default:
+		return llvmValue(result)
+	}
+}
+
+func (c *compiler) compileReturn(value llvm.Value) {
+	info := c.table.context.abi.Return
+
+	switch info.Kind {
+	case ir.PassIndirect:
+		c.builder.CreateStore(value, c.table.context.returnPointer)
+		c.builder.CreateRetVoid()
+	case ir.PassCoerced:
+		c.builder.CreateRet(c.bitCast(llvmValue(value), info.Type, info.AbiType).toRValue(c))
+	default:
+		c.builder.CreateRet(value)
+	}
+}
+
+// Converts a parameter passed according to the ABI back to its original type
+func (c *compiler) fromAbi(param llvm.Value, info ir.PassInfo) value {
+	switch info.Kind {
+	case ir.PassIndirect:
+		return deref{value: param, ty: c.llvmType(info.Type)}
+	case ir.PassCoerced:
+		return c.bitCast(llvmValue(param), info.AbiType, info.Type)
+	default:
+		return llvmValue(param)
+	}
+}
+
+// Adds the attributes which tell LLVM that a value is passed in memory,
+// either to a function declaration or to a call
+func (c *compiler) addAbiAttributes(abi *ir.FunctionAbi, addAttribute func(int, llvm.Attribute)) {
+	// Index 0 is the return value, so parameters start at 1
+	offset := 1
+	if abi.Return.Kind == ir.PassIndirect {
+		addAttribute(offset, c.typeAttribute("sret", abi.Return.Type))
+		offset++
+	}
+
+	for i, param := range abi.Parameters {
+		if param.Kind == ir.PassIndirect && param.ByVal {
+			addAttribute(i+offset, c.typeAttribute("byval", param.Type))
+			addAttribute(i+offset, c.context.CreateEnumAttribute(
+				llvm.AttributeKindID("align"),
+				uint64(max(types.Alignment(param.Type, c.target), 8)),
+			))
+		}
+	}
+}
+
+func (c *compiler) typeAttribute(name string, ty types.Type) llvm.Attribute {
+	return c.context.CreateTypeAttribute(llvm.AttributeKindID(name), c.llvmType(ty))
+}
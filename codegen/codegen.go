@@ -2,7 +2,10 @@ package codegen
 
 import (
 	"fmt"
+	"slices"
 
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/text"
 	"github.com/gearsdatapacks/libra/type_checker/ir"
 	"github.com/gearsdatapacks/libra/type_checker/types"
 	"tinygo.org/x/go-llvm"
@@ -12,63 +15,144 @@ type compiler struct {
 	context llvm.Context
 	mainModule,
 	currentModule llvm.Module
-	builder llvm.Builder
-	table   *table
+	builder   llvm.Builder
+	table     *table
+	typeInfos []typeInfo
+	methods   []*ir.FunctionDeclaration
+	target    types.TargetInfo
+	// Nil if debug information is disabled
+	debug       *debugInfo
+	diagnostics diagnostics.Manager
+	// The location of the node currently being compiled, used
+	// to report errors which happen during code generation
+	location text.Location
 }
 
-func Compile(pkg *ir.LoweredPackage) llvm.Module {
+// Compiles a lowered package to an LLVM module, optionally
+// including DWARF debug information
+func Compile(
+	pkg *ir.LoweredPackage,
+	target types.TargetInfo,
+	debugInfo bool,
+	diagnostics diagnostics.Manager,
+) (llvm.Module, diagnostics.Manager) {
 	context := llvm.NewContext()
 	compiler := &compiler{
-		context:    context,
-		mainModule: context.NewModule("main"),
-		builder:    context.NewBuilder(),
-		table:      newTable(),
+		context:     context,
+		mainModule:  context.NewModule("main"),
+		builder:     context.NewBuilder(),
+		table:       newTable(),
+		target:      target,
+		diagnostics: diagnostics,
+	}
+	if debugInfo {
+		compiler.debug = newDebugInfo()
+	}
+
+	for _, mod := range pkg.Modules {
+		for _, fn := range mod.Functions {
+			if fn.MethodOf != nil {
+				compiler.methods = append(compiler.methods, fn)
+			}
+		}
 	}
 
 	for _, mod := range pkg.Modules {
 		compiler.currentModule = compiler.context.NewModule(mod.Name)
+		compiler.typeInfos = []typeInfo{}
 		// TODO: Codegen globals and types
 
+		registered := make([]*ir.FunctionDeclaration, 0, len(mod.Functions))
 		for _, fn := range mod.Functions {
-			compiler.registerFn(fn)
+			if compiler.registerFn(fn) {
+				registered = append(registered, fn)
+			}
 		}
 
-		for _, fn := range mod.Functions {
+		for _, fn := range registered {
 			compiler.compileFn(fn)
 		}
-
-		err := llvm.LinkModules(compiler.mainModule, compiler.currentModule)
-		if err != nil {
-			// TODO: Don't crash here
-			panic(err)
+		if compiler.debug != nil {
+			compiler.finaliseDebugInfo()
 		}
+
+		compiler.verifyModule(mod.Functions)
+		compiler.linkModule()
+	}
+
+	if target.IsWasi() {
+		compiler.compileWasiRuntime()
+	}
+	if compiler.debug != nil {
+		compiler.addDebugInfoFlags()
 	}
 
-	return compiler.mainModule
+	return compiler.mainModule, compiler.diagnostics
 }
 
-func (c *compiler) registerFn(fn *ir.FunctionDeclaration) {
-	paramTypes := make([]llvm.Type, 0, len(fn.Parameters))
+// Declares a function so it can be called before it is compiled, returning
+// whether it could be. Functions which can't be declared are reported and
+// then skipped.
+func (c *compiler) registerFn(fn *ir.FunctionDeclaration) (ok bool) {
+	c.location, _ = functionLocation(fn)
+	defer func() {
+		if err := recover(); err != nil {
+			c.reportInternalError(err)
+			c.table.addValue(fn.Name, failedFunction{})
+			ok = false
+		}
+	}()
+
+	paramTypes := make([]llvm.Type, 0, len(fn.Parameters)+1)
+	// Values returned in memory are written to a pointer passed as the first argument
+	if fn.Abi.Return.Kind == ir.PassIndirect {
+		paramTypes = append(paramTypes, llvm.PointerType(c.llvmType(fn.Abi.Return.Type), 0))
+	}
 	for _, param := range fn.Type.Parameters {
-		paramTypes = append(paramTypes, param.ToLlvm(c.context))
+		paramTypes = append(paramTypes, c.llvmType(param))
 	}
 	var retTy llvm.Type
 	if fn.Type.ReturnType == types.Void {
 		retTy = c.context.VoidType()
 	} else {
-		retTy = fn.Type.ReturnType.ToLlvm(c.context)
+		retTy = c.llvmType(fn.Type.ReturnType)
 	}
 	ty := llvm.FunctionType(retTy, paramTypes, false)
-	name := fn.Name
-	if fn.Extern != nil {
-		name = *fn.Extern
+	name := c.functionName(fn)
+	var function llvm.Value
+	if (fn.ImportModule != nil || fn.ImportName != nil) && c.target.IsWasm() {
+		// Like in C, functions are imported from `env` by default
+		importModule, importName := "env", name
+		if fn.ImportModule != nil {
+			importModule = *fn.ImportModule
+		}
+		if fn.ImportName != nil {
+			importName = *fn.ImportName
+		}
+		function = importFunction(c.currentModule, importModule, importName, name, ty)
+	} else {
+		function = llvm.AddFunction(c.currentModule, name, ty)
+	}
+	// Only exported functions can be used from outside
+	// the library or executable they are compiled into
+	if fn.Extern == nil && !fn.Exported && name != "main" {
+		function.SetVisibility(llvm.HiddenVisibility)
 	}
-	function := llvm.AddFunction(c.currentModule, name, ty)
-	// function.SetLinkage(llvm.ExternalLinkage)
-	for i, param := range function.Params() {
+	params := function.Params()
+	for i, param := range params[len(params)-len(fn.Parameters):] {
 		param.SetName(fn.Parameters[i])
 	}
+	c.addAbiAttributes(fn.Abi, function.AddAttributeAtIndex)
 	c.table.addValue(fn.Name, llvmValue(function))
+	return true
+}
+
+// The name of the symbol a function is compiled to
+func (c *compiler) functionName(fn *ir.FunctionDeclaration) string {
+	if fn.Extern != nil {
+		return *fn.Extern
+	}
+	return fn.Name
 }
 
 func (c *compiler) compileFn(fn *ir.FunctionDeclaration) {
@@ -77,13 +161,24 @@ func (c *compiler) compileFn(fn *ir.FunctionDeclaration) {
 	}
 
 	function := c.table.getValue(fn.Name).toRValue(c)
+	table := c.table
+	c.location, _ = functionLocation(fn)
+
+	// If anything goes wrong, the error is reported and the rest
+	// of the function is skipped, so other errors can be found
+	defer func() {
+		if err := recover(); err != nil {
+			c.reportInternalError(err)
+			removeBody(function)
+			c.table = table
+			c.builder.SetCurrentDebugLocation(0, 0, llvm.Metadata{}, llvm.Metadata{})
+		}
+	}()
+
 	c.table = childTable(c.table)
 	c.table.context = &fnContext{
 		blocks: map[string]llvm.BasicBlock{},
-	}
-
-	for i, param := range function.Params() {
-		c.table.addValue(fn.Parameters[i], llvmValue(param))
+		abi:    fn.Abi,
 	}
 
 	for _, stmt := range fn.Body.Statements {
@@ -93,19 +188,39 @@ func (c *compiler) compileFn(fn *ir.FunctionDeclaration) {
 		}
 	}
 
+	// Parameters which were changed to match the ABI are converted back
+	// to their original types in the entry block
+	if function.BasicBlocksCount() != 0 {
+		c.builder.SetInsertPointAtEnd(function.EntryBasicBlock())
+	}
+	c.beginFunctionDebugInfo(fn, function)
+
+	params := function.Params()
+	if fn.Abi.Return.Kind == ir.PassIndirect {
+		c.table.context.returnPointer = params[0]
+		params = params[1:]
+	}
+	for i, param := range params {
+		value := c.fromAbi(param, fn.Abi.Parameters[i])
+		c.table.addValue(fn.Parameters[i], value)
+		c.declareDebugVariable(fn.Parameters[i], fn.Abi.Parameters[i].Type, value, text.Location{}, i+1)
+	}
+
 	for _, stmt := range fn.Body.Statements {
 		c.compileStatement(stmt)
 	}
 
+	c.builder.SetCurrentDebugLocation(0, 0, llvm.Metadata{}, llvm.Metadata{})
 	c.table = c.table.parent
-	// TODO: Don't crash here
-	// llvm.VerifyFunction(function, llvm.AbortProcessAction)
 }
 
 func (c *compiler) compileStatement(statement ir.Statement) {
+	c.setLocation(statement.GetLocation())
+
 	switch stmt := statement.(type) {
 	case *ir.VariableDeclaration:
-		alloca := c.builder.CreateAlloca(stmt.Symbol.Type.ToLlvm(c.context), stmt.Symbol.Name)
+		alloca := c.builder.CreateAlloca(c.llvmType(stmt.Symbol.Type), stmt.Symbol.Name)
+		c.declareDebugVariable(stmt.Symbol.Name, stmt.Symbol.Type, stackVariable(alloca), stmt.Location, 0)
 		if stmt.Value != nil {
 			value := c.compileExpression(stmt.Value, true).toRValue(c)
 			c.builder.CreateStore(value, alloca)
@@ -117,7 +232,7 @@ func (c *compiler) compileStatement(statement ir.Statement) {
 			c.builder.CreateRetVoid()
 		} else {
 			value := c.compileExpression(stmt.Value, true).toRValue(c)
-			c.builder.CreateRet(value)
+			c.compileReturn(value)
 		}
 
 	case *ir.Label:
@@ -135,14 +250,36 @@ func (c *compiler) compileStatement(statement ir.Statement) {
 	case ir.Expression:
 		c.compileExpression(stmt, false)
 	default:
-		panic("Unreachable")
+		panic(internalError(fmt.Sprintf("Unexpected statement type: %T", statement)))
+	}
+}
+
+// Sets the location of the node being compiled. Lowered nodes
+// created by the compiler may not have a location, in which case
+// the location of the enclosing node is used.
+func (c *compiler) setLocation(location text.Location) {
+	if location.File != nil {
+		c.location = location
 	}
+	c.setDebugLocation(location)
 }
 
 func (c *compiler) compileExpression(expression ir.Expression, used bool) value {
+	// The location isn't restored if compilation panics, so that
+	// the error is reported at the innermost expression
+	previous := c.location
+	if location := expression.GetLocation(); location.File != nil {
+		c.location = location
+	}
+	value := c.compileExpressionNode(expression, used)
+	c.location = previous
+	return value
+}
+
+func (c *compiler) compileExpressionNode(expression ir.Expression, used bool) value {
 	switch expr := expression.(type) {
 	case *ir.ArrayExpression:
-		panic("TODO")
+		panic(unsupported("array expressions"))
 	case *ir.Assignment:
 		lValue := c.compileExpression(expr.Assignee, true).toLValue()
 		rValue := c.compileExpression(expr.Value, true).toRValue(c)
@@ -164,47 +301,46 @@ func (c *compiler) compileExpression(expression ir.Expression, used bool) value
 		}
 		return llvmValue(llvm.ConstInt(c.context.Int1Type(), value, false))
 	case *ir.Conversion:
+		// Numbers can't be formatted without allocating a string at runtime
+		_, isNumber := types.Unwrap(expr.Expression.Type()).(types.Numeric)
+		if isNumber && types.Unwrap(expr.To) == types.String {
+			panic(unsupported("converting numbers to strings"))
+		}
 		return c.compileExpression(expr.Expression, used)
 	case *ir.DerefExpression:
 		value := c.compileExpression(expr.Value, true).toRValue(c)
 		return deref{
 			value: value,
-			ty:    expr.Type().ToLlvm(c.context),
+			ty:    c.llvmType(expr.Type()),
 		}
 	case *ir.FloatLiteral:
 		if !used {
 			return llvmValue{}
 		}
-		return llvmValue(llvm.ConstFloat(expr.Type().ToLlvm(c.context), expr.Value))
+		return llvmValue(llvm.ConstFloat(c.llvmType(expr.Type()), expr.Value))
 	case *ir.FunctionCall:
-		callee := c.compileExpression(expr.Function, true).toRValue(c)
-		args := make([]llvm.Value, 0, len(expr.Arguments))
-		for _, arg := range expr.Arguments {
-			args = append(args, c.compileExpression(arg, true).toRValue(c))
-		}
-		var name string
-		if expr.ReturnType != types.Void && used {
-			name = "call_tmp"
-		}
-		return llvmValue(c.builder.CreateCall(callee.GlobalValueType(), callee, args, name))
+		return c.compileFunctionCall(expr, used)
 	case *ir.FunctionExpression:
-		panic("TODO")
+		panic(unsupported("function expressions"))
 	case *ir.IndexExpression:
-		panic("TODO")
+		return c.compileIndexExpression(expr)
 	case *ir.IntegerLiteral:
 		if !used {
 			return llvmValue{}
 		}
-		return llvmValue(llvm.ConstInt(expr.Type().ToLlvm(c.context), uint64(expr.Value), true))
+		return llvmValue(llvm.ConstInt(c.llvmType(expr.Type()), uint64(expr.Value), true))
 	case *ir.UintLiteral:
 		if !used {
 			return llvmValue{}
 		}
-		return llvmValue(llvm.ConstInt(expr.Type().ToLlvm(c.context), expr.Value, true))
+		return llvmValue(llvm.ConstInt(c.llvmType(expr.Type()), expr.Value, true))
 	case *ir.MapExpression:
-		panic("TODO")
+		panic(unsupported("map expressions"))
 	case *ir.MemberExpression:
-		panic("TODO")
+		if expr.Left.Type() == types.RuntimeType {
+			return c.compileTypeInfoMember(expr)
+		}
+		return c.compileMemberExpression(expr)
 	case *ir.RefExpression:
 		value := c.compileExpression(expr.Value, true)
 		return llvmValue(value.toRef(c))
@@ -213,17 +349,20 @@ func (c *compiler) compileExpression(expression ir.Expression, used bool) value
 		if !used {
 			return llvmValue{}
 		}
-		return llvmValue(c.builder.CreateGlobalString(expr.Value, ".str_const"))
+		return llvmValue(c.builder.CreateGlobalStringPtr(expr.Value, ".str_const"))
 	case *ir.StructExpression:
 		return c.compileStructExpression(expr)
 	case *ir.TupleExpression:
-		panic("TODO")
+		panic(unsupported("tuple expressions"))
 	case *ir.TupleStructExpression:
-		panic("TODO")
+		panic(unsupported("tuple struct expressions"))
 	case *ir.TypeCheck:
-		panic("TODO")
+		panic(unsupported("type checks"))
 	case *ir.TypeExpression:
-		panic("TODO")
+		if !used {
+			return llvmValue{}
+		}
+		return llvmValue(c.typeInfo(expr.DataType))
 	case *ir.UnaryExpression:
 		return c.compileUnaryExpression(expr)
 	case *ir.VariableExpression:
@@ -236,16 +375,34 @@ func (c *compiler) compileExpression(expression ir.Expression, used bool) value
 			return llvmValue{}
 		}
 
-		toType := expr.To.ToLlvm(c.context)
 		left := c.compileExpression(expr.Value, true)
-		alloca := c.builder.CreateAlloca(toType, "bitcast")
-		c.builder.CreateStore(left.toRValue(c), alloca)
-		return stackVariable(alloca)
+		return c.bitCast(left, expr.Value.Type(), expr.To)
 	default:
-		panic(fmt.Sprintf("Unexpected expression type: %T", expression))
+		panic(internalError(fmt.Sprintf("Unexpected expression type: %T", expression)))
 	}
 }
 
+// Reinterprets the bits of a value as a different type
+func (c *compiler) bitCast(value value, from, to types.Type) value {
+	fromType := c.llvmType(from)
+	toType := c.llvmType(to)
+	// Make sure there is enough space to store the original value
+	if types.ByteSize(from, c.target) > types.ByteSize(to, c.target) {
+		alloca := c.builder.CreateAlloca(fromType, "bitcast")
+		c.builder.CreateStore(value.toRValue(c), alloca)
+		return deref{value: c.pointerTo(alloca, toType), ty: toType}
+	}
+	alloca := c.builder.CreateAlloca(toType, "bitcast")
+	c.builder.CreateStore(value.toRValue(c), c.pointerTo(alloca, fromType))
+	return stackVariable(alloca)
+}
+
+// Casts a pointer to point to a different type. This does nothing
+// with opaque pointers, but older versions of LLVM require it.
+func (c *compiler) pointerTo(pointer llvm.Value, ty llvm.Type) llvm.Value {
+	return c.builder.CreateBitCast(pointer, llvm.PointerType(ty, 0), "")
+}
+
 func (c *compiler) compileBinaryExpression(binExpr *ir.BinaryExpression) value {
 	left := c.compileExpression(binExpr.Left, true).toRValue(c)
 	right := c.compileExpression(binExpr.Right, true).toRValue(c)
@@ -264,9 +421,9 @@ func (c *compiler) compileBinaryExpression(binExpr *ir.BinaryExpression) value {
 	case ir.BitwiseOr:
 		v = c.builder.CreateOr(left, right, "bit_or_tmp")
 	case ir.Concat:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
 	case ir.Divide:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
 	case ir.Equal:
 		// TODO: Non-integer comparisons
 		v = c.builder.CreateICmp(llvm.IntEQ, left, right, "eq_tmp")
@@ -289,9 +446,9 @@ func (c *compiler) compileBinaryExpression(binExpr *ir.BinaryExpression) value {
 	case ir.LogicalOr:
 		v = c.builder.CreateOr(left, right, "or_tmp")
 	case ir.ModuloFloat:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
 	case ir.ModuloInt:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
 	case ir.MultiplyFloat:
 		v = c.builder.CreateFMul(left, right, "fmul_tmp")
 	case ir.MultiplyInt:
@@ -300,9 +457,9 @@ func (c *compiler) compileBinaryExpression(binExpr *ir.BinaryExpression) value {
 		// TODO: Non-integer comparisons
 		v = c.builder.CreateICmp(llvm.IntNE, left, right, "ne_tmp")
 	case ir.PowerFloat:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
 	case ir.PowerInt:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", binExpr.Operator.Symbol())))
 	case ir.ArithmeticRightShift:
 		// TODO: Probably use lshr for unsigned types
 		v = c.builder.CreateAShr(left, right, "arsh_tmp")
@@ -330,15 +487,15 @@ func (c *compiler) compileUnaryExpression(unExpr *ir.UnaryExpression) value {
 	case ir.BitwiseNot:
 		v = c.builder.CreateNot(operand, "bit_not_tmp")
 	case ir.CrashError:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
 	case ir.DecrementInt:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
 	case ir.DecrementFloat:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
 	case ir.IncrementInt:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
 	case ir.IncrementFloat:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
 	case ir.LogicalNot:
 		v = c.builder.CreateNot(operand, "not_tmp")
 	case ir.NegateFloat:
@@ -346,7 +503,7 @@ func (c *compiler) compileUnaryExpression(unExpr *ir.UnaryExpression) value {
 	case ir.NegateInt:
 		v = c.builder.CreateNeg(operand, "neg_tmp")
 	case ir.PropagateError:
-		panic("TODO")
+		panic(unsupported(fmt.Sprintf("the %q operator", unExpr.Operator.Symbol())))
 	default:
 		panic("Unreachable")
 	}
@@ -354,11 +511,54 @@ func (c *compiler) compileUnaryExpression(unExpr *ir.UnaryExpression) value {
 	return llvmValue(v)
 }
 
+// Reads a field of a struct, or of the struct a pointer points to
+func (c *compiler) compileMemberExpression(expr *ir.MemberExpression) value {
+	var pointer llvm.Value
+	ty := expr.Left.Type()
+	if ptrType, ok := types.Unwrap(ty).(*types.Pointer); ok {
+		pointer = c.compileExpression(expr.Left, true).toRValue(c)
+		ty = ptrType.Underlying
+	} else {
+		pointer = c.compileExpression(expr.Left, true).toRef(c)
+	}
+
+	structType, ok := types.Unwrap(ty).(*types.Struct)
+	if !ok {
+		panic(unsupported(fmt.Sprintf("members of %s", ty.String())))
+	}
+	index := slices.Index(structType.FieldOrder, expr.Member)
+	field := c.builder.CreateStructGEP(c.llvmType(structType), pointer, index, expr.Member)
+	return deref{
+		value: field,
+		ty:    c.llvmType(expr.DataType),
+	}
+}
+
+// Reads an item from the buffer a list points to
+// TODO: Check that the index is in bounds
+func (c *compiler) compileIndexExpression(expr *ir.IndexExpression) value {
+	listType, ok := types.Unwrap(expr.Left.Type()).(*types.ListType)
+	if !ok {
+		panic(unsupported(fmt.Sprintf("indexing %s", expr.Left.Type().String())))
+	}
+	list := c.compileExpression(expr.Left, true).toRef(c)
+	index := c.compileExpression(expr.Index, true).toRValue(c)
+
+	itemType := c.llvmType(listType.ElemType)
+	dataField := c.builder.CreateStructGEP(c.llvmType(listType), list, 2, "list_data")
+	data := c.builder.CreateLoad(llvm.PointerType(itemType, 0), dataField, "load_tmp")
+	item := c.builder.CreateInBoundsGEP(itemType, data, []llvm.Value{index}, "list_item")
+	return deref{
+		value: item,
+		ty:    itemType,
+	}
+}
+
 func (c *compiler) compileStructExpression(s *ir.StructExpression) value {
 	values := make([]llvm.Value, 0, len(s.Fields))
 	structType := types.Unwrap(s.Struct).(*types.Struct)
 	for _, name := range structType.FieldOrder {
 		values = append(values, c.compileExpression(s.Fields[name], true).toRValue(c))
 	}
-	return llvmValue(llvm.ConstStruct(values, false))
+	return llvmValue(c.context.ConstStruct(values, false))
 }
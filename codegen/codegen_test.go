@@ -1,13 +1,20 @@
 package codegen_test
 
 import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/gearsdatapacks/libra/codegen"
 	utils "github.com/gearsdatapacks/libra/test_utils"
 )
 
 func TestConstantCodegen(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		"let value = 10",
 		"let value: u16 = 301",
 		"let weight = 61.2",
@@ -17,7 +24,7 @@ func TestConstantCodegen(t *testing.T) {
 }
 
 func TestBinaryExpressions(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		"mut a = 1; mut b = 2; let res = a + b",
 		"mut a: f32 = 4; mut b: f32 = 9.2; let res = a + b",
 		"mut a: u8 = 4; mut b: u8 = 23; let res = a + b",
@@ -49,7 +56,7 @@ func TestBinaryExpressions(t *testing.T) {
 }
 
 func TestAssignment(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		"mut age = 1; age += 1",
 		"mut value = 100; value = 200",
 		"mut x: f32 = 1; x = 3.1",
@@ -58,7 +65,7 @@ func TestAssignment(t *testing.T) {
 }
 
 func TestUnaryExpressions(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		"mut a = 31; let neg = -a",
 		"mut f = 4.2; let neg = -f",
 		"mut b = true; let not = !b",
@@ -67,7 +74,7 @@ func TestUnaryExpressions(t *testing.T) {
 }
 
 func TestFunctions(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		`fn add(a, b: i32): i32 {
 	return a + b
 }
@@ -79,11 +86,19 @@ let added2 = add(added, 1)`,
 fn exit(code: i32)
 
 exit(31)`,
+
+		`pub fn double(x: i32): i32 {
+	return helper(x) * 2
+}
+
+fn helper(x: i32): i32 {
+	return x
+}`,
 	)
 }
 
 func TestPointers(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		"let value = 1; let ptr = &value; let value2 = ptr.*",
 		"let ptr = &12; let value = ptr.*",
 		`mut value = 1
@@ -94,7 +109,7 @@ value = ptr.* + 1`,
 }
 
 func TestStructs(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		"struct Vector2 { x, y: f32 }; let my_vec = Vector2 { x: 10, y: 3.1 }",
 		"struct Colour { r, g, b, a: u8 }; let red = Colour { r: 0xFF, g: 0, b: 0, a: 0xFF }",
 		`struct Vector2 { x, y: f64 }
@@ -111,7 +126,7 @@ let transform = Transform {
 }
 
 func TestABI(t *testing.T) {
-	utils.MatchCodegenSnaps(t,
+	matchCodegenSnaps(t,
 		`@extern
 fn set_colour(c: Colour)
 
@@ -129,5 +144,485 @@ struct Vector {
 }
 
 move(Vector { x: 1.3, y: 5.2 })`,
+
+		`@extern
+fn print_bits(b: Bits)
+
+@untagged
+union Bits {
+  int: i32,
+  float: f32
+}
+
+let bits: Bits = 1.5 -> f32
+print_bits(bits)`,
+
+		`@extern
+fn print_item(i: Item)
+
+struct Item {
+  id: i32,
+  weight: f64
+}
+
+print_item(Item { id: 1, weight: 0.5 })`,
+
+		`@extern
+fn print_triple(t: Triple)
+
+struct Triple {
+  a, b, c: i64
+}
+
+print_triple(Triple { a: 1, b: 2, c: 3 })`,
+
+		`@extern
+fn make_triple(a: i64): Triple
+
+struct Triple {
+  a, b, c: i64
+}
+
+let triple = make_triple(1)`,
+
+		`struct Item {
+  id: i32,
+  weight: f64
+}
+
+fn echo(i: Item): Item {
+  return i
+}`,
+	)
+}
+
+func TestInternalErrors(t *testing.T) {
+	matchCodegenErrors(t,
+		"mut count = 1; count++",
+		`fn first(values: i32[2]): i32 {
+	return values[0]
+}
+
+fn square(f: f32): f32 {
+	return f ** 2
+}`,
+		"fn _f(_x: f16) {}",
+		"fn half(x: f16): f16 { x }\nfn _use() { half(1) }",
+	)
+}
+
+func TestTypeInfo(t *testing.T) {
+	matchCodegenSnaps(t,
+		`struct Point { x: i32, y: f32 }
+
+fn size_of(ty: Type): i64 {
+  return ty.size
+}
+
+let point_size = size_of(Point)`,
+	)
+
+	// List lengths in the type-info table are pointer-sized
+	matchCodegenSnapsForTarget(t, "i686-unknown-linux-gnu",
+		`struct Point { x: i32, y: f32 }
+
+fn name_of(ty: Type): string {
+  return ty.name
+}
+
+let name = name_of(Point)`,
 	)
 }
+
+const abiTestC = `#include <stdio.h>
+#include <stdint.h>
+
+typedef struct { uint8_t r, g, b, a; } Colour;
+typedef struct { float x, y, z; } Vector3;
+typedef struct { int32_t id; double weight; } Item;
+typedef struct { int64_t a, b, c; } Triple;
+typedef union { int64_t a; uint8_t b[12]; } Mixed;
+typedef struct { Mixed mixed; int32_t tag; } Tagged;
+
+void print_colour(Colour c) { printf("%d %d %d %d\n", c.r, c.g, c.b, c.a); }
+void print_vector(Vector3 v) { printf("%.2f %.2f %.2f\n", v.x, v.y, v.z); }
+void print_item(Item i) { printf("%d %.2f\n", i.id, i.weight); }
+void print_triple(Triple t) { printf("%ld %ld %ld\n", (long)t.a, (long)t.b, (long)t.c); }
+void print_tag(int32_t tag) { printf("%d\n", tag); }
+
+Colour make_colour(uint8_t value) { return (Colour){ value, value + 1, value + 2, 255 }; }
+Vector3 make_vector(float x) { return (Vector3){ x, x * 2, x * 3 }; }
+Item make_item(int32_t id, double weight) { return (Item){ id, weight }; }
+Triple make_triple(int64_t a) { return (Triple){ a, a * 2, a * 3 }; }
+Tagged make_tagged(int64_t a, int32_t tag) { return (Tagged){ { .a = a }, tag }; }
+
+Item echo_item(Item i);
+Triple echo_triple(Triple t);
+Vector3 echo_vector(Vector3 v);
+
+void call_libra(void) {
+  print_item(echo_item((Item){ 12, 0.75 }));
+  print_triple(echo_triple((Triple){ 100, 200, 300 }));
+  print_vector(echo_vector((Vector3){ 0.5, 1.5, 2.5 }));
+}
+`
+
+func TestSysVABI(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("The System V ABI is only implemented for x86-64")
+	}
+
+	for _, level := range []codegen.OptLevel{codegen.O0, codegen.O2} {
+		t.Run(level.String(), func(t *testing.T) {
+			testSysVABI(t, level)
+		})
+	}
+}
+
+func testSysVABI(t *testing.T, level codegen.OptLevel) {
+	output := runWithC(t, level, `struct Colour { r, g, b, a: u8 }
+struct Vector3 { x, y, z: f32 }
+struct Item { id: i32, weight: f64 }
+struct Triple { a, b, c: i64 }
+@untagged
+union Mixed { a: i64, b: u8[12] }
+struct Tagged { mixed: Mixed, tag: i32 }
+
+@extern
+fn print_colour(c: Colour)
+@extern
+fn print_vector(v: Vector3)
+@extern
+fn print_item(i: Item)
+@extern
+fn print_triple(t: Triple)
+@extern
+fn print_tag(tag: i32)
+
+@extern
+fn make_colour(value: u8): Colour
+@extern
+fn make_vector(x: f32): Vector3
+@extern
+fn make_item(id: i32, weight: f64): Item
+@extern
+fn make_triple(a: i64): Triple
+@extern
+fn make_tagged(a: i64, tag: i32): Tagged
+
+@extern
+fn call_libra()
+
+fn echo_item(i: Item): Item {
+  return i
+}
+
+fn echo_triple(t: Triple): Triple {
+  return t
+}
+
+fn echo_vector(v: Vector3): Vector3 {
+  return v
+}
+
+print_colour(Colour { r: 1, g: 2, b: 3, a: 4 })
+print_vector(Vector3 { x: 1.5, y: 2.5, z: 3.5 })
+print_item(Item { id: 7, weight: 2.5 })
+print_triple(Triple { a: 1, b: 2, c: 3 })
+
+print_colour(make_colour(10))
+print_vector(make_vector(1.25))
+print_item(make_item(3, 1.5))
+print_triple(make_triple(4))
+
+call_libra()
+
+let tagged = make_tagged(5, 6)
+print_tag(tagged.tag)`, abiTestC)
+
+	utils.AssertEq(t, output, `1 2 3 4
+1.50 2.50 3.50
+7 2.50
+1 2 3
+10 11 12 255
+1.25 2.50 3.75
+3 1.50
+4 8 12
+12 0.75
+100 200 300
+0.50 1.50 2.50
+6
+`)
+}
+
+func TestAAPCS64ABI(t *testing.T) {
+	matchCodegenSnapsForTarget(t, "aarch64-unknown-linux-gnu",
+		`@extern
+fn move(v: Vector)
+
+struct Vector {
+  x, y, z: f32
+}
+
+move(Vector { x: 1.3, y: 5.2, z: 0.5 })`,
+
+		`@extern
+fn get_position(): Vector
+
+struct Vector {
+  x, y: f64
+}
+
+let position = get_position()`,
+
+		`@extern
+fn set_colour(c: Colour): Colour
+
+struct Colour {
+  r, g, b, a: u8
+}
+
+let old = set_colour(Colour { r: 0x15, g: 0xcc, b: 0xcc, a: 0xFF })`,
+
+		`@extern
+fn print_item(i: Item)
+
+struct Item {
+  id: i32,
+  weight: f64
+}
+
+print_item(Item { id: 1, weight: 0.5 })`,
+
+		`@extern
+fn scale(t: Triple, by: i64): Triple
+
+struct Triple {
+  a, b, c: i64
+}
+
+let triple = Triple { a: 1, b: 2, c: 3 }
+let scaled = scale(triple, 2)`,
+
+		`struct Vector {
+  x, y: f32
+}
+
+fn echo(v: Vector): Vector {
+  return v
+}`,
+	)
+}
+
+func TestWasm(t *testing.T) {
+	matchCodegenSnapsForTarget(t, "wasm32-unknown-unknown",
+		`@extern fd_close
+@import_module wasi_snapshot_preview1
+fn close(fd: i32): i32
+
+let result = close(3)`,
+		`@extern
+fn log(value: i32)
+
+log(42)`,
+		`@extern log_value
+@import_module console
+@import_name log
+fn log(value: i32)
+
+log(42)`,
+	)
+
+	matchCodegenSnapsForTarget(t, "wasm32-wasi",
+		`@extern
+fn print(s: string)
+
+print("Hello, world!")`,
+	)
+
+	matchCodegenSnapsForTarget(t, "wasm64-wasi",
+		`@extern
+fn print(s: string)
+
+print("Hello, world!")`,
+	)
+}
+
+func TestOptimisation(t *testing.T) {
+	src := `fn sum(n: i32): i32 {
+	mut total = 0
+	mut i = 0
+	while i < n {
+		total = total + i
+		i = i + 1
+	}
+	return total
+}
+
+@untagged
+union Bits { int: i32, float: f32 }
+
+fn to_bits(f: f32): i32 {
+	let bits: Bits = f
+	return bits.int
+}`
+
+	unoptimised := optimisedIr(t, codegen.O0, src)
+	if !strings.Contains(unoptimised, "alloca") {
+		t.Fatalf("Expected unoptimised code to use stack slots, got:\n%s", unoptimised)
+	}
+
+	for _, level := range []codegen.OptLevel{codegen.O1, codegen.O2, codegen.O3, codegen.Os} {
+		optimised := optimisedIr(t, level, src)
+		if strings.Contains(optimised, "alloca") {
+			t.Errorf("Expected stack slots to be removed at %s, got:\n%s", level, optimised)
+		}
+	}
+}
+
+func TestJit(t *testing.T) {
+	tests := []struct {
+		src      string
+		function string
+		result   int64
+	}{
+		{"fn answer(): i32 { return 42 }", "answer", 42},
+		{`fn sum(n: i32): i32 {
+	mut total = 0
+	mut i = 1
+	while i <= n {
+		total = total + i
+		i = i + 1
+	}
+	return total
+}
+fn result(): i32 { return sum(10) }`, "result", 55},
+		{`fn fib(n: i64): i64 {
+	if n < 2 { return n }
+	return fib(n - 1) + fib(n - 2)
+}
+fn result(): i64 { return fib(20) }`, "result", 6765},
+		{`fn negative(): i32 { return -7 }`, "negative", -7},
+		{`@extern
+fn abs(n: i32): i32
+fn result(): i32 { return abs(-12) }`, "result", 12},
+		{`struct Point { x, y: i32 }
+fn get_y(p: *Point): i32 { return p.y }
+fn result(): i32 {
+	let point = Point { x: 3, y: 4 }
+	return point.x + get_y(&point)
+}`, "result", 7},
+		{`struct Rect { width, height: f32 }
+@extern
+fn strlen(s: string): i64
+fn field_name(ty: Type, index: i32): string { return ty.fields[index].name }
+fn result(): i64 { return strlen(field_name(Rect, 1)) }`, "result", 6},
+	}
+
+	for _, test := range tests {
+		result := jitCall(t, test.src, test.function)
+		utils.AssertEq(t, result, test.result)
+	}
+}
+
+func TestDebugInfo(t *testing.T) {
+	object := debugObject(t, `fn add(a, b: i32): i32 {
+	let sum = a + b
+	return sum
+}
+
+struct Point { x, y: f32 }
+
+fn origin(point: Point) {}
+
+let total = add(1, 2)`)
+
+	file, err := elf.NewFile(bytes.NewReader(object))
+	if err != nil {
+		t.Skip("Debug information can only be read from ELF files")
+	}
+	data, err := file.DWARF()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The line each declaration is on, and the name of its type
+	declarations := map[string]int{}
+	typeNames := map[string]string{}
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil {
+			break
+		}
+		name, ok := entry.Val(dwarf.AttrName).(string)
+		if !ok {
+			continue
+		}
+		switch entry.Tag {
+		case dwarf.TagSubprogram, dwarf.TagVariable, dwarf.TagFormalParameter:
+			declarations[name] = int(entry.Val(dwarf.AttrDeclLine).(int64))
+			if offset, ok := entry.Val(dwarf.AttrType).(dwarf.Offset); ok {
+				ty, err := data.Type(offset)
+				if err != nil {
+					t.Fatal(err)
+				}
+				typeNames[name] = ty.String()
+				if structType, ok := ty.(*dwarf.StructType); ok {
+					typeNames[name] = structType.Defn()
+				}
+			}
+		}
+	}
+
+	// Maps are printed with sorted keys, so they can be compared as strings
+	utils.AssertEq(t, fmt.Sprint(declarations), fmt.Sprint(map[string]int{
+		"add": 1, "a": 1, "b": 1, "sum": 2,
+		"origin": 8, "point": 8, "main": 10, "total": 10,
+	}))
+	utils.AssertEq(t, fmt.Sprint(typeNames), fmt.Sprint(map[string]string{
+		"add": "i32", "a": "i32", "b": "i32", "sum": "i32",
+		"point": "struct Point {x f32@0; y f32@4}", "total": "i32",
+	}))
+
+	// Every statement should be mapped back to its line
+	lines := map[int]bool{}
+	for _, unit := range compileUnits(t, data) {
+		lineReader, err := data.LineReader(unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var line dwarf.LineEntry
+		for lineReader.Next(&line) == nil {
+			if strings.HasSuffix(line.File.Name, "test.lb") {
+				lines[line.Line] = true
+			}
+		}
+	}
+	for _, line := range []int{2, 3, 10} {
+		if !lines[line] {
+			t.Errorf("Expected line %d to be in the line table, got %v", line, lines)
+		}
+	}
+}
+
+func compileUnits(t *testing.T, data *dwarf.Data) []*dwarf.Entry {
+	units := []*dwarf.Entry{}
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil {
+			return units
+		}
+		if entry.Tag == dwarf.TagCompileUnit {
+			units = append(units, entry)
+			reader.SkipChildren()
+		}
+	}
+}
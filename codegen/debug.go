@@ -0,0 +1,371 @@
+package codegen
+
+import (
+	"debug/dwarf"
+	"fmt"
+	"path/filepath"
+
+	"github.com/gearsdatapacks/libra/text"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"tinygo.org/x/go-llvm"
+)
+
+// DWARF has no language code for Libra, so we claim to be C, which
+// debuggers have the best support for. LLVM's C API numbers languages
+// from 0, so this is one less than the DWARF code for C99.
+const dwarfLanguage llvm.DwarfLang = 0x0b
+
+const dwarfVersion = 4
+
+// LLVM ignores debug info with a different version to its own
+const debugInfoVersion = 3
+
+// The debug information for a single LLVM module. LLVM only allows
+// one compile unit per DIBuilder, so there is a builder for each file.
+type debugInfo struct {
+	files map[*text.SourceFile]*debugFile
+}
+
+type debugFile struct {
+	builder *llvm.DIBuilder
+	file    llvm.Metadata
+	types   map[types.Type]llvm.Metadata
+}
+
+// The debug scope of the function currently being compiled
+type debugScope struct {
+	file       *debugFile
+	subprogram llvm.Metadata
+	// The line the function starts on
+	line int
+}
+
+func newDebugInfo() *debugInfo {
+	return &debugInfo{files: map[*text.SourceFile]*debugFile{}}
+}
+
+func (c *compiler) debugFile(source *text.SourceFile) *debugFile {
+	if file, ok := c.debug.files[source]; ok {
+		return file
+	}
+
+	path, err := filepath.Abs(source.FileName)
+	if err != nil {
+		path = source.FileName
+	}
+	dir, name := filepath.Split(path)
+
+	builder := llvm.NewDIBuilder(c.currentModule)
+	builder.CreateCompileUnit(llvm.DICompileUnit{
+		Language: dwarfLanguage,
+		File:     name,
+		Dir:      dir,
+		Producer: "libra",
+	})
+	file := &debugFile{
+		builder: builder,
+		file:    builder.CreateFile(name, dir),
+		types:   map[types.Type]llvm.Metadata{},
+	}
+	c.debug.files[source] = file
+	return file
+}
+
+// Resolves the debug information for the current module, so it can be linked
+func (c *compiler) finaliseDebugInfo() {
+	for _, file := range c.debug.files {
+		file.builder.Finalize()
+		file.builder.Destroy()
+	}
+	c.debug = newDebugInfo()
+}
+
+func (c *compiler) addDebugInfoFlags() {
+	i32 := c.context.Int32Type()
+	// Behaviour 2 means that LLVM warns if linked modules disagree
+	warning := llvm.ConstInt(i32, 2, false).ConstantAsMetadata()
+
+	c.mainModule.AddNamedMetadataOperand("llvm.module.flags", c.context.MDNode([]llvm.Metadata{
+		warning,
+		c.context.MDString("Dwarf Version"),
+		llvm.ConstInt(i32, dwarfVersion, false).ConstantAsMetadata(),
+	}))
+	c.mainModule.AddNamedMetadataOperand("llvm.module.flags", c.context.MDNode([]llvm.Metadata{
+		warning,
+		c.context.MDString("Debug Info Version"),
+		llvm.ConstInt(i32, debugInfoVersion, false).ConstantAsMetadata(),
+	}))
+}
+
+// DWARF lines and columns start from 1
+func debugPosition(location text.Location) (line, column int) {
+	span := location.Span.ToLineSpan(location.File)
+	return span.StartLine + 1, span.StartColumn + 1
+}
+
+// The location of a function, which is the location of its first
+// statement if it was created by the compiler, as `main` can be
+func functionLocation(fn *ir.FunctionDeclaration) (text.Location, bool) {
+	if fn.Location.File != nil {
+		return fn.Location, true
+	}
+	for _, stmt := range fn.Body.Statements {
+		if location := stmt.GetLocation(); location.File != nil {
+			return location, true
+		}
+	}
+	return text.Location{}, false
+}
+
+// Attaches debug information to a function before its body is compiled
+func (c *compiler) beginFunctionDebugInfo(fn *ir.FunctionDeclaration, function llvm.Value) {
+	location, ok := functionLocation(fn)
+	if c.debug == nil || !ok {
+		c.table.context.debug = nil
+		c.builder.SetCurrentDebugLocation(0, 0, llvm.Metadata{}, llvm.Metadata{})
+		return
+	}
+
+	file := c.debugFile(location.File)
+	line, column := debugPosition(location)
+
+	// The types in the function's signature may have been changed to
+	// match the ABI, so the original types are used instead
+	paramTypes := make([]llvm.Metadata, 0, len(fn.Abi.Parameters)+1)
+	paramTypes = append(paramTypes, c.debugType(file, fn.Abi.Return.Type))
+	for _, param := range fn.Abi.Parameters {
+		paramTypes = append(paramTypes, c.debugType(file, param.Type))
+	}
+
+	subprogram := file.builder.CreateFunction(file.file, llvm.DIFunction{
+		Name:        fn.Name,
+		LinkageName: function.Name(),
+		File:        file.file,
+		Line:        line,
+		Type: file.builder.CreateSubroutineType(llvm.DISubroutineType{
+			File:       file.file,
+			Parameters: paramTypes,
+		}),
+		LocalToUnit:  false,
+		IsDefinition: true,
+		ScopeLine:    line,
+		Flags:        llvm.FlagPrototyped,
+	})
+	function.SetSubprogram(subprogram)
+
+	c.table.context.debug = &debugScope{file: file, subprogram: subprogram, line: line}
+	c.builder.SetCurrentDebugLocation(uint(line), uint(column), subprogram, llvm.Metadata{})
+}
+
+// Sets the source location of the instructions generated for a statement
+func (c *compiler) setDebugLocation(location text.Location) {
+	scope := c.table.context.debug
+	if scope == nil || location.File == nil {
+		return
+	}
+	line, column := debugPosition(location)
+	c.builder.SetCurrentDebugLocation(uint(line), uint(column), scope.subprogram, llvm.Metadata{})
+}
+
+// Describes a local variable or parameter, so a debugger can display it.
+// A parameter number of 0 means the variable is not a parameter.
+// Variables without a location are placed at the start of the function.
+func (c *compiler) declareDebugVariable(
+	name string,
+	ty types.Type,
+	value value,
+	location text.Location,
+	paramNumber int,
+) {
+	scope := c.table.context.debug
+	if scope == nil {
+		return
+	}
+	line, column := scope.line, 0
+	if location.File != nil {
+		line, column = debugPosition(location)
+	}
+
+	builder := scope.file.builder
+	var variable llvm.Metadata
+	if paramNumber == 0 {
+		variable = builder.CreateAutoVariable(scope.subprogram, llvm.DIAutoVariable{
+			Name:           name,
+			File:           scope.file.file,
+			Line:           line,
+			Type:           c.debugType(scope.file, ty),
+			AlwaysPreserve: true,
+		})
+	} else {
+		variable = builder.CreateParameterVariable(scope.subprogram, llvm.DIParameterVariable{
+			Name:           name,
+			File:           scope.file.file,
+			Line:           line,
+			Type:           c.debugType(scope.file, ty),
+			AlwaysPreserve: true,
+			ArgNo:          paramNumber,
+		})
+	}
+
+	debugLocation := llvm.DebugLoc{
+		Line:  uint(line),
+		Col:   uint(column),
+		Scope: scope.subprogram,
+	}
+	expression := builder.CreateExpression(nil)
+	block := c.builder.GetInsertBlock()
+
+	switch value := value.(type) {
+	case stackVariable, deref:
+		builder.InsertDeclareAtEnd(value.toLValue(), variable, expression, debugLocation, block)
+	default:
+		builder.InsertValueAtEnd(value.toRValue(c), variable, expression, debugLocation, block)
+	}
+}
+
+// Builds a DWARF description of a type
+func (c *compiler) debugType(file *debugFile, ty types.Type) llvm.Metadata {
+	if ty == types.Void {
+		return llvm.Metadata{}
+	}
+	if metadata, ok := file.types[ty]; ok {
+		return metadata
+	}
+
+	builder := file.builder
+	size := uint64(types.BitSize(ty, c.target))
+	align := uint32(types.Alignment(ty, c.target) * 8)
+	pointerSize := uint64(c.target.PointerSize * 8)
+
+	var metadata llvm.Metadata
+	switch unwrapped := types.Unwrap(ty).(type) {
+	case types.PrimaryType:
+		switch unwrapped {
+		case types.Bool:
+			metadata = builder.CreateBasicType(llvm.DIBasicType{
+				Name:       "bool",
+				SizeInBits: 8,
+				Encoding:   llvm.DW_ATE_boolean,
+			})
+		case types.String, types.RuntimeType:
+			metadata = builder.CreatePointerType(llvm.DIPointerType{
+				Pointee: builder.CreateBasicType(llvm.DIBasicType{
+					Name:       "u8",
+					SizeInBits: 8,
+					Encoding:   llvm.DW_ATE_unsigned_char,
+				}),
+				SizeInBits: pointerSize,
+				Name:       unwrapped.String(),
+			})
+		}
+
+	case types.Numeric:
+		encoding := llvm.DW_ATE_signed
+		switch unwrapped.Kind {
+		case types.NumUint:
+			encoding = llvm.DW_ATE_unsigned
+		case types.NumFloat:
+			encoding = llvm.DW_ATE_float
+		}
+		metadata = builder.CreateBasicType(llvm.DIBasicType{
+			Name:       unwrapped.String(),
+			SizeInBits: uint64(unwrapped.BitWidth),
+			Encoding:   encoding,
+		})
+
+	case *types.Pointer:
+		metadata = builder.CreatePointerType(llvm.DIPointerType{
+			Pointee:    c.debugType(file, unwrapped.Underlying),
+			SizeInBits: pointerSize,
+		})
+
+	case *types.Enum:
+		metadata = c.debugType(file, unwrapped.Underlying)
+
+	case *types.ArrayType:
+		metadata = builder.CreateArrayType(llvm.DIArrayType{
+			SizeInBits:  size,
+			AlignInBits: align,
+			ElementType: c.debugType(file, unwrapped.ElemType),
+			Subscripts:  []llvm.DISubrange{{Lo: 0, Count: int64(unwrapped.Length)}},
+		})
+
+	case *types.Struct:
+		names := make([]string, 0, len(unwrapped.FieldOrder))
+		fieldTypes := make([]types.Type, 0, len(unwrapped.FieldOrder))
+		for _, name := range unwrapped.FieldOrder {
+			names = append(names, name)
+			fieldTypes = append(fieldTypes, unwrapped.Fields[name].Type)
+		}
+		metadata = c.debugStruct(file, ty, unwrapped.Name, names, fieldTypes)
+
+	case *types.TupleStruct:
+		metadata = c.debugStruct(file, ty, unwrapped.Name, nil, unwrapped.Types)
+	case *types.TupleType:
+		metadata = c.debugStruct(file, ty, ty.String(), nil, unwrapped.Types)
+	}
+
+	// Types which can't be described yet are shown as opaque structs
+	if metadata.C == nil {
+		metadata = builder.CreateStructType(file.file, llvm.DIStructType{
+			Name:        ty.String(),
+			File:        file.file,
+			SizeInBits:  size,
+			AlignInBits: align,
+		})
+	}
+
+	file.types[ty] = metadata
+	return metadata
+}
+
+// Describes a struct or tuple. Unnamed fields are named by their index.
+func (c *compiler) debugStruct(
+	file *debugFile,
+	ty types.Type,
+	name string,
+	fieldNames []string,
+	fieldTypes []types.Type,
+) llvm.Metadata {
+	size := uint64(types.BitSize(ty, c.target))
+	align := uint32(types.Alignment(ty, c.target) * 8)
+
+	// Structs can contain pointers to themselves, so a placeholder
+	// is used until all the fields have been described
+	placeholder := file.builder.CreateReplaceableCompositeType(file.file, llvm.DIReplaceableCompositeType{
+		Tag:         dwarf.TagStructType,
+		Name:        name,
+		File:        file.file,
+		SizeInBits:  size,
+		AlignInBits: align,
+	})
+	file.types[ty] = placeholder
+
+	offsets := types.FieldOffsets(fieldTypes, c.target)
+	members := make([]llvm.Metadata, 0, len(fieldTypes))
+	for i, fieldType := range fieldTypes {
+		fieldName := fmt.Sprint(i)
+		if fieldNames != nil {
+			fieldName = fieldNames[i]
+		}
+		members = append(members, file.builder.CreateMemberType(file.file, llvm.DIMemberType{
+			Name:         fieldName,
+			File:         file.file,
+			SizeInBits:   uint64(types.BitSize(fieldType, c.target)),
+			AlignInBits:  uint32(types.Alignment(fieldType, c.target) * 8),
+			OffsetInBits: uint64(offsets[i] * 8),
+			Type:         c.debugType(file, fieldType),
+		}))
+	}
+
+	metadata := file.builder.CreateStructType(file.file, llvm.DIStructType{
+		Name:        name,
+		File:        file.file,
+		SizeInBits:  size,
+		AlignInBits: align,
+		Elements:    members,
+	})
+	placeholder.ReplaceAllUsesWith(metadata)
+	return metadata
+}
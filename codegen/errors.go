@@ -0,0 +1,91 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/text"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"tinygo.org/x/go-llvm"
+)
+
+// An error in code generation. These are caused by bugs or missing
+// features in the compiler, not by the program being compiled, so
+// they are reported as internal compiler errors.
+type internalError string
+
+func unsupported(feature string) internalError {
+	return internalError(fmt.Sprintf("Code generation for %s is not implemented yet", feature))
+}
+
+// Stops compiling a function because of an error which
+// has already been reported somewhere else
+type alreadyReported struct{}
+
+// A function which couldn't be declared. Its error is reported when it is
+// declared, so functions using it are skipped without reporting another.
+type failedFunction struct{}
+
+func (failedFunction) toRValue(*compiler) llvm.Value { panic(alreadyReported{}) }
+func (failedFunction) toLValue() llvm.Value          { panic(alreadyReported{}) }
+func (failedFunction) toRef(*compiler) llvm.Value    { panic(alreadyReported{}) }
+
+// Reports an error at the location of the node currently being compiled
+func (c *compiler) reportInternalError(err any) {
+	if _, ok := err.(alreadyReported); ok {
+		return
+	}
+	message, ok := err.(internalError)
+	if !ok {
+		message = internalError(fmt.Sprint(err))
+	}
+	c.diagnostics.Report(diagnostics.InternalError(c.location, string(message)))
+}
+
+// Deletes the body of a function which failed to compile,
+// leaving only its declaration
+func removeBody(function llvm.Value) {
+	for _, block := range function.BasicBlocks() {
+		block.EraseFromParent()
+	}
+	// Only function definitions can have debug information
+	function.SetSubprogram(llvm.Metadata{})
+}
+
+// Checks that the code generated for a module is valid, reporting
+// an error at each function which isn't
+func (c *compiler) verifyModule(functions []*ir.FunctionDeclaration) {
+	err := llvm.VerifyModule(c.currentModule, llvm.ReturnStatusAction)
+	if err == nil {
+		return
+	}
+
+	reported := false
+	for _, fn := range functions {
+		function := c.currentModule.NamedFunction(c.functionName(fn))
+		if function.IsNil() || function.BasicBlocksCount() == 0 {
+			continue
+		}
+		if llvm.VerifyFunction(function, llvm.ReturnStatusAction) != nil {
+			c.location, _ = functionLocation(fn)
+			c.reportInternalError(fmt.Sprintf("Generated invalid code for function %q:\n%s", fn.Name, err))
+			removeBody(function)
+			reported = true
+		}
+	}
+
+	// The problem isn't in any particular function
+	if !reported {
+		c.location = text.Location{}
+		c.reportInternalError(fmt.Sprintf("Generated an invalid module:\n%s", err))
+	}
+}
+
+// Links the current module into the main one
+func (c *compiler) linkModule() {
+	err := llvm.LinkModules(c.mainModule, c.currentModule)
+	if err != nil {
+		c.location = text.Location{}
+		c.reportInternalError(fmt.Sprintf("Failed to link module: %s", err))
+	}
+}
@@ -0,0 +1,193 @@
+package codegen_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/gearsdatapacks/libra/codegen"
+	"github.com/gearsdatapacks/libra/diagnostics"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"tinygo.org/x/go-llvm"
+)
+
+func matchCodegenSnaps(t *testing.T, tests ...string) {
+	t.Helper()
+	matchCodegenSnapsForTarget(t, utils.DefaultTarget, tests...)
+}
+
+func matchCodegenSnapsForTarget(t *testing.T, target string, tests ...string) {
+	t.Helper()
+
+	for _, src := range tests {
+		module := getCode(t, target, src)
+		utils.MatchSnap(t, src, module.String())
+	}
+}
+
+func matchCodegenErrors(t *testing.T, tests ...string) {
+	t.Helper()
+
+	for _, src := range tests {
+		_, diagnostics := getCodeWithDiagnostics(t, utils.DefaultTarget, src, false)
+		var diags bytes.Buffer
+		for _, diag := range diagnostics {
+			diag.WriteTo(&diags, false)
+		}
+
+		utils.MatchSnap(t, src, diags.String())
+	}
+}
+
+// Compiles and optimises a program for the host, returning the
+// resulting LLVM IR. The output of LLVM's optimisation passes changes
+// between versions, so tests check properties of it instead of
+// comparing it to a snapshot.
+func optimisedIr(t *testing.T, level codegen.OptLevel, src string) string {
+	t.Helper()
+
+	target := types.TargetFor(llvm.DefaultTargetTriple())
+	module, _ := getOptimisedCode(t, target, level, src)
+	return module.String()
+}
+
+// Compiles a program for the host with debug information, and
+// returns the object file it produces
+func debugObject(t *testing.T, src string) []byte {
+	t.Helper()
+
+	target := types.TargetFor(llvm.DefaultTargetTriple())
+	module := compileCode(t, target.Triple, src, true)
+	if err := llvm.VerifyModule(module, llvm.ReturnStatusAction); err != nil {
+		t.Fatalf("Invalid module: %s\n%s", err, module.String())
+	}
+
+	machine, err := codegen.NewTargetMachine(target, "", "", codegen.O0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, err := codegen.EmitObject(module, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
+
+// Compiles a program for the host and runs it with the JIT, returning
+// the result of calling the given function, which takes no parameters
+func jitCall(t *testing.T, src, function string) int64 {
+	t.Helper()
+
+	target := types.TargetFor(llvm.DefaultTargetTriple())
+	module := getCode(t, target.Triple, src)
+	jit, err := codegen.NewJit(module, codegen.O0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jit.Dispose()
+
+	result, err := jit.Call(function)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// Compiles and optimises a program, and links it with some C code using
+// the system's C compiler, then runs it and returns what it printed
+func runWithC(t *testing.T, level codegen.OptLevel, src, cSrc string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Linking with C is not supported on Windows")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("No C compiler found")
+	}
+
+	target := types.TargetFor(llvm.DefaultTargetTriple())
+	module, machine := getOptimisedCode(t, target, level, src)
+	object, err := codegen.EmitObject(module, machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	objectPath := filepath.Join(dir, "test.o")
+	cPath := filepath.Join(dir, "test.c")
+	exePath := filepath.Join(dir, "test")
+	if err := os.WriteFile(objectPath, object, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cPath, []byte(cSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command(cc, cPath, objectPath, "-o", exePath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to compile C code: %s\n%s", err, output)
+	}
+
+	// The exit code is ignored, as `main` doesn't return a value yet
+	output, err = exec.Command(exePath).Output()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+func getCode(t *testing.T, target, input string) llvm.Module {
+	t.Helper()
+	return compileCode(t, target, input, false)
+}
+
+func compileCode(t *testing.T, target, input string, debugInfo bool) llvm.Module {
+	t.Helper()
+
+	module, diags := getCodeWithDiagnostics(t, target, input, debugInfo)
+	utils.AssertNoErrors(t, diags)
+
+	return module
+}
+
+func getCodeWithDiagnostics(
+	t *testing.T,
+	target, input string,
+	debugInfo bool,
+) (llvm.Module, []diagnostics.Diagnostic) {
+	t.Helper()
+
+	lowered, diags := utils.Lower(t, target, input)
+	if diagnostics.Manager(diags).HasErrors() {
+		return llvm.Module{}, diags
+	}
+	return codegen.Compile(lowered, types.TargetFor(target), debugInfo, diags)
+}
+
+func getOptimisedCode(
+	t *testing.T,
+	target types.TargetInfo,
+	level codegen.OptLevel,
+	input string,
+) (llvm.Module, llvm.TargetMachine) {
+	t.Helper()
+
+	module := getCode(t, target.Triple, input)
+	machine, err := codegen.NewTargetMachine(target, "", "", level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != codegen.O0 {
+		if err := codegen.Optimise(module, machine, level); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return module, machine
+}
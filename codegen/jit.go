@@ -0,0 +1,101 @@
+package codegen
+
+import (
+	"fmt"
+
+	"tinygo.org/x/go-llvm"
+)
+
+// Compiles a module to machine code in memory, so that it can be run
+// in the current process without writing an object file or linking.
+// External functions are resolved against the libraries already loaded
+// into the process, which includes libc.
+type Jit struct {
+	engine llvm.ExecutionEngine
+	module llvm.Module
+}
+
+// The name of the function which flushes libc's output buffers
+const flushFunction = "libra.flush"
+
+// Creates a JIT for a module compiled for the host. The JIT takes
+// ownership of the module, which is freed when the JIT is disposed.
+func NewJit(module llvm.Module, level OptLevel) (*Jit, error) {
+	llvm.LinkInMCJIT()
+	if err := llvm.InitializeNativeTarget(); err != nil {
+		return nil, err
+	}
+	if err := llvm.InitializeNativeAsmPrinter(); err != nil {
+		return nil, err
+	}
+
+	addFlushFunction(module)
+
+	options := llvm.NewMCJITCompilerOptions()
+	options.SetMCJITOptimizationLevel(uint(level.codeGenLevel()))
+	engine, err := llvm.NewMCJITCompiler(module, options)
+	if err != nil {
+		return nil, err
+	}
+	return &Jit{engine: engine, module: module}, nil
+}
+
+// Output written by C functions such as `printf` is buffered, and
+// would be lost when the compiler exits, so it must be flushed after
+// running a program. MCJIT can only call functions with simple
+// signatures, so `fflush(NULL)` is wrapped in a function with no
+// parameters.
+func addFlushFunction(module llvm.Module) {
+	context := module.Context()
+	fflushType := llvm.FunctionType(
+		context.Int32Type(),
+		[]llvm.Type{llvm.PointerType(context.Int8Type(), 0)},
+		false,
+	)
+	fflush := module.NamedFunction("fflush")
+	if fflush.IsNil() {
+		fflush = llvm.AddFunction(module, "fflush", fflushType)
+	}
+
+	function := llvm.AddFunction(module, flushFunction, llvm.FunctionType(context.VoidType(), nil, false))
+	builder := context.NewBuilder()
+	defer builder.Dispose()
+	builder.SetInsertPointAtEnd(context.AddBasicBlock(function, "entry"))
+	builder.CreateCall(fflushType, fflush, []llvm.Value{llvm.ConstNull(fflushType.ParamTypes()[0])}, "")
+	builder.CreateRetVoid()
+}
+
+// Runs the program's `main` function
+func (j *Jit) Run() error {
+	_, err := j.Call("main")
+	if err != nil {
+		return err
+	}
+	_, err = j.Call(flushFunction)
+	return err
+}
+
+// Calls a function which takes no parameters, returning its result as an
+// integer. Functions which don't return an integer return 0.
+func (j *Jit) Call(name string) (int64, error) {
+	function := j.module.NamedFunction(name)
+	if function.IsNil() || function.BasicBlocksCount() == 0 {
+		return 0, fmt.Errorf("Cannot find function %q", name)
+	}
+	fnType := function.GlobalValueType()
+	if fnType.ParamTypesCount() != 0 {
+		return 0, fmt.Errorf("Cannot call function %q, as it has parameters", name)
+	}
+
+	result := j.engine.RunFunction(function, nil)
+	defer result.Dispose()
+
+	if fnType.ReturnType().TypeKind() != llvm.IntegerTypeKind {
+		return 0, nil
+	}
+	return int64(result.Int(true)), nil
+}
+
+func (j *Jit) Dispose() {
+	j.engine.Dispose()
+}
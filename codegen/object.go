@@ -0,0 +1,135 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"tinygo.org/x/go-llvm"
+)
+
+type OptLevel int
+
+const (
+	O0 OptLevel = iota
+	O1
+	O2
+	O3
+	Os
+)
+
+func ParseOptLevel(level string) (OptLevel, error) {
+	switch level {
+	case "0":
+		return O0, nil
+	case "1":
+		return O1, nil
+	case "2":
+		return O2, nil
+	case "3":
+		return O3, nil
+	case "s":
+		return Os, nil
+	default:
+		return O0, fmt.Errorf("Unknown optimisation level %q", level)
+	}
+}
+
+func (level OptLevel) String() string {
+	switch level {
+	case O0:
+		return "O0"
+	case O1:
+		return "O1"
+	case O2:
+		return "O2"
+	case O3:
+		return "O3"
+	case Os:
+		return "Os"
+	default:
+		panic("Unreachable")
+	}
+}
+
+func (level OptLevel) codeGenLevel() llvm.CodeGenOptLevel {
+	switch level {
+	case O0:
+		return llvm.CodeGenLevelNone
+	case O1:
+		return llvm.CodeGenLevelLess
+	case O3:
+		return llvm.CodeGenLevelAggressive
+	default:
+		// Like clang, `-Os` only affects the IR passes which are run
+		return llvm.CodeGenLevelDefault
+	}
+}
+
+// Creates a machine which generates code for the given target
+func NewTargetMachine(target types.TargetInfo, cpu, features string, level OptLevel) (llvm.TargetMachine, error) {
+	llvm.InitializeAllTargetInfos()
+	llvm.InitializeAllTargets()
+	llvm.InitializeAllTargetMCs()
+	llvm.InitializeAllAsmParsers()
+	llvm.InitializeAllAsmPrinters()
+	llvmTarget, err := llvm.GetTargetFromTriple(target.Triple)
+	if err != nil {
+		return llvm.TargetMachine{}, err
+	}
+	if cpu == "" {
+		cpu = "generic"
+	}
+	machine := llvmTarget.CreateTargetMachine(
+		target.Triple,
+		cpu,
+		features,
+		level.codeGenLevel(),
+		llvm.RelocPIC,
+		llvm.CodeModelDefault,
+	)
+	return machine, nil
+}
+
+func setTarget(module llvm.Module, machine llvm.TargetMachine) {
+	module.SetTarget(machine.Triple())
+	module.SetDataLayout(machine.CreateTargetData().String())
+}
+
+// Runs LLVM's standard optimisation pipeline for the given level over a
+// module. The codegen relies on these passes to turn its stack slots
+// into registers, so without them the output is very slow.
+func Optimise(module llvm.Module, machine llvm.TargetMachine, level OptLevel) error {
+	setTarget(module, machine)
+
+	options := llvm.NewPassBuilderOptions()
+	defer options.Dispose()
+	return module.RunPasses(fmt.Sprintf("default<%s>", level), machine, options)
+}
+
+// Compiles a module to an object file
+func EmitObject(module llvm.Module, machine llvm.TargetMachine) ([]byte, error) {
+	setTarget(module, machine)
+
+	buffer, err := machine.EmitToMemoryBuffer(module, llvm.ObjectFile)
+	if err != nil {
+		return nil, err
+	}
+	defer buffer.Dispose()
+	return append([]byte{}, buffer.Bytes()...), nil
+}
+
+// Compiles a module to LLVM bitcode, which can be optimised
+// along with code from other languages when linking
+func EmitBitcode(module llvm.Module, machine llvm.TargetMachine) []byte {
+	setTarget(module, machine)
+
+	buffer := llvm.WriteBitcodeToMemoryBuffer(module)
+	defer buffer.Dispose()
+	return append([]byte{}, buffer.Bytes()...)
+}
+
+// Returns the textual LLVM IR of a module
+func EmitIr(module llvm.Module, machine llvm.TargetMachine) string {
+	setTarget(module, machine)
+	return module.String()
+}
@@ -3,6 +3,7 @@ package codegen
 import (
 	"fmt"
 
+	"github.com/gearsdatapacks/libra/type_checker/ir"
 	"tinygo.org/x/go-llvm"
 )
 
@@ -14,6 +15,11 @@ type table struct {
 
 type fnContext struct {
 	blocks map[string]llvm.BasicBlock
+	abi    *ir.FunctionAbi
+	// The pointer to write the return value to, if it is returned in memory
+	returnPointer llvm.Value
+	// Nil if debug information is not being generated for this function
+	debug *debugScope
 }
 
 func newTable() *table {
@@ -0,0 +1,168 @@
+package codegen
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"tinygo.org/x/go-llvm"
+)
+
+type typeInfo struct {
+	ty    types.Type
+	value llvm.Value
+}
+
+// Returns a handle to the type-info table entry for a type,
+// emitting the entry if it hasn't been used in this module yet
+func (c *compiler) typeInfo(ty types.Type) llvm.Value {
+	for _, info := range c.typeInfos {
+		if types.Match(info.ty, ty) {
+			return info.value
+		}
+	}
+
+	infoType := c.llvmType(types.TypeInfo)
+	global := llvm.AddGlobal(c.currentModule, infoType, "typeinfo."+ty.String())
+	global.SetLinkage(llvm.PrivateLinkage)
+	global.SetGlobalConstant(true)
+
+	handle := llvm.ConstBitCast(global, c.llvmType(types.RuntimeType))
+	// The entry is registered before its initialiser is built,
+	// so that recursive types can refer to themselves
+	c.typeInfos = append(c.typeInfos, typeInfo{ty: ty, value: handle})
+
+	i64 := c.context.Int64Type()
+	global.SetInitializer(c.context.ConstStruct([]llvm.Value{
+		c.constString(ty.String()),
+		llvm.ConstInt(i64, uint64(types.ByteSize(ty, c.target)), false),
+		llvm.ConstInt(i64, uint64(types.Alignment(ty, c.target)), false),
+		llvm.ConstInt(c.llvmType(types.TypeKindEnum), uint64(types.KindOf(ty)), false),
+		c.constList(types.TypeField, c.fieldInfo(ty)),
+		c.constList(types.TypeVariant, c.variantInfo(ty)),
+		c.constList(types.TypeMethod, c.methodInfo(ty)),
+	}, false))
+
+	return handle
+}
+
+func (c *compiler) constString(value string) llvm.Value {
+	str := c.context.ConstString(value, true)
+	global := llvm.AddGlobal(c.currentModule, str.Type(), ".str_const")
+	global.SetLinkage(llvm.PrivateLinkage)
+	global.SetUnnamedAddr(true)
+	global.SetGlobalConstant(true)
+	global.SetInitializer(str)
+	return llvm.ConstBitCast(global, c.llvmType(types.String))
+}
+
+func (c *compiler) constList(elemType types.Type, elems []llvm.Value) llvm.Value {
+	length := llvm.ConstInt(c.llvmType(types.Usize(c.target)), uint64(len(elems)), false)
+	ptrType := llvm.PointerType(c.llvmType(elemType), 0)
+
+	var data llvm.Value
+	if len(elems) == 0 {
+		data = llvm.ConstNull(ptrType)
+	} else {
+		array := llvm.ConstArray(c.llvmType(elemType), elems)
+		global := llvm.AddGlobal(c.currentModule, array.Type(), ".list_const")
+		global.SetLinkage(llvm.PrivateLinkage)
+		global.SetGlobalConstant(true)
+		global.SetInitializer(array)
+		data = llvm.ConstBitCast(global, ptrType)
+	}
+
+	return c.context.ConstStruct([]llvm.Value{length, length, data}, false)
+}
+
+func (c *compiler) fieldInfo(ty types.Type) []llvm.Value {
+	var names []string
+	var fieldTypes []types.Type
+
+	switch ty := types.Unwrap(ty).(type) {
+	case *types.Struct:
+		for _, name := range ty.FieldOrder {
+			names = append(names, name)
+			fieldTypes = append(fieldTypes, ty.Fields[name].Type)
+		}
+	case *types.TupleStruct:
+		fieldTypes = ty.Types
+	case *types.TupleType:
+		fieldTypes = ty.Types
+	default:
+		return nil
+	}
+
+	offsets := types.FieldOffsets(fieldTypes, c.target)
+	fields := make([]llvm.Value, 0, len(fieldTypes))
+	for i, fieldType := range fieldTypes {
+		name := fmt.Sprint(i)
+		if names != nil {
+			name = names[i]
+		}
+		fields = append(fields, c.context.ConstStruct([]llvm.Value{
+			c.constString(name),
+			c.typeInfo(fieldType),
+			llvm.ConstInt(c.context.Int64Type(), uint64(offsets[i]), false),
+		}, false))
+	}
+	return fields
+}
+
+func (c *compiler) variantInfo(ty types.Type) []llvm.Value {
+	variants := []llvm.Value{}
+
+	switch ty := types.Unwrap(ty).(type) {
+	case *types.Union:
+		for _, name := range ty.MemberNames() {
+			var variantType types.Type = ty.Members[name]
+			if variant, ok := variantType.(*types.UnionVariant); ok {
+				variantType = variant.Type
+			}
+			variants = append(variants, c.variant(name, variantType))
+		}
+	case *types.Enum:
+		names := make([]string, 0, len(ty.Members))
+		for name := range ty.Members {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			variants = append(variants, c.variant(name, ty.Underlying))
+		}
+	}
+
+	return variants
+}
+
+func (c *compiler) variant(name string, ty types.Type) llvm.Value {
+	return c.context.ConstStruct([]llvm.Value{c.constString(name), c.typeInfo(ty)}, false)
+}
+
+func (c *compiler) methodInfo(ty types.Type) []llvm.Value {
+	methods := []llvm.Value{}
+	for _, fn := range c.methods {
+		if types.Match(fn.MethodOf, ty) {
+			methods = append(methods, c.context.ConstStruct([]llvm.Value{
+				c.constString(fn.Name),
+				c.typeInfo(fn.Type),
+			}, false))
+		}
+	}
+	return methods
+}
+
+// Reads a member from the type-info table entry a `Type` points to
+func (c *compiler) compileTypeInfoMember(expr *ir.MemberExpression) value {
+	handle := c.compileExpression(expr.Left, true).toRValue(c)
+	infoType := c.llvmType(types.TypeInfo)
+	info := c.builder.CreateBitCast(handle, llvm.PointerType(infoType, 0), "type_info")
+	index := slices.Index(types.TypeInfo.FieldOrder, expr.Member)
+	member := c.builder.CreateStructGEP(infoType, info, index, expr.Member)
+
+	return deref{
+		value: member,
+		ty:    c.llvmType(types.TypeInfo.Fields[expr.Member].Type),
+	}
+}
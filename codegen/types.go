@@ -0,0 +1,118 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"tinygo.org/x/go-llvm"
+)
+
+// The LLVM type used to represent values of a type
+func (c *compiler) llvmType(ty types.Type) llvm.Type {
+	switch ty := ty.(type) {
+	case types.PrimaryType:
+		switch ty {
+		case types.Invalid:
+			panic("Type should not be invalid at this point")
+		case types.Bool:
+			return c.context.Int1Type()
+		case types.String:
+			// TODO: Use proper strings, not cstrings
+			return llvm.PointerType(c.context.Int8Type(), 0)
+		case types.RuntimeType:
+			// A pointer into the type-info table
+			return llvm.PointerType(c.context.Int8Type(), 0)
+		case types.Never:
+			panic(unsupported("the never type"))
+		default:
+			panic("Unreachable")
+		}
+
+	case types.Numeric:
+		if ty.Kind == types.NumFloat {
+			switch ty.BitWidth {
+			case 16:
+				panic(unsupported("f16 values"))
+			case 32:
+				return c.context.FloatType()
+			case 64:
+				return c.context.DoubleType()
+			default:
+				panic("Invalid float bit-width")
+			}
+		}
+		switch ty.BitWidth {
+		case 8, 16, 32, 64:
+			return c.context.IntType(ty.BitWidth)
+		default:
+			panic("Invalid int bit-width")
+		}
+
+	case *types.ListType:
+		usize := c.llvmType(types.Usize(c.target))
+		return c.context.StructType([]llvm.Type{
+			usize,
+			usize,
+			llvm.PointerType(c.llvmType(ty.ElemType), 0),
+		}, false)
+	case *types.ArrayType:
+		return llvm.ArrayType(c.llvmType(ty.ElemType), ty.Length)
+	case *types.TupleType:
+		return c.aggregateType(ty.Types)
+	case *types.TupleStruct:
+		return c.aggregateType(ty.Types)
+	case *types.Struct:
+		fields := make([]types.Type, 0, len(ty.FieldOrder))
+		for _, name := range ty.FieldOrder {
+			fields = append(fields, ty.Fields[name].Type)
+		}
+		return c.aggregateType(fields)
+	case *types.Union:
+		if !ty.Untagged {
+			panic(unsupported("tagged unions"))
+		}
+		// Untagged unions are laid out like C unions, so all members overlap.
+		// We use the most strictly aligned member to represent it, so that the
+		// union has the same alignment, followed by padding to fill the rest.
+		var aligned types.Type = types.Void
+		for _, name := range ty.MemberNames() {
+			member := types.Unwrap(ty.Members[name])
+			align := types.Alignment(member, c.target)
+			alignedAlign := types.Alignment(aligned, c.target)
+			if align > alignedAlign || (align == alignedAlign &&
+				types.ByteSize(member, c.target) > types.ByteSize(aligned, c.target)) {
+				aligned = member
+			}
+		}
+
+		padding := types.ByteSize(ty, c.target) - types.ByteSize(aligned, c.target)
+		if padding == 0 {
+			return c.llvmType(aligned)
+		}
+		return c.context.StructType([]llvm.Type{
+			c.llvmType(aligned),
+			llvm.ArrayType(c.context.Int8Type(), padding),
+		}, false)
+	case *types.Pointer:
+		return llvm.PointerType(c.llvmType(ty.Underlying), 0)
+	case *types.Builder:
+		return c.llvmType(ty.Struct)
+	case *types.Enum:
+		return c.llvmType(ty.Underlying)
+	case *types.Alias:
+		return c.llvmType(ty.Type)
+	case *types.Explicit:
+		return c.llvmType(ty.Type)
+
+	default:
+		panic(unsupported(fmt.Sprintf("values of type %s", ty.String())))
+	}
+}
+
+func (c *compiler) aggregateType(fields []types.Type) llvm.Type {
+	llvmTypes := make([]llvm.Type, 0, len(fields))
+	for _, field := range fields {
+		llvmTypes = append(llvmTypes, c.llvmType(field))
+	}
+	return c.context.StructType(llvmTypes, false)
+}
@@ -0,0 +1,181 @@
+package codegen
+
+import (
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"tinygo.org/x/go-llvm"
+)
+
+// Programs running under WASI don't have libc, so a minimal runtime is
+// compiled into them instead, which provides `print`, `alloc` and an entry
+// point on top of the imports WASI provides.
+
+const wasiModule = "wasi_snapshot_preview1"
+const wasmPageSize = 65536
+
+func (c *compiler) compileWasiRuntime() {
+	c.currentModule = c.context.NewModule("runtime")
+
+	c.compileWasiPrint()
+	c.compileWasiAlloc()
+	c.compileWasiStart()
+
+	c.verifyModule(nil)
+	c.linkModule()
+}
+
+// Declares a function imported from a WebAssembly module
+func importFunction(module llvm.Module, importModule, name, symbol string, ty llvm.Type) llvm.Value {
+	function := llvm.AddFunction(module, symbol, ty)
+	function.AddTargetDependentFunctionAttr("wasm-import-module", importModule)
+	function.AddTargetDependentFunctionAttr("wasm-import-name", name)
+	return function
+}
+
+// Writes a string to stdout, followed by a newline
+func (c *compiler) compileWasiPrint() {
+	i8 := c.context.Int8Type()
+	i32 := c.context.Int32Type()
+	str := c.llvmType(types.String)
+	iovec := c.context.StructType([]llvm.Type{str, i32}, false)
+
+	fdWriteType := llvm.FunctionType(i32, []llvm.Type{
+		i32,
+		llvm.PointerType(iovec, 0),
+		i32,
+		llvm.PointerType(i32, 0),
+	}, false)
+	fdWrite := importFunction(c.currentModule, wasiModule, "fd_write", "__wasi_fd_write", fdWriteType)
+
+	printType := llvm.FunctionType(c.context.VoidType(), []llvm.Type{str}, false)
+	print := llvm.AddFunction(c.currentModule, "print", printType)
+	value := print.Param(0)
+	value.SetName("value")
+
+	entry := c.context.AddBasicBlock(print, "entry")
+	strlen := c.context.AddBasicBlock(print, "strlen")
+	write := c.context.AddBasicBlock(print, "write")
+
+	c.builder.SetInsertPointAtEnd(entry)
+	c.builder.CreateBr(strlen)
+
+	// Strings are still null-terminated, so their length has to be counted
+	c.builder.SetInsertPointAtEnd(strlen)
+	length := c.builder.CreatePHI(i32, "length")
+	charPtr := c.builder.CreateGEP(i8, value, []llvm.Value{length}, "char_ptr")
+	char := c.builder.CreateLoad(i8, charPtr, "char")
+	next := c.builder.CreateAdd(length, llvm.ConstInt(i32, 1, false), "next")
+	length.AddIncoming(
+		[]llvm.Value{llvm.ConstInt(i32, 0, false), next},
+		[]llvm.BasicBlock{entry, strlen},
+	)
+	isEnd := c.builder.CreateICmp(llvm.IntEQ, char, llvm.ConstInt(i8, 0, false), "is_end")
+	c.builder.CreateCondBr(isEnd, write, strlen)
+
+	c.builder.SetInsertPointAtEnd(write)
+	iovecsType := llvm.ArrayType(iovec, 2)
+	iovecs := c.builder.CreateAlloca(iovecsType, "iovecs")
+	buffers := []llvm.Value{value, c.constString("\n")}
+	lengths := []llvm.Value{length, llvm.ConstInt(i32, 1, false)}
+	for i := range buffers {
+		iovecPtr := c.builder.CreateGEP(iovecsType, iovecs, []llvm.Value{
+			llvm.ConstInt(i32, 0, false),
+			llvm.ConstInt(i32, uint64(i), false),
+		}, "iovec")
+		iovecValue := c.builder.CreateInsertValue(llvm.Undef(iovec), buffers[i], 0, "")
+		iovecValue = c.builder.CreateInsertValue(iovecValue, lengths[i], 1, "")
+		c.builder.CreateStore(iovecValue, iovecPtr)
+	}
+
+	written := c.builder.CreateAlloca(i32, "written")
+	firstIovec := c.builder.CreateGEP(iovecsType, iovecs, []llvm.Value{
+		llvm.ConstInt(i32, 0, false),
+		llvm.ConstInt(i32, 0, false),
+	}, "iovecs_ptr")
+	stdout := llvm.ConstInt(i32, 1, false)
+	c.builder.CreateCall(fdWriteType, fdWrite, []llvm.Value{
+		stdout, firstIovec, llvm.ConstInt(i32, uint64(len(buffers)), false), written,
+	}, "")
+	c.builder.CreateRetVoid()
+}
+
+// A bump allocator, which starts allocating at the end of static data and
+// grows linear memory as needed. Memory is never freed.
+func (c *compiler) compileWasiAlloc() {
+	i8 := c.context.Int8Type()
+	usize := c.llvmType(types.Usize(c.target))
+	ptr := llvm.PointerType(i8, 0)
+
+	// Defined by the linker, at the end of static data
+	heapBase := llvm.AddGlobal(c.currentModule, i8, "__heap_base")
+	heapTop := llvm.AddGlobal(c.currentModule, usize, "libra.heap_top")
+	heapTop.SetLinkage(llvm.InternalLinkage)
+	heapTop.SetInitializer(llvm.ConstInt(usize, 0, false))
+
+	// Memory is counted in pages of pointer size, so wasm64 uses the 64-bit
+	// versions of the intrinsics. The index of the memory is always 32-bit.
+	i32 := c.context.Int32Type()
+	suffix := ".i32"
+	if c.target.PointerSize == 8 {
+		suffix = ".i64"
+	}
+	memorySizeType := llvm.FunctionType(usize, []llvm.Type{i32}, false)
+	memorySize := llvm.AddFunction(c.currentModule, "llvm.wasm.memory.size"+suffix, memorySizeType)
+	memoryGrowType := llvm.FunctionType(usize, []llvm.Type{i32, usize}, false)
+	memoryGrow := llvm.AddFunction(c.currentModule, "llvm.wasm.memory.grow"+suffix, memoryGrowType)
+
+	allocType := llvm.FunctionType(ptr, []llvm.Type{usize}, false)
+	alloc := llvm.AddFunction(c.currentModule, "alloc", allocType)
+	size := alloc.Param(0)
+	size.SetName("size")
+
+	entry := c.context.AddBasicBlock(alloc, "entry")
+	grow := c.context.AddBasicBlock(alloc, "grow")
+	failed := c.context.AddBasicBlock(alloc, "failed")
+	done := c.context.AddBasicBlock(alloc, "done")
+
+	zero := llvm.ConstInt(usize, 0, false)
+	memoryIndex := llvm.ConstInt(i32, 0, false)
+	align := uint64(c.target.MaxAlignment)
+
+	c.builder.SetInsertPointAtEnd(entry)
+	top := c.builder.CreateLoad(usize, heapTop, "top")
+	isFirst := c.builder.CreateICmp(llvm.IntEQ, top, zero, "is_first")
+	start := c.builder.CreateSelect(isFirst, llvm.ConstPtrToInt(heapBase, usize), top, "start")
+	start = c.builder.CreateAdd(start, llvm.ConstInt(usize, align-1, false), "")
+	start = c.builder.CreateAnd(start, llvm.ConstNot(llvm.ConstInt(usize, align-1, false)), "aligned")
+	end := c.builder.CreateAdd(start, size, "end")
+	pages := c.builder.CreateCall(memorySizeType, memorySize, []llvm.Value{memoryIndex}, "pages")
+	pageSize := llvm.ConstInt(usize, wasmPageSize, false)
+	memoryEnd := c.builder.CreateMul(pages, pageSize, "memory_end")
+	needsGrow := c.builder.CreateICmp(llvm.IntUGT, end, memoryEnd, "needs_grow")
+	c.builder.CreateCondBr(needsGrow, grow, done)
+
+	c.builder.SetInsertPointAtEnd(grow)
+	missing := c.builder.CreateSub(end, memoryEnd, "missing")
+	missing = c.builder.CreateAdd(missing, llvm.ConstInt(usize, wasmPageSize-1, false), "")
+	newPages := c.builder.CreateUDiv(missing, pageSize, "new_pages")
+	result := c.builder.CreateCall(memoryGrowType, memoryGrow, []llvm.Value{memoryIndex, newPages}, "result")
+	// `memory.grow` returns -1 if there isn't enough memory
+	hasFailed := c.builder.CreateICmp(llvm.IntEQ, result, llvm.ConstAllOnes(usize), "has_failed")
+	c.builder.CreateCondBr(hasFailed, failed, done)
+
+	c.builder.SetInsertPointAtEnd(failed)
+	c.builder.CreateRet(llvm.ConstNull(ptr))
+
+	c.builder.SetInsertPointAtEnd(done)
+	c.builder.CreateStore(end, heapTop)
+	c.builder.CreateRet(c.builder.CreateIntToPtr(start, ptr, "allocated"))
+}
+
+// The entry point of a WASI command, which runs the program's main function
+func (c *compiler) compileWasiStart() {
+	startType := llvm.FunctionType(c.context.VoidType(), []llvm.Type{}, false)
+	start := llvm.AddFunction(c.currentModule, "_start", startType)
+	c.builder.SetInsertPointAtEnd(c.context.AddBasicBlock(start, "entry"))
+
+	if mainFn := c.mainModule.NamedFunction("main"); !mainFn.IsNil() {
+		main := llvm.AddFunction(c.currentModule, "main", mainFn.GlobalValueType())
+		c.builder.CreateCall(mainFn.GlobalValueType(), main, []llvm.Value{}, "")
+	}
+	c.builder.CreateRetVoid()
+}
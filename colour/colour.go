@@ -47,6 +47,6 @@ func SetColour(colour Colour) {
 
 func ResetColour() {
 	if UseColour {
-		fmt.Print(Reset)
+		fmt.Fprint(Writer, Reset)
 	}
 }
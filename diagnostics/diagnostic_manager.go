@@ -2,6 +2,7 @@ package diagnostics
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/gearsdatapacks/libra/lexer/token"
 	"github.com/gearsdatapacks/libra/text"
@@ -13,87 +14,95 @@ func (m *Manager) Report(diagnostic *Diagnostic) {
 	*m = append(*m, *diagnostic)
 }
 
-func (m *Manager) ReportMany(diagnostics []*Diagnostic) {
-	for _, diagnostic := range diagnostics {
-		m.Report(diagnostic)
+// Whether any of the diagnostics are errors, rather than warnings
+// or information, which don't prevent a program from compiling
+func (m Manager) HasErrors() bool {
+	for _, diagnostic := range m {
+		if diagnostic.Kind == Error {
+			return true
+		}
 	}
+	return false
 }
 
-func makeError(msg string, location text.Location) *Diagnostic {
-	return new(Error, msg, location)
+// Each constructor has its own code, which must never change or be
+// reused, as codes are used to look up explanations and to allow or
+// deny diagnostics. Errors start with "E" and warnings with "W".
+func makeError(code, msg string, location text.Location) *Diagnostic {
+	return new(Error, code, msg, location)
 }
 
-func makeInfo(msg string, location text.Location) *Diagnostic {
-	return new(Info, msg, location)
+func makeWarning(code, msg string, location text.Location) *Diagnostic {
+	return new(Warning, code, msg, location)
 }
 
 // Lexer Diagnostics
 
 func InvalidCharacter(location text.Location, char byte) *Diagnostic {
 	msg := fmt.Sprintf("Invalid character: %q", char)
-	return makeError(msg, location)
+	return makeError("E0001", msg, location)
 }
 
 func UnterminatedString(location text.Location) *Diagnostic {
 	const msg = "Unterminated string"
-	return makeError(msg, location)
+	return makeError("E0002", msg, location)
 }
 
 func UnterminatedComment(location text.Location) *Diagnostic {
 	const msg = "Unterminated block comment"
-	return makeError(msg, location)
+	return makeError("E0003", msg, location)
 }
 
 func InvalidEscapeSequence(location text.Location, char byte) *Diagnostic {
 	msg := fmt.Sprintf("Invalid escape sequence: '\\%c'", char)
-	return makeError(msg, location)
+	return makeError("E0004", msg, location)
 }
 
 func ExpectedEscapeSequence(location text.Location) *Diagnostic {
 	const msg = "Expected escape sequence, reached end of file"
-	return makeError(msg, location)
+	return makeError("E0005", msg, location)
 }
 
 func InvalidAsciiSequence(location text.Location, sequence string) *Diagnostic {
 	msg := fmt.Sprintf("Invalid ascii escape sequence: '\\x%s'", sequence)
-	return makeError(msg, location)
+	return makeError("E0006", msg, location)
 }
 
 func InvalidUnicodeSequence(location text.Location, sequence string) *Diagnostic {
 	msg := fmt.Sprintf("Invalid unicode escape sequence: '\\x%s'", sequence)
-	return makeError(msg, location)
+	return makeError("E0007", msg, location)
 }
 
 func NumbersCannotEndWithSeparator(location text.Location) *Diagnostic {
 	const msg = "Numbers cannot end with numeric separators"
-	return makeError(msg, location)
+	return makeError("E0008", msg, location)
 }
 
 func RadixMustBeFollowedByNumber(location text.Location) *Diagnostic {
 	const msg = "Radix specifiers must be followed by valid digits"
-	return makeError(msg, location)
+	return makeError("E0009", msg, location)
 }
 
 // Parser Diagnostics
 
 func ExpectedExpression(location text.Location, kind token.Kind) *Diagnostic {
 	msg := fmt.Sprintf("Expected expression, found %s", kind.String())
-	return makeError(msg, location)
+	return makeError("E0010", msg, location)
 }
 
 func ExpectedNewline(location text.Location, kind token.Kind) *Diagnostic {
 	msg := fmt.Sprintf("Expected newline after statement, found %s", kind.String())
-	return makeError(msg, location)
+	return makeError("E0011", msg, location)
 }
 
 func ExpectedToken(location text.Location, expected token.Kind, actual token.Kind) *Diagnostic {
 	msg := fmt.Sprintf("Expected %s, found %s", expected.String(), actual.String())
-	return makeError(msg, location)
+	return makeError("E0012", msg, location)
 }
 
 func ElseStatementWithoutIf(location text.Location) *Diagnostic {
 	const msg = "Else statement not allowed without preceding if"
-	return makeError(msg, location)
+	return makeError("E0013", msg, location)
 }
 
 func ExpectedKeyword(location text.Location, keyword string, foundToken token.Token) *Diagnostic {
@@ -103,100 +112,94 @@ func ExpectedKeyword(location text.Location, keyword string, foundToken token.To
 	}
 
 	msg := fmt.Sprintf("Expected %q keyword, found %s", keyword, tokenValue)
-	return makeError(msg, location)
+	return makeError("E0014", msg, location)
 }
 
-func KeywordOverwritten(location text.Location, keyword string, declared text.Location) []*Diagnostic {
+func KeywordOverwritten(location text.Location, keyword string, declared text.Location) *Diagnostic {
 	errMsg := fmt.Sprintf(
 		"Expected %q keyword, but it has been overwritten by a variable",
 		keyword)
 	const info = "Try removing or renaming this variable"
 
-	return []*Diagnostic{makeError(errMsg, location), makeInfo(info, declared)}
+	return makeError("E0015", errMsg, location).WithLabel(declared, info)
 }
 
-func LastParameterMustHaveType(location text.Location, fnLocation text.Location) []*Diagnostic {
+func LastParameterMustHaveType(location text.Location, fnLocation text.Location) *Diagnostic {
 	const msg = "The last parameter of a function must have a type annotation"
-	diagnostics := []*Diagnostic{makeError(msg, location)}
+	const info = "Parameter of this function"
 
-	fieldLine := location.Span.ToLineSpan(location.File).StartLine
-	fnLine := fnLocation.Span.ToLineSpan(location.File).StartLine
-	if fieldLine != fnLine {
-		const info = "Parameter of this function"
-		diagnostics = append(diagnostics, makeInfo(info, fnLocation))
-	}
-	return diagnostics
+	return makeError("E0016", msg, location).WithLabel(fnLocation, info)
 }
 
 func MutWithoutParamName(location text.Location) *Diagnostic {
 	msg := `"mut" must be followed by a parameter name`
-	return makeError(msg, location)
+	return makeError("E0017", msg, location)
 }
 
-func LastStructFieldMustHaveType(location text.Location, structLoc text.Location) []*Diagnostic {
+func LastStructFieldMustHaveType(location text.Location, structLoc text.Location) *Diagnostic {
 	const errMsg = "The last field of a struct must have a type annotation"
-	diagnostics := []*Diagnostic{makeError(errMsg, location)}
+	const info = "Field in this struct"
 
-	fieldLine := location.Span.ToLineSpan(location.File).StartLine
-	structLine := structLoc.Span.ToLineSpan(location.File).StartLine
-	if fieldLine != structLine {
-		const info = "Field in this struct"
-		diagnostics = append(diagnostics, makeInfo(info, structLoc))
-	}
-	return diagnostics
+	return makeError("E0018", errMsg, location).WithLabel(structLoc, info)
 }
 
 func MemberAndMethodNotAllowed(location text.Location) *Diagnostic {
 	const msg = "Functions cannot be both methods and static members"
 
-	return makeError(msg, location)
+	return makeError("E0019", msg, location)
 }
 
 func ExpectedMemberOrStructBody(location text.Location, tok token.Token) *Diagnostic {
 	msg := fmt.Sprintf("Invalid right-hand side of expression. Expected identifier or struct body, found %s", tok.Kind.String())
 
-	return makeError(msg, location)
+	return makeError("E0020", msg, location)
 }
 
 func OneImportModifierAllowed(location text.Location) *Diagnostic {
 	const msg = "Only one import modifier is allowed"
 
-	return makeError(msg, location)
+	return makeError("E0021", msg, location)
 }
 
 func OnlyTopLevelStatement(location text.Location, stmtKind string) *Diagnostic {
 	msg := fmt.Sprintf("%s not allowed here", stmtKind)
 
-	return makeError(msg, location)
+	return makeError("E0022", msg, location)
 }
 
 func ExpectedType(location text.Location, kind token.Kind) *Diagnostic {
 	msg := fmt.Sprintf("Expected type, found %s", kind.String())
-	return makeError(msg, location)
+	return makeError("E0023", msg, location)
 }
 
 func CannotExport(location text.Location) *Diagnostic {
 	const msg = "Only top-level declarations can be exported"
 
-	return makeError(msg, location)
+	return makeError("E0024", msg, location)
 }
 
 func CannotExplicit(location text.Location) *Diagnostic {
 	const msg = "Statement cannot be marked explicit"
 
-	return makeError(msg, location)
+	return makeError("E0025", msg, location)
 }
 
 func InvalidAttribute(location text.Location, name string) *Diagnostic {
 	msg := fmt.Sprintf("The attribute %q does not exist", name)
 
-	return makeError(msg, location)
+	return makeError("E0026", msg, location)
 }
 
 func CannotAttribute(location text.Location, attribute string) *Diagnostic {
 	msg := fmt.Sprintf("Statement cannot be marked with attribute %q", attribute)
 
-	return makeError(msg, location)
+	return makeError("E0027", msg, location)
+}
+
+func UnknownWarningCode(location text.Location, code string) *Diagnostic {
+	msg := fmt.Sprintf("Unknown warning code %q", code)
+
+	return makeWarning("W0010", msg, location).suggest(code, warningCodes())
 }
 
 // Type-checker Diagnostics
@@ -205,53 +208,53 @@ type tcType interface {
 	String() string
 }
 
-func UndefinedType(location text.Location, name string) *Diagnostic {
+func UndefinedType(location text.Location, name string, candidates []string) *Diagnostic {
 	msg := fmt.Sprintf("Type %q is not defined", name)
-	return makeError(msg, location)
+	return makeError("E0028", msg, location).suggest(name, candidates)
 }
 
 func NotAssignable(location text.Location, expected, actual tcType) *Diagnostic {
 	msg := fmt.Sprintf("Value of type %q is not assignable to type %q", actual.String(), expected.String())
-	return makeError(msg, location)
+	return makeError("E0029", msg, location)
 }
 
 func VariableDefined(location text.Location, name string) *Diagnostic {
 	msg := fmt.Sprintf("Variable %q is already defined", name)
-	return makeError(msg, location)
+	return makeError("E0030", msg, location)
 }
 
-func VariableUndefined(location text.Location, name string) *Diagnostic {
+func VariableUndefined(location text.Location, name string, candidates []string) *Diagnostic {
 	msg := fmt.Sprintf("Variable %q is not defined", name)
-	return makeError(msg, location)
+	return makeError("E0031", msg, location).suggest(name, candidates)
 }
 
 func BinaryOperatorUndefined(location text.Location, operator string, left, right tcType) *Diagnostic {
 	msg := fmt.Sprintf("Operator %q is not defined for types %q and %q", operator, left.String(), right.String())
-	return makeError(msg, location)
+	return makeError("E0032", msg, location)
 }
 
 func UnaryOperatorUndefined(location text.Location, operator string, operand tcType) *Diagnostic {
 	msg := fmt.Sprintf("Operator %s is not defined for operand of type %q", operator, operand.String())
-	return makeError(msg, location)
+	return makeError("E0033", msg, location)
 }
 
 func CannotCast(location text.Location, from, to tcType) *Diagnostic {
 	msg := fmt.Sprintf("Cannot cast value of type %q to type %q", from.String(), to.String())
-	return makeError(msg, location)
+	return makeError("E0034", msg, location)
 }
 
 func CannotIncDec(incDec string) *Partial {
 	msg := fmt.Sprintf("Cannot %s a non-variable value", incDec)
-	return partial(Error, msg)
+	return partial(Error, "E0035", msg)
 }
 
-var ValueImmutablePartial = partial(Error, "Cannot modify value, it is immutable")
+var ValueImmutablePartial = partial(Error, "E0036", "Cannot modify value, it is immutable")
 
 func ValueImmutable(location text.Location) *Diagnostic {
 	return ValueImmutablePartial.Location(location)
 }
 
-var NotConstPartial = partial(Error, "Value must be known at compile time")
+var NotConstPartial = partial(Error, "E0037", "Value must be known at compile time")
 
 func NotConst(location text.Location) *Diagnostic {
 	return NotConstPartial.Location(location)
@@ -259,182 +262,208 @@ func NotConst(location text.Location) *Diagnostic {
 
 func CountMustBeInt(location text.Location) *Diagnostic {
 	const msg = "Array length must be an integer"
-	return makeError(msg, location)
+	return makeError("E0038", msg, location)
 }
 
 func CannotIndex(leftType, indexType tcType) *Partial {
 	msg := fmt.Sprintf("Cannot index value of type %q with value of type %q", leftType.String(), indexType.String())
-	return partial(Error, msg)
+	return partial(Error, "E0039", msg)
 }
 
 func NotHashable(location text.Location, ty tcType) *Diagnostic {
 	msg := fmt.Sprintf("Value of type %q cannot be used as a key in a map", ty.String())
-	return makeError(msg, location)
+	return makeError("E0040", msg, location)
+}
+
+func AmbiguousVariant(location text.Location, union, ty tcType, candidates []string) *Diagnostic {
+	quoted := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		quoted = append(quoted, fmt.Sprintf("%q", candidate))
+	}
+	msg := fmt.Sprintf(
+		"Value of type %q could be any of the variants %s of union %q",
+		ty.String(),
+		strings.Join(quoted, ", "),
+		union.String(),
+	)
+	return makeError("E0041", msg, location)
 }
 
 func CannotAssign(location text.Location) *Diagnostic {
 	const msg = "Cannot assign to a non-variable value"
-	return makeError(msg, location)
+	return makeError("E0042", msg, location)
 }
 
 func IndexOutOfBounds(index, len int64) *Partial {
 	msg := fmt.Sprintf("Index %d is out of bounds of array of length %d", index, len)
-	return partial(Error, msg)
+	return partial(Error, "E0043", msg)
 }
 
 func ConditionMustBeBool(location text.Location) *Diagnostic {
 	const msg = "Condition must be a boolean"
-	return makeError(msg, location)
+	return makeError("E0044", msg, location)
 }
 
 func NotIterable(location text.Location) *Diagnostic {
 	const msg = "Value is not iterable"
-	return makeError(msg, location)
+	return makeError("E0045", msg, location)
 }
 
 func NoReturnOutsideFunction(location text.Location) *Diagnostic {
 	const msg = "Cannot use return outside of a function"
-	return makeError(msg, location)
+	return makeError("E0046", msg, location)
 }
 
 func ExpectedReturnValue(location text.Location) *Diagnostic {
 	const msg = "Expected a return value"
-	return makeError(msg, location)
+	return makeError("E0047", msg, location)
 }
 
 func NotCallable(location text.Location, ty tcType) *Diagnostic {
 	msg := fmt.Sprintf("Value of type %q cannot be called", ty.String())
-	return makeError(msg, location)
+	return makeError("E0048", msg, location)
 }
 
 func WrongNumberArguments(location text.Location, expected, actual int) *Diagnostic {
 	msg := fmt.Sprintf("Incorrect number of arguments (expected %d, found %d)", expected, actual)
-	return makeError(msg, location)
+	return makeError("E0049", msg, location)
 }
 
-func NoMember(leftType tcType, member string) *Partial {
+func NoMember(leftType tcType, member string, candidates []string) *Partial {
 	msg := fmt.Sprintf("Value of type %q does not have member %q", leftType.String(), member)
-	return partial(Error, msg)
+	return partial(Error, "E0050", msg).suggest(member, candidates)
 }
 
 func FieldPrivate(leftType tcType, member string) *Partial {
 	msg := fmt.Sprintf("Field %q of type %q is private", member, leftType.String())
-	return partial(Error, msg)
+	return partial(Error, "E0051", msg)
 }
 
 func OnlyConstructTypes(location text.Location) *Diagnostic {
 	const msg = "Cannot construct value, not a type"
-	return makeError(msg, location)
+	return makeError("E0052", msg, location)
 }
 
 func CannotConstruct(location text.Location, ty tcType) *Diagnostic {
 	msg := fmt.Sprintf("Cannot construct value of type %q", ty.String())
-	return makeError(msg, location)
+	return makeError("E0053", msg, location)
 }
 
-func NoStructMember(location text.Location, name, member string) *Diagnostic {
+func NoStructMember(location text.Location, name, member string, candidates []string) *Diagnostic {
 	msg := fmt.Sprintf("Struct %q does not have member %q", name, member)
-	return makeError(msg, location)
+	return makeError("E0054", msg, location).suggest(member, candidates)
 }
 
 func CannotUseStatementOutsideLoop(location text.Location, stmtKind string) *Diagnostic {
 	msg := fmt.Sprintf("Cannot use %s outside of a loop", stmtKind)
-	return makeError(msg, location)
+	return makeError("E0055", msg, location)
 }
 
 func CannotUseStatementOutsideBlock(location text.Location, stmtKind string) *Diagnostic {
 	msg := fmt.Sprintf("Cannot use %s outside of a block", stmtKind)
-	return makeError(msg, location)
+	return makeError("E0056", msg, location)
 }
 
 func ExpressionNotType(location text.Location, ty tcType) *Diagnostic {
 	msg := fmt.Sprintf("Expected a type, found value of type %q", ty.String())
-	return makeError(msg, location)
+	return makeError("E0057", msg, location)
 }
 
 func NamedParamInFnType(location text.Location) *Diagnostic {
 	const msg = "Parameters in function types must be unnamed"
-	return makeError(msg, location)
+	return makeError("E0058", msg, location)
 }
 
 func UnnamedParameter(location text.Location) *Diagnostic {
 	const msg = "Unnamed parameters are only allowed in function types"
-	return makeError(msg, location)
+	return makeError("E0059", msg, location)
 }
 
 func NoExport(location text.Location, module, member string) *Diagnostic {
 	msg := fmt.Sprintf("Module %q does not export member %q", module, member)
-	return makeError(msg, location)
+	return makeError("E0060", msg, location)
 }
 
 func CannotDeref(location text.Location, ty tcType) *Diagnostic {
 	msg := fmt.Sprintf("Cannot dereference non-pointer value of type %q", ty.String())
-	return makeError(msg, location)
+	return makeError("E0061", msg, location)
 }
 
 func MutRefOfNotMut(location text.Location) *Diagnostic {
 	const msg = "Cannot take a mutable reference to an immutable value"
-	return makeError(msg, location)
+	return makeError("E0062", msg, location)
 }
 
 func MixedNamedUnnamedStructFields(location text.Location) *Diagnostic {
 	const msg = "Cannot mix named and unnamed struct fields"
-	return makeError(msg, location)
+	return makeError("E0063", msg, location)
 }
 
 func WrongNumberTupleValues(location text.Location, expected, found int) *Diagnostic {
 	msg := fmt.Sprintf("Incorrect number of values supplied to struct (expected %d, found %d)", expected, found)
-	return makeError(msg, location)
+	return makeError("E0064", msg, location)
 }
 
 func TupleStructWithNames(location text.Location) *Diagnostic {
 	const msg = "Field names not allowed when constructing tuple structs"
-	return makeError(msg, location)
+	return makeError("E0065", msg, location)
 }
 
 func NoNameStructMember(location text.Location) *Diagnostic {
 	const msg = "Struct members must all be named"
-	return makeError(msg, location)
+	return makeError("E0066", msg, location)
 }
 
 func PubUnnamedStructField(location text.Location) *Diagnostic {
 	const msg = "`pub` keyword not allowed for unnamed fields"
-	return makeError(msg, location)
+	return makeError("E0067", msg, location)
 }
 
-func NoVariant(unionName, member string) *Partial {
+func NoVariant(unionName, member string, candidates []string) *Partial {
 	msg := fmt.Sprintf("Union %q has no variant %q", unionName, member)
-	return partial(Error, msg)
+	return partial(Error, "E0068", msg).suggest(member, candidates)
+}
+
+func UntaggedTypeCheck(location text.Location, ty tcType) *Diagnostic {
+	msg := fmt.Sprintf("Union %q is untagged, so its variant cannot be checked", ty.String())
+	return makeError("E0069", msg, location)
 }
 
 func NotATag(location text.Location, ty tcType) *Diagnostic {
 	msg := fmt.Sprintf("%q is not a tag", ty.String())
-	return makeError(msg, location)
+	return makeError("E0070", msg, location)
 }
 
-func ModuleUndefined(location text.Location, module string) *Diagnostic {
-	msg := fmt.Sprintf("The module %s does not exist", module)
-	return makeError(msg, location)
+func ModuleUndefined(location text.Location, module string, candidates []string) *Diagnostic {
+	msg := fmt.Sprintf("The module %q does not exist", module)
+	diagnostic := makeError("E0071", msg, location)
+	// The location includes the quotes around the module's path
+	if match, ok := closestMatch(module, candidates); ok {
+		fix := didYouMean(location, match)
+		fix.Edits[0].Replacement = fmt.Sprintf("%q", match)
+		diagnostic.WithFix(fix)
+	}
+	return diagnostic
 }
 
 func ExpressionIndexWithoutIndex(location text.Location) *Diagnostic {
 	const msg = "Index expressions which aren't list types must have an index"
-	return makeError(msg, location)
+	return makeError("E0072", msg, location)
 }
 
 func NoPropagateOutsideFunction() *Partial {
 	const msg = "Cannot propagate errors outside of a function"
-	return partial(Error, msg)
+	return partial(Error, "E0073", msg)
 }
 
 func PropagateFnMustReturnResult() *Partial {
 	const msg = "Can only propagate errors in functions which return result types"
-	return partial(Error, msg)
+	return partial(Error, "E0074", msg)
 }
 
 func PropagateFnMustReturnOption() *Partial {
 	const msg = "Can only propagate void options in functions which return option types"
-	return partial(Error, msg)
+	return partial(Error, "E0075", msg)
 }
 
 func CannotEnum(location text.Location, ty tcType) *Diagnostic {
@@ -443,37 +472,171 @@ func CannotEnum(location text.Location, ty tcType) *Diagnostic {
 
 func CannotEnumPartial(ty tcType) *Partial {
 	msg := fmt.Sprintf("Type %q cannot generate enum values automatically", ty)
-	return partial(Error, msg)
+	return partial(Error, "E0076", msg)
 }
 
-func NoEnumMember(name string, member string) *Partial {
+func NoEnumMember(name string, member string, candidates []string) *Partial {
 	msg := fmt.Sprintf("Enum %q has no member %q", name, member)
-	return partial(Error, msg)
+	return partial(Error, "E0077", msg).suggest(member, candidates)
 }
 
 func BranchTypesMustMatch(location text.Location, expected, got tcType) *Diagnostic {
 	msg := fmt.Sprintf("If-else branches must yield matching types. Expected %q, found %q", expected, got)
-	return makeError(msg, location)
+	return makeError("E0078", msg, location)
 }
 
 func ExternWithBody(location text.Location) *Diagnostic {
 	const msg = "Functions marked external cannot have bodies"
-	return makeError(msg, location)
+	return makeError("E0079", msg, location)
+}
+
+func ImportWithoutExtern(location text.Location) *Diagnostic {
+	const msg = "Only functions marked extern can be imported from a module"
+	return makeError("E0080", msg, location)
 }
 
 func NoBody(location text.Location) *Diagnostic {
 	const msg = "Functions must have bodies or be marked extern"
-	return makeError(msg, location)
+	return makeError("E0081", msg, location)
+}
+
+func NotGenerator(location text.Location, ty tcType) *Diagnostic {
+	msg := fmt.Sprintf("Value of type %q is not a method generator", ty.String())
+	return makeError("E0082", msg, location).
+		WithNote("Generators take a type and return the function to add to it, like `fn(ty: Type): fn(ty): string`")
+}
+
+func CannotGenerate(location text.Location, method string, ty tcType) *Diagnostic {
+	msg := fmt.Sprintf("Cannot generate method %q for type %q", method, ty.String())
+	return makeError("E0083", msg, location)
+}
+
+func GeneratorUndefined(location text.Location, name string, candidates []string) *Diagnostic {
+	msg := fmt.Sprintf("Generator %q is not defined", name)
+	return makeError("E0092", msg, location).suggest(name, candidates)
+}
+
+func NotEvaluable(location text.Location) *Diagnostic {
+	const msg = "Statement cannot be run at compile time"
+	return makeError("E0093", msg, location).
+		WithNote("Generators can only declare constants, check constant conditions and return a function")
+}
+
+func NoGeneratedFunction(location text.Location) *Diagnostic {
+	const msg = "Generators must return a function literal"
+	return makeError("E0094", msg, location)
+}
+
+func GeneratorCalled(location text.Location, name string) *Diagnostic {
+	msg := fmt.Sprintf("Generator %q can only be used with `@gen`", name)
+	return makeError("E0095", msg, location)
+}
+
+func BuilderOnlyField(ty tcType, member string) *Partial {
+	msg := fmt.Sprintf("Field %q of type %q can only be accessed while building", member, ty.String())
+	return partial(Error, "E0084", msg)
+}
+
+func NotBuildable(location text.Location, ty tcType) *Diagnostic {
+	msg := fmt.Sprintf("Type %q is not a struct, so cannot be built", ty.String())
+	return makeError("E0085", msg, location)
+}
+
+func BuilderFinalised(location text.Location, name string, finalised text.Location) *Diagnostic {
+	errMsg := fmt.Sprintf("Builder %q cannot be used after it has been finalised", name)
+	const info = "Builder finalised here"
+
+	return makeError("E0086", errMsg, location).WithLabel(finalised, info)
+}
+
+func UsedDeprecated(location text.Location, name string, message *string, declared text.Location) *Diagnostic {
+	warnMsg := fmt.Sprintf("%q is deprecated", name)
+	if message != nil && *message != "" {
+		warnMsg += ": " + *message
+	}
+	const info = "Deprecated here"
+
+	return makeWarning("W0001", warnMsg, location).WithLabel(declared, info)
+}
+
+func UsedTodo(location text.Location, name string, message *string, declared text.Location, isError bool) *Diagnostic {
+	msg := fmt.Sprintf("%q is not yet implemented", name)
+	if message != nil && *message != "" {
+		msg += ": " + *message
+	}
+	const info = "Marked as todo here"
+
+	if isError {
+		return makeError("E0091", msg, location).
+			WithLabel(declared, info).
+			WithNote("Unfinished code cannot be used in release builds")
+	}
+	return makeWarning("W0002", msg, location).WithLabel(declared, info)
+}
+
+func UnusedVariable(location text.Location, name string) *Diagnostic {
+	msg := fmt.Sprintf("Variable %q is never used", name)
+	return makeWarning("W0003", msg, location).WithHelp(ignoreUnusedHelp(name))
+}
+
+func UnreadVariable(location text.Location, name string) *Diagnostic {
+	msg := fmt.Sprintf("Variable %q is assigned to, but never read", name)
+	return makeWarning("W0004", msg, location).WithHelp(ignoreUnusedHelp(name))
+}
+
+func UnnecessaryMut(location text.Location, name string, fix Fix) *Diagnostic {
+	msg := fmt.Sprintf("Variable %q is declared as mutable, but is never modified", name)
+	return makeWarning("W0005", msg, location).WithFix(fix)
+}
+
+func UnusedFunction(location text.Location, name string) *Diagnostic {
+	msg := fmt.Sprintf("Function %q is never used", name)
+	return makeWarning("W0006", msg, location).WithHelp(ignoreUnusedHelp(name))
+}
+
+func UnusedType(location text.Location, name string) *Diagnostic {
+	msg := fmt.Sprintf("Type %q is never used", name)
+	return makeWarning("W0007", msg, location).WithHelp(ignoreUnusedHelp(name))
+}
+
+func ignoreUnusedHelp(name string) string {
+	return fmt.Sprintf("If this is intentional, rename it to %q", "_"+name)
+}
+
+func UnusedImport(location text.Location, module string) *Diagnostic {
+	msg := fmt.Sprintf("Module %q is imported, but never used", module)
+	return makeWarning("W0008", msg, location)
+}
+
+func UnusedImportedSymbol(location text.Location, name string) *Diagnostic {
+	msg := fmt.Sprintf("%q is imported, but never used", name)
+	return makeWarning("W0009", msg, location)
 }
 
 // Lowerer errors
 
 func NotAllPathsReturn(location text.Location) *Diagnostic {
 	const msg = "Not all code paths return a value"
-	return makeError(msg, location)
+	return makeError("E0087", msg, location)
 }
 
 func NonDeclOutsideMain(location text.Location) *Diagnostic {
 	const msg = "Main is defined explicitly. Only declarations may be in module scope"
-	return makeError(msg, location)
+	return makeError("E0088", msg, location)
+}
+
+func UnsupportedAbi(location text.Location, ty tcType, target string) *Diagnostic {
+	msg := fmt.Sprintf(
+		"Values of type %q cannot be passed to or from external functions, as the calling convention of target %q is not supported",
+		ty.String(),
+		target,
+	)
+	return makeError("E0089", msg, location)
+}
+
+// Codegen errors
+
+func InternalError(location text.Location, message string) *Diagnostic {
+	msg := fmt.Sprintf("Internal compiler error: %s", message)
+	return makeError("E0090", msg, location)
 }
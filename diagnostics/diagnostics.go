@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/gearsdatapacks/libra/colour"
@@ -20,95 +22,314 @@ const (
 
 type Partial struct {
 	Kind    DiagnosticKind
+	Code    string
 	Message string
+	// A name which may have been meant instead of the one used,
+	// which replaces it where the diagnostic is reported
+	suggestion string
 }
 
-func partial(kind DiagnosticKind, message string) *Partial {
+func partial(kind DiagnosticKind, code, message string) *Partial {
 	return &Partial{
 		Kind:    kind,
+		Code:    code,
 		Message: message,
 	}
 }
 
 func (p *Partial) Location(location text.Location) *Diagnostic {
-	return new(p.Kind, p.Message, location)
+	diagnostic := new(p.Kind, p.Code, p.Message, location)
+	if p.suggestion != "" {
+		diagnostic.WithFix(didYouMean(location, p.suggestion))
+	}
+	return diagnostic
 }
 
 type Diagnostic struct {
-	Kind     DiagnosticKind
+	Kind DiagnosticKind
+	// A stable identifier for the kind of problem, which
+	// doesn't change if the message is reworded
+	Code     string
 	Message  string
 	Location text.Location
+	// Other parts of the source code related to the problem,
+	// such as where something was previously declared
+	Labels []Label
+	// Extra information about the problem
+	Notes []string
+	// Suggestions for how to fix the problem
+	Help []string
+	// Changes to the source code which would fix the problem
+	Fixes []Fix
+}
+
+// A part of the source code, shown underlined with a message
+type Label struct {
+	Location text.Location
+	Message  string
 }
 
-func new(kind DiagnosticKind, message string, location text.Location) *Diagnostic {
+// A suggested change to the source code
+type Fix struct {
+	Message string
+	Edits   []Edit
+	// Whether the fix can be applied without a person checking it,
+	// because it can't change what the program was meant to do
+	MachineApplicable bool
+}
+
+// Replaces the text at a location. An empty location inserts text.
+type Edit struct {
+	Location    text.Location
+	Replacement string
+}
+
+func new(kind DiagnosticKind, code, message string, location text.Location) *Diagnostic {
 	return &Diagnostic{
 		Kind:     kind,
+		Code:     code,
 		Message:  message,
 		Location: location,
 	}
 }
 
+func (d *Diagnostic) WithLabel(location text.Location, message string) *Diagnostic {
+	d.Labels = append(d.Labels, Label{Location: location, Message: message})
+	return d
+}
+
+func (d *Diagnostic) WithNote(note string) *Diagnostic {
+	d.Notes = append(d.Notes, note)
+	return d
+}
+
+func (d *Diagnostic) WithHelp(help string) *Diagnostic {
+	d.Help = append(d.Help, help)
+	return d
+}
+
+func (d *Diagnostic) WithFix(fix Fix) *Diagnostic {
+	d.Fixes = append(d.Fixes, fix)
+	return d
+}
+
+// The help messages of a diagnostic, including those of its fixes
+func (d *Diagnostic) HelpMessages() []string {
+	messages := slices.Clone(d.Help)
+	for _, fix := range d.Fixes {
+		messages = append(messages, fix.Message)
+	}
+	return messages
+}
+
 func (d *Diagnostic) Print() {
-	d.WriteTo(os.Stdout, true)
+	d.WriteTo(os.Stderr, true)
 }
 
+// A label which is being rendered, along with which part of the
+// source code it covers
+type renderedLabel struct {
+	Label
+	span    text.LineSpan
+	primary bool
+}
+
+// Renders a diagnostic with the source code it refers to. Each line
+// is shown with its line number, and the labels of the diagnostic are
+// underlined, along with a line either side of the main problem for
+// context.
 func (d *Diagnostic) WriteTo(to io.Writer, printColour bool) {
 	colour.UseColour = printColour
 	colour.Writer = to
 
 	diagColour := colour.Reset
+	kind := "info"
 	switch d.Kind {
 	case Error:
 		diagColour = colour.Error
+		kind = "error"
 	case Warning:
 		diagColour = colour.Warning
+		kind = "warning"
 	case Info:
 		diagColour = colour.Info
 	}
 
-	fileName := d.Location.File.FileName
-	span := d.Location.Span.ToLineSpan(d.Location.File)
-	lines := d.Location.File.Lines
-
+	colour.SetColour(diagColour)
+	fmt.Fprint(to, kind)
+	if d.Code != "" {
+		fmt.Fprintf(to, "[%s]", d.Code)
+	}
 	colour.SetColour(colour.White)
-	fmt.Fprintf(to, "%s:%d:%d:\n", fileName, span.StartLine+1, span.StartColumn+1)
+	fmt.Fprint(to, ": "+d.Message)
 	colour.ResetColour()
+	fmt.Fprintln(to)
 
-	spanLines := lines[span.StartLine : span.EndLine+1]
-	numLines := len(spanLines)
+	// Labels are grouped by file, starting with the
+	// one which contains the main problem
+	files := []*text.SourceFile{}
+	labels := map[*text.SourceFile][]renderedLabel{}
+	addLabel := func(label Label, primary bool) {
+		file := label.Location.File
+		// Some errors, such as internal compiler errors, may not be
+		// caused by any particular part of the source code
+		if file == nil {
+			return
+		}
+		if _, ok := labels[file]; !ok {
+			files = append(files, file)
+		}
+		labels[file] = append(labels[file], renderedLabel{
+			Label:   label,
+			span:    label.Location.Span.ToLineSpan(file),
+			primary: primary,
+		})
+	}
+	addLabel(Label{Location: d.Location}, true)
+	for _, label := range d.Labels {
+		addLabel(label, false)
+	}
 
-	fmt.Fprint(to, spanLines[0].Text[:span.StartColumn])
+	gutterWidth := 0
+	for _, fileLabels := range labels {
+		for _, label := range fileLabels {
+			lastLine := min(label.span.EndLine+2, len(label.Location.File.Lines))
+			gutterWidth = max(gutterWidth, len(strconv.Itoa(lastLine)))
+		}
+	}
+	gutter := strings.Repeat(" ", gutterWidth)
 
-	colour.SetColour(diagColour)
-	if numLines == 1 {
-		fmt.Fprint(to, spanLines[0].Text[span.StartColumn:span.EndColumn])
-	} else {
-		for i, line := range spanLines {
-			line := line.Text
-			if i == 0 {
-				fmt.Fprintln(to, line[span.StartColumn:])
-			} else if i == numLines-1 {
-				fmt.Fprint(to, line[:span.EndColumn])
-			} else {
-				fmt.Fprintln(to, line)
-			}
+	for i, file := range files {
+		fileLabels := labels[file]
+		arrow := "-->"
+		if i != 0 {
+			arrow = ":::"
 		}
+		span := fileLabels[0].span
+		colour.SetColour(colour.Info)
+		fmt.Fprintf(to, "%s%s ", gutter, arrow)
+		colour.ResetColour()
+		fmt.Fprintf(to, "%s:%d:%d\n", file.FileName, span.StartLine+1, span.StartColumn+1)
+
+		writeSnippet(to, file, fileLabels, gutterWidth, diagColour)
 	}
 
+	help := d.HelpMessages()
+	if len(d.Notes)+len(help) != 0 {
+		if len(files) != 0 {
+			writeGutter(to, gutter, "|")
+			fmt.Fprintln(to)
+		}
+		for _, note := range d.Notes {
+			writeGutter(to, gutter, "=")
+			fmt.Fprintln(to, " note: "+note)
+		}
+		for _, help := range help {
+			writeGutter(to, gutter, "=")
+			fmt.Fprintln(to, " help: "+help)
+		}
+	}
+
+	fmt.Fprintln(to)
+}
+
+func writeGutter(to io.Writer, lineNumber, separator string) {
+	colour.SetColour(colour.Info)
+	fmt.Fprint(to, lineNumber+" "+separator)
 	colour.ResetColour()
+}
+
+// Writes the lines of a file which are covered by labels, with each
+// label underlined below it. Long labels only show their first and last
+// lines, and lines which aren't shown are replaced with `...`.
+func writeSnippet(to io.Writer, file *text.SourceFile, labels []renderedLabel, gutterWidth int, diagColour colour.Colour) {
+	lines := []int{}
+	for _, label := range labels {
+		lines = append(lines, label.span.StartLine, label.span.EndLine)
+		if label.primary {
+			lines = append(lines, max(label.span.StartLine-1, 0), min(label.span.EndLine+1, len(file.Lines)-1))
+		}
+	}
+	slices.Sort(lines)
+	lines = slices.Compact(lines)
 
-	fmt.Fprintln(to, spanLines[numLines-1].Text[span.EndColumn:])
+	gutter := strings.Repeat(" ", gutterWidth)
+	writeGutter(to, gutter, "|")
+	fmt.Fprintln(to)
 
-	column := span.StartColumn
-	if numLines > 1 {
-		column = 0
+	for i, line := range lines {
+		if i != 0 {
+			// A single hidden line takes as much space as the `...` would
+			if gap := line - lines[i-1]; gap == 2 {
+				writeLine(to, file, line-1, labels, gutterWidth, diagColour)
+			} else if gap > 2 {
+				colour.SetColour(colour.Info)
+				fmt.Fprintln(to, "...")
+				colour.ResetColour()
+			}
+		}
+		writeLine(to, file, line, labels, gutterWidth, diagColour)
 	}
-	arrow := strings.Repeat(" ", column) + "^"
-	fmt.Fprint(to, arrow+" ")
+}
 
-	colour.SetColour(diagColour)
-	fmt.Fprintln(to, d.Message)
+func writeLine(to io.Writer, file *text.SourceFile, lineNumber int, labels []renderedLabel, gutterWidth int, diagColour colour.Colour) {
+	line := file.Lines[lineNumber].Text
+	writeGutter(to, fmt.Sprintf("%*d", gutterWidth, lineNumber+1), "|")
+	if line != "" {
+		fmt.Fprint(to, " "+line)
+	}
 	fmt.Fprintln(to)
 
-	colour.ResetColour()
+	gutter := strings.Repeat(" ", gutterWidth)
+	for _, label := range labels {
+		span := label.span
+		if lineNumber < span.StartLine || lineNumber > span.EndLine {
+			continue
+		}
+
+		start := 0
+		if lineNumber == span.StartLine {
+			start = span.StartColumn
+		} else {
+			start = len(line) - len(strings.TrimLeft(line, " \t"))
+		}
+		end := len(line)
+		if lineNumber == span.EndLine {
+			end = span.EndColumn
+		}
+		start = min(start, len(line))
+		end = min(end, len(line))
+
+		underline := "-"
+		labelColour := colour.Info
+		if label.primary {
+			underline = "^"
+			labelColour = diagColour
+		}
+		width := max(len([]rune(line[start:end])), 1)
+
+		writeGutter(to, gutter, "|")
+		fmt.Fprint(to, " "+padding(line[:start]))
+		colour.SetColour(labelColour)
+		fmt.Fprint(to, strings.Repeat(underline, width))
+		if lineNumber == span.EndLine && label.Message != "" {
+			fmt.Fprint(to, " "+label.Message)
+		}
+		colour.ResetColour()
+		fmt.Fprintln(to)
+	}
+}
+
+// Whitespace which lines up with the end of some text. Tabs are kept
+// as tabs, since they may be displayed at different widths.
+func padding(text string) string {
+	var result strings.Builder
+	for _, char := range text {
+		if char == '\t' {
+			result.WriteRune('\t')
+		} else {
+			result.WriteRune(' ')
+		}
+	}
+	return result.String()
 }
@@ -0,0 +1,59 @@
+package diagnostics_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/text"
+)
+
+func render(diagnostic *diagnostics.Diagnostic) string {
+	var output bytes.Buffer
+	diagnostic.WriteTo(&output, false)
+	return output.String()
+}
+
+func TestRenderLabels(t *testing.T) {
+	file := text.NewFile("test.lb", "let in = 1\nlet a = 2\n\nfor i in [1] {}\nlet b = 3")
+	diagnostic := diagnostics.KeywordOverwritten(
+		text.Location{File: file, Span: text.NewSpan(28, 30)},
+		"in",
+		text.Location{File: file, Span: text.NewSpan(4, 6)},
+	).WithNote("A note").WithHelp("Some help")
+
+	utils.AssertEq(t, render(diagnostic), `error[E0015]: Expected "in" keyword, but it has been overwritten by a variable
+ --> test.lb:4:7
+  |
+1 | let in = 1
+  |     -- Try removing or renaming this variable
+2 | let a = 2
+3 |
+4 | for i in [1] {}
+  |       ^^
+5 | let b = 3
+  |
+  = note: A note
+  = help: Some help
+
+`)
+}
+
+func TestRenderMultipleLines(t *testing.T) {
+	file := text.NewFile("test.lb", "fn f() {\n\tlet a = 1\n\tlet b = 2\n\tlet c = 3\n\tlet d = 4\n}")
+	diagnostic := diagnostics.NotAllPathsReturn(text.Location{File: file, Span: text.NewSpan(10, 52)})
+
+	utils.AssertEq(t, render(diagnostic), `error[E0087]: Not all code paths return a value
+ --> test.lb:2:2
+  |
+1 | fn f() {
+2 | 	let a = 1
+  | 	^^^^^^^^^
+...
+5 | 	let d = 4
+  | 	^^^^^^^^^
+6 | }
+
+`)
+}
@@ -0,0 +1,939 @@
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A long-form explanation of a diagnostic, shown by `libra explain`
+type Explanation struct {
+	Code        string
+	Title       string
+	Description string
+	// Code which causes the diagnostic. Some diagnostics can't be
+	// caused by any particular program, so have no examples.
+	Example string
+	// The example, changed so that it no longer causes the diagnostic
+	Fixed string
+}
+
+// Looks up the explanation of a diagnostic code, ignoring case
+func Explain(code string) (Explanation, bool) {
+	code = strings.ToUpper(code)
+	for _, explanation := range Explanations {
+		if explanation.Code == code {
+			return explanation, true
+		}
+	}
+	return Explanation{}, false
+}
+
+func (e Explanation) String() string {
+	var result strings.Builder
+	fmt.Fprintf(&result, "%s: %s\n\n%s\n", e.Code, e.Title, e.Description)
+	if e.Example != "" {
+		result.WriteString("\nErroneous code example:\n\n")
+		result.WriteString(indent(e.Example))
+	}
+	if e.Fixed != "" {
+		result.WriteString("\nFixed code:\n\n")
+		result.WriteString(indent(e.Fixed))
+	}
+	return result.String()
+}
+
+func indent(code string) string {
+	var result strings.Builder
+	for _, line := range strings.Split(code, "\n") {
+		if line == "" {
+			result.WriteString("\n")
+		} else {
+			result.WriteString("    " + line + "\n")
+		}
+	}
+	return result.String()
+}
+
+// The explanations of every diagnostic code. Examples which import a
+// module refer to a module "maths", which exports `add` and `sub`
+// functions, and a `Counter` struct with a public `count` field and a
+// private `step` field, created using `counter()`.
+var Explanations = []Explanation{
+	// Lexer diagnostics
+	{
+		Code:  "E0001",
+		Title: "Invalid character",
+		Description: `A character was found which isn't part of any valid token, such as "#"
+or "$" outside of a string. Remove it, or put it in a string if it
+was meant to be text.`,
+		Example: `let tag = #red`,
+		Fixed:   `let tag = "#red"`,
+	},
+	{
+		Code:  "E0002",
+		Title: "Unterminated string",
+		Description: `A string literal was started, but the file ended before the closing
+quote was found. Strings can't span multiple lines, so make sure every
+string is closed on the line it starts.`,
+		Example: `let greeting = "Hello`,
+		Fixed:   `let greeting = "Hello"`,
+	},
+	{
+		Code:  "E0003",
+		Title: "Unterminated block comment",
+		Description: `A block comment was started with "/*", but the file ended before it
+was closed with "*/". Block comments can be nested, so each "/*" inside
+a comment needs its own "*/".`,
+		Example: "/* Adds one /* to a number */\nlet one = 1",
+		Fixed:   "/* Adds one /* to a number */ */\nlet one = 1",
+	},
+	{
+		Code:  "E0004",
+		Title: "Invalid escape sequence",
+		Description: `A backslash in a string must be followed by one of the supported escape
+sequences, such as "\n", "\t", "\\" or "\"". To include a backslash
+itself, escape it with another backslash.`,
+		Example: `let pattern = "\d+"`,
+		Fixed:   `let pattern = "\\d+"`,
+	},
+	{
+		Code:  "E0005",
+		Title: "Expected escape sequence, reached end of file",
+		Description: `The file ended in the middle of an escape sequence. "\x" must be
+followed by two hexadecimal digits, and "\u" by four.`,
+		Example: `let bell = "\x7"`,
+		Fixed:   `let bell = "\x07"`,
+	},
+	{
+		Code:  "E0006",
+		Title: "Invalid ascii escape sequence",
+		Description: `An ascii escape sequence, starting with "\x", must be followed by two
+hexadecimal digits giving the value of the character.`,
+		Example: `let letter = "\xZZ"`,
+		Fixed:   `let letter = "\x5A"`,
+	},
+	{
+		Code:  "E0007",
+		Title: "Invalid unicode escape sequence",
+		Description: `A unicode escape sequence, starting with "\u", must be followed by four
+hexadecimal digits giving the code point of the character.`,
+		Example: `let accent = "\u00G9"`,
+		Fixed:   `let accent = "é"`,
+	},
+	{
+		Code:  "E0008",
+		Title: "Numbers cannot end with numeric separators",
+		Description: `Underscores can be used to separate the digits of a number, but they
+must be between two digits. A number can't end with an underscore, and
+neither can the part before a decimal point.`,
+		Example: `let million = 1_000_000_`,
+		Fixed:   `let million = 1_000_000`,
+	},
+	{
+		Code:  "E0009",
+		Title: "Radix specifiers must be followed by valid digits",
+		Description: `A number starting with "0b", "0o" or "0x" must be followed by digits
+which are valid in that base. Binary numbers can only contain 0 and 1,
+and octal numbers the digits 0 to 7.`,
+		Example: `let mask = 0b21`,
+		Fixed:   `let mask = 0b101`,
+	},
+
+	// Parser diagnostics
+	{
+		Code:  "E0010",
+		Title: "Expected expression",
+		Description: `An expression was expected, but something else was found. This usually
+means part of an expression is missing, such as the right operand of a
+binary operator.`,
+		Example: `let total = 3 *`,
+		Fixed:   `let total = 3 * 2`,
+	},
+	{
+		Code:  "E0011",
+		Title: "Expected newline after statement",
+		Description: `Each statement must be on its own line, or separated from the next
+statement with a semicolon.`,
+		Example: `let a = 1 let b = 2`,
+		Fixed:   "let a = 1\nlet b = 2",
+	},
+	{
+		Code:  "E0012",
+		Title: "Expected token",
+		Description: `A particular token was expected, but a different one was found. This
+is often a missing closing bracket.`,
+		Example: `let sum = (1 + 2`,
+		Fixed:   `let sum = (1 + 2)`,
+	},
+	{
+		Code:  "E0013",
+		Title: "Else statement not allowed without preceding if",
+		Description: `An "else" branch must directly follow the body of an "if" expression.
+Other statements can't come between them.`,
+		Example: "mut count = 0\nif count == 0 {\n  count = 1\n}\nlet total = count\nelse {\n  count = 2\n}",
+		Fixed:   "mut count = 0\nif count == 0 {\n  count = 1\n} else {\n  count = 2\n}\nlet total = count",
+	},
+	{
+		Code:  "E0014",
+		Title: "Expected keyword",
+		Description: `A keyword is required here, such as the "in" of a "for" loop, but
+something else was found.`,
+		Example: `for item [1, 2, 3] {}`,
+		Fixed:   `for item in [1, 2, 3] {}`,
+	},
+	{
+		Code:  "E0015",
+		Title: "Keyword overwritten by a variable",
+		Description: `Some keywords, such as "in", are only keywords in certain places, so
+they can be used as variable names. However, once a variable has that
+name, it can't be used as a keyword in the same scope. Rename the
+variable.`,
+		Example: "let in = 1\nfor i in [1, 2, 3] {}",
+		Fixed:   "let inches = 1\nfor i in [1, 2, 3] {}",
+	},
+	{
+		Code:  "E0016",
+		Title: "The last parameter of a function must have a type annotation",
+		Description: `Parameters without a type annotation take the type of the next
+parameter which has one, so the last parameter must always have one.`,
+		Example: `fn add(a, b) {}`,
+		Fixed:   `fn add(a, b: i32) {}`,
+	},
+	{
+		Code:  "E0017",
+		Title: `"mut" must be followed by a parameter name`,
+		Description: `A parameter marked "mut" must have a name, as only named parameters can
+be modified. Function types can't have mutable parameters.`,
+		Example: `fn push(mut i32[]) {}`,
+		Fixed:   `fn push(mut list: i32[]) {}`,
+	},
+	{
+		Code:  "E0018",
+		Title: "The last field of a struct must have a type annotation",
+		Description: `Fields without a type annotation take the type of the next field which
+has one, so the last field must always have one.`,
+		Example: `struct Rect { width: i32, height }`,
+		Fixed:   `struct Rect { width, height: i32 }`,
+	},
+	{
+		Code:  "E0019",
+		Title: "Functions cannot be both methods and static members",
+		Description: `A function can be a method, called on a value of a type, or a static
+member, accessed through the type itself, but not both.`,
+		Example: "struct Point { x, y: i32 }\nfn (Point) Point.sum(): i32 { this.x + this.y }",
+		Fixed:   "struct Point { x, y: i32 }\nfn (Point) sum(): i32 { this.x + this.y }",
+	},
+	{
+		Code:  "E0020",
+		Title: "Invalid right-hand side of expression",
+		Description: `A "." at the start of an expression must be followed by the name of a
+member, or by a struct body.`,
+		Example: "enum Colour { Red, Green }\nlet colour: Colour = .",
+		Fixed:   "enum Colour { Red, Green }\nlet colour: Colour = Colour.Red",
+	},
+	{
+		Code:  "E0021",
+		Title: "Only one import modifier is allowed",
+		Description: `An import can either import everything from a module, import specific
+members, or give the module a different name, but it can't do more than
+one of these.`,
+		Example: `import * from "maths" as m`,
+		Fixed:   `import "maths" as m`,
+	},
+	{
+		Code:  "E0022",
+		Title: "Statement not allowed here",
+		Description: `Declarations, such as functions, types and imports, can only be at the
+top level of a file, not inside blocks.`,
+		Example: "if true {\n  fn helper() {}\n}",
+		Fixed:   "fn helper() {}\nif true {\n  helper()\n}",
+	},
+	{
+		Code:  "E0023",
+		Title: "Expected type",
+		Description: `A type was expected, but something else was found. This usually means
+a type annotation or declaration is missing its type.`,
+		Example: `type Id = ;`,
+		Fixed:   `type Id = i32`,
+	},
+	{
+		Code:  "E0024",
+		Title: "Only top-level declarations can be exported",
+		Description: `"pub" makes a declaration visible to other modules, so it can only be
+used on declarations at the top level of a file.`,
+		Example: "fn total(): i32 {\n  pub return 10\n}",
+		Fixed:   "pub fn total(): i32 {\n  return 10\n}",
+	},
+	{
+		Code:  "E0025",
+		Title: "Statement cannot be marked explicit",
+		Description: `Only type declarations can be marked "explicit", which stops values of
+the underlying type being implicitly converted to them.`,
+		Example: `explicit fn metres(): f32 { 1.0 }`,
+		Fixed:   `explicit type Metres = f32`,
+	},
+	{
+		Code:  "E0026",
+		Title: "The attribute does not exist",
+		Description: `Attributes are written with "@" before a declaration, and only a fixed
+set of them exist, such as "@extern", "@tag", "@todo", "@doc",
+"@deprecated" and "@gen".`,
+		Example: "@inline\nfn add(a, b: i32): i32 { a + b }",
+		Fixed:   "fn add(a, b: i32): i32 { a + b }",
+	},
+	{
+		Code:  "E0027",
+		Title: "Statement cannot be marked with attribute",
+		Description: `Each attribute can only be used on certain kinds of declaration. For
+example, "@tag" can only be used on type declarations.`,
+		Example: "tag Shape\n@tag Shape\nfn circle() {}",
+		Fixed:   "tag Shape\n@tag Shape\nstruct Circle { radius: f32 }",
+	},
+
+	// Type checker diagnostics
+	{
+		Code:  "E0028",
+		Title: "Type is not defined",
+		Description: `A name was used as a type, but no type with that name is in scope.
+Check the spelling, or declare or import the type.`,
+		Example: "struct Point { x, y: i32 }\nfn Piont.origin(): Point { Point { x: 0, y: 0 } }",
+		Fixed:   "struct Point { x, y: i32 }\nfn Point.origin(): Point { Point { x: 0, y: 0 } }",
+	},
+	{
+		Code:  "E0029",
+		Title: "Value is not assignable to type",
+		Description: `A value was used where a value of a different type was expected, such
+as in a variable with a type annotation, or as a function argument.
+Change the value, or convert it to the expected type.`,
+		Example: `let count: i32 = "ten"`,
+		Fixed:   `let count: i32 = 10`,
+	},
+	{
+		Code:  "E0030",
+		Title: "Variable is already defined",
+		Description: `A variable can't be declared twice in the same scope. To change the
+value of a variable, declare it as mutable and assign to it instead.`,
+		Example: "let total = 1\nlet total = 2",
+		Fixed:   "mut total = 1\ntotal = 2",
+	},
+	{
+		Code:  "E0031",
+		Title: "Variable is not defined",
+		Description: `A name was used, but no variable, function or type with that name is in
+scope. Check the spelling, or declare or import it.`,
+		Example: "let value = 1\nlet copy = valeu",
+		Fixed:   "let value = 1\nlet copy = value",
+	},
+	{
+		Code:  "E0032",
+		Title: "Operator is not defined for types",
+		Description: `A binary operator was used with operands whose types it doesn't support.
+Numbers of different types must be converted to the same type first.`,
+		Example: `let sum = 1 + "two"`,
+		Fixed:   `let sum = 1 + 2`,
+	},
+	{
+		Code:  "E0033",
+		Title: "Operator is not defined for operand",
+		Description: `A unary operator was used with an operand whose type it doesn't
+support, such as negating a boolean.`,
+		Example: `let negative = -true`,
+		Fixed:   `let negative = -1`,
+	},
+	{
+		Code:  "E0034",
+		Title: "Cannot cast value",
+		Description: `The "->" operator can only convert between certain types, such as
+between different kinds of number. Other conversions must be written
+explicitly.`,
+		Example: `let truthy = 1 -> bool`,
+		Fixed:   `let truthy = 1 != 0`,
+	},
+	{
+		Code:  "E0035",
+		Title: "Cannot increment or decrement a non-variable value",
+		Description: `"++" and "--" change the value of a variable, so they can only be used
+on variables, or on fields and elements of them.`,
+		Example: "mut count = 1\n(count + 1)++",
+		Fixed:   "mut count = 1\ncount++",
+	},
+	{
+		Code:  "E0036",
+		Title: "Cannot modify value, it is immutable",
+		Description: `Variables declared with "let" and parameters not marked "mut" can't be
+changed after they are declared. Declare the variable with "mut" to
+allow it to change.`,
+		Example: "let count = 0\ncount = 1",
+		Fixed:   "mut count = 0\ncount = 1",
+	},
+	{
+		Code:  "E0037",
+		Title: "Value must be known at compile time",
+		Description: `Some values, such as constants, array lengths and types, must be known
+when the program is compiled. Mutable variables can change while the
+program runs, so they can't be used here.`,
+		Example: "mut base = 0\nconst next = base + 1",
+		Fixed:   "const base = 0\nconst next = base + 1",
+	},
+	{
+		Code:        "E0038",
+		Title:       "Array length must be an integer",
+		Description: `The length of an array type must be a whole number.`,
+		Example:     `let values: i32[1.5] = [1]`,
+		Fixed:       `let values: i32[1] = [1]`,
+	},
+	{
+		Code:  "E0039",
+		Title: "Cannot index value",
+		Description: `The value can't be indexed with a value of that type. Arrays and lists
+are indexed with integers, and maps with their key type.`,
+		Example: `let second = [1, 2, 3][1.5]`,
+		Fixed:   `let second = [1, 2, 3][1]`,
+	},
+	{
+		Code:  "E0040",
+		Title: "Value cannot be used as a key in a map",
+		Description: `Map keys must be hashable, so that values can be looked up by them.
+Arrays and lists can't be used as keys.`,
+		Example: `let names = {[1, 2]: "pair"}`,
+		Fixed:   `let names = {1: "one", 2: "two"}`,
+	},
+	{
+		Code:  "E0041",
+		Title: "Value could be any of multiple variants",
+		Description: `A value was converted to a union, but it could be any of several of
+the union's variants. Convert it to one of the variants first, so that
+it's clear which was meant.`,
+		Example: "union Small { i8, u8 }\nlet small: Small = 7",
+		Fixed:   "union Small { i8, u8 }\nlet small: Small = 7 -> u8",
+	},
+	{
+		Code:        "E0042",
+		Title:       "Cannot assign to a non-variable value",
+		Description: `Only variables, and fields and elements of them, can be assigned to.`,
+		Example:     "mut value = 1\nvalue + 1 = 2",
+		Fixed:       "mut value = 1\nvalue = 2",
+	},
+	{
+		Code:  "E0043",
+		Title: "Index is out of bounds",
+		Description: `An array was indexed with a constant which is past its end. Indices
+start at 0, so the last element of an array of length 3 is at index 2.`,
+		Example: `let last = [1, 2, 3][3]`,
+		Fixed:   `let last = [1, 2, 3][2]`,
+	},
+	{
+		Code:  "E0044",
+		Title: "Condition must be a boolean",
+		Description: `The conditions of "if" expressions and "while" loops must be booleans.
+Other values aren't implicitly treated as true or false, so compare
+them explicitly.`,
+		Example: "let count = 1\nlet some = if count { true } else { false }",
+		Fixed:   "let count = 1\nlet some = if count != 0 { true } else { false }",
+	},
+	{
+		Code:  "E0045",
+		Title: "Value is not iterable",
+		Description: `A "for" loop can only iterate over values which contain elements, such
+as arrays, lists and maps.`,
+		Example: `for i in 10 {}`,
+		Fixed:   `for i in [1, 2, 3] {}`,
+	},
+	{
+		Code:  "E0046",
+		Title: "Cannot use return outside of a function",
+		Description: `"return" exits the function it is in, so it can only be used inside a
+function.`,
+		Example: `return 10`,
+		Fixed:   "fn ten(): i32 {\n  return 10\n}",
+	},
+	{
+		Code:  "E0047",
+		Title: "Expected a return value",
+		Description: `A function with a return type must return a value of that type. A
+"return" without a value is only allowed in functions which don't
+return anything.`,
+		Example: "fn ten(): i32 {\n  return\n}",
+		Fixed:   "fn ten(): i32 {\n  return 10\n}",
+	},
+	{
+		Code:        "E0048",
+		Title:       "Value cannot be called",
+		Description: `Only functions can be called.`,
+		Example:     "let name = \"Libra\"\nlet called = name()",
+		Fixed:       "fn name(): string { \"Libra\" }\nlet called = name()",
+	},
+	{
+		Code:  "E0049",
+		Title: "Incorrect number of arguments",
+		Description: `A function must be called with exactly one argument for each of its
+parameters.`,
+		Example: "fn add(a, b: i32): i32 { a + b }\nlet sum = add(1)",
+		Fixed:   "fn add(a, b: i32): i32 { a + b }\nlet sum = add(1, 2)",
+	},
+	{
+		Code:  "E0050",
+		Title: "Value does not have member",
+		Description: `A member was accessed which the value doesn't have. Values have the
+fields of their type, as well as any methods declared for it.`,
+		Example: "struct Point { x, y: i32 }\nlet point = Point { x: 1, y: 2 }\nlet z = point.z",
+		Fixed:   "struct Point { x, y: i32 }\nlet point = Point { x: 1, y: 2 }\nlet y = point.y",
+	},
+	{
+		Code:  "E0051",
+		Title: "Field is private",
+		Description: `Fields of a struct can only be accessed from other modules if they are
+marked "pub". Use a public field or function of the module instead, or
+make the field public.`,
+		Example: "import \"maths\"\nlet step = maths.counter().step",
+		Fixed:   "import \"maths\"\nlet count = maths.counter().count",
+	},
+	{
+		Code:  "E0052",
+		Title: "Cannot construct value, not a type",
+		Description: `Only types can be used to construct values with a struct body.
+Values can't be constructed from other values.`,
+	},
+	{
+		Code:  "E0053",
+		Title: "Cannot construct value of type",
+		Description: `Only structs can be constructed with a struct body. Values of other
+types are written as literals, or converted to.`,
+		Example: `let number = i32 { 1 }`,
+		Fixed:   `let number: i32 = 1`,
+	},
+	{
+		Code:  "E0054",
+		Title: "Struct does not have member",
+		Description: `A value was given for a field which the struct doesn't have. Check the
+spelling of the field.`,
+		Example: "struct Point { x, y: i32 }\nlet point = Point { x: 1, z: 2 }",
+		Fixed:   "struct Point { x, y: i32 }\nlet point = Point { x: 1, y: 2 }",
+	},
+	{
+		Code:  "E0055",
+		Title: "Cannot use statement outside of a loop",
+		Description: `"break" and "continue" can only be used inside a loop. Functions
+declared inside a loop are not part of the loop.`,
+		Example: `break`,
+		Fixed:   "for i in [1, 2, 3] {\n  if i == 2 {\n    break\n  }\n}",
+	},
+	{
+		Code:  "E0056",
+		Title: "Cannot use statement outside of a block",
+		Description: `"yield" gives the value of a block expression, so it can only be used
+directly inside a block. Loops are not blocks.`,
+		Example: `yield 10`,
+		Fixed:   `let ten = { yield 10 }`,
+	},
+	{
+		Code:  "E0057",
+		Title: "Expected a type, found value",
+		Description: `A value was used where a type was expected, such as in a type
+annotation.`,
+		Example: `const size: 10 = 10`,
+		Fixed:   `const size: i32 = 10`,
+	},
+	{
+		Code:  "E0058",
+		Title: "Parameters in function types must be unnamed",
+		Description: `Function types only describe the types of the parameters, so the
+parameters can't be given names.`,
+		Example: `type Callback = fn(i32, second: string)`,
+		Fixed:   `type Callback = fn(i32, string)`,
+	},
+	{
+		Code:  "E0059",
+		Title: "Unnamed parameters are only allowed in function types",
+		Description: `Parameters of functions must be named, so that they can be used in the
+body of the function. Only function types can have unnamed parameters.`,
+		Example: `let log = fn(level: i32, string[]) {}`,
+		Fixed:   `let log = fn(level: i32, messages: string[]) {}`,
+	},
+	{
+		Code:  "E0060",
+		Title: "Module does not export member",
+		Description: `A member was imported from a module which doesn't export anything with
+that name. Check the spelling, and that the member is marked "pub".`,
+		Example: `import { divide } from "maths"`,
+		Fixed:   `import { add } from "maths"`,
+	},
+	{
+		Code:  "E0061",
+		Title: "Cannot dereference non-pointer value",
+		Description: `".*" gets the value a pointer points to, so it can only be used on
+pointers.`,
+		Example: `let value = 10.*`,
+		Fixed:   "let value = 10\nlet pointer = &value\nlet copy = pointer.*",
+	},
+	{
+		Code:  "E0062",
+		Title: "Cannot take a mutable reference to an immutable value",
+		Description: `A mutable pointer can be used to change the value it points to, so it
+can only point to a mutable value.`,
+		Example: "const value = 10\nlet pointer = &mut value",
+		Fixed:   "mut value = 10\nlet pointer = &mut value",
+	},
+	{
+		Code:  "E0063",
+		Title: "Cannot mix named and unnamed struct fields",
+		Description: `A struct can either have named fields, or be a tuple struct with only
+unnamed fields, but not both.`,
+		Example: `struct Pair { first: i32, i32[] }`,
+		Fixed:   `struct Pair { first: i32, rest: i32[] }`,
+	},
+	{
+		Code:  "E0064",
+		Title: "Incorrect number of values supplied to struct",
+		Description: `When constructing a tuple struct, a value must be given for each of its
+fields, in order.`,
+		Example: "struct Pair { i32, i32 }\nlet pair = Pair { 1, 2, 3 }",
+		Fixed:   "struct Pair { i32, i32 }\nlet pair = Pair { 1, 2 }",
+	},
+	{
+		Code:  "E0065",
+		Title: "Field names not allowed when constructing tuple structs",
+		Description: `The fields of a tuple struct have no names, so its values are given in
+order, without names.`,
+		Example: "struct Pair { i32, f32 }\nlet pair = Pair { first: 1, second: 2.5 }",
+		Fixed:   "struct Pair { i32, f32 }\nlet pair = Pair { 1, 2.5 }",
+	},
+	{
+		Code:  "E0066",
+		Title: "Struct members must all be named",
+		Description: `When constructing a struct with named fields, each value must be given
+with the name of its field.`,
+		Example: "struct Vector { x, y: i32 }\nlet vector = Vector { 1, 2 }",
+		Fixed:   "struct Vector { x, y: i32 }\nlet vector = Vector { x: 1, y: 2 }",
+	},
+	{
+		Code:  "E0067",
+		Title: `"pub" keyword not allowed for unnamed fields`,
+		Description: `Unnamed fields can't be accessed by name, so they can't be made public.
+Give the field a name to make it public.`,
+		Example: `struct Wrapper { pub string }`,
+		Fixed:   `struct Wrapper { pub value: string }`,
+	},
+	{
+		Code:  "E0068",
+		Title: "Union has no variant",
+		Description: `A variant was accessed which the union doesn't have. Check the spelling
+of the variant.`,
+		Example: "union Number { int: i32, float: f32 }\ntype Whole = Number.integer",
+		Fixed:   "union Number { int: i32, float: f32 }\ntype Whole = Number.int",
+	},
+	{
+		Code:  "E0069",
+		Title: "Union is untagged, so its variant cannot be checked",
+		Description: `Untagged unions don't store which variant they contain, so "is" can't
+be used to check it. Remove "@untagged" to store the variant.`,
+		Example: "@untagged\nunion Bits { int: i32, float: f32 }\nlet bits: Bits = 1 -> i32\nlet is_int = bits is i32",
+		Fixed:   "union Bits { int: i32, float: f32 }\nlet bits: Bits = 1 -> i32\nlet is_int = bits is i32",
+	},
+	{
+		Code:  "E0070",
+		Title: "Type is not a tag",
+		Description: `The "@tag" attribute adds a type to a tag, so it must be given a tag,
+declared with the "tag" keyword.`,
+		Example: "type Shape = i32\n@tag Shape\nstruct Circle { radius: f32 }",
+		Fixed:   "tag Shape\n@tag Shape\nstruct Circle { radius: f32 }",
+	},
+	{
+		Code:  "E0071",
+		Title: "The module does not exist",
+		Description: `An import refers to a module which doesn't exist. Modules are imported
+by the path of their directory, relative to the importing module.`,
+		Example: `import "math"`,
+		Fixed:   `import "maths"`,
+	},
+	{
+		Code:  "E0072",
+		Title: "Index expressions which aren't list types must have an index",
+		Description: `Empty brackets after a type make a list type, but other values must be
+indexed with a value between the brackets.`,
+		Example: `let first = [1, 2, 3][]`,
+		Fixed:   `let first = [1, 2, 3][0]`,
+	},
+	{
+		Code:  "E0073",
+		Title: "Cannot propagate errors outside of a function",
+		Description: `"?" returns the error from the current function if there is one, so it
+can only be used inside a function.`,
+		Example: "let maybe: ?i32 = 5\nlet value = maybe?",
+		Fixed:   "fn double(maybe: ?i32): ?i32 {\n  let value = maybe?\n  return value * 2\n}",
+	},
+	{
+		Code:  "E0074",
+		Title: "Can only propagate errors in functions which return result types",
+		Description: `Using "?" on a result returns its error from the function, so the
+function must return a result too.`,
+		Example: "fn unwrap(result: !i32): i32 {\n  result?\n}",
+		Fixed:   "fn double(result: !i32): !i32 {\n  let value = result?\n  return value * 2\n}",
+	},
+	{
+		Code:  "E0075",
+		Title: "Can only propagate void options in functions which return option types",
+		Description: `Using "?" on an option returns from the function if the option is
+empty, so the function must return an option too.`,
+		Example: "fn unwrap(option: ?i32): i32 {\n  option?\n}",
+		Fixed:   "fn double(option: ?i32): ?i32 {\n  let value = option?\n  return value * 2\n}",
+	},
+	{
+		Code:  "E0076",
+		Title: "Type cannot generate enum values automatically",
+		Description: `Enum members without a value are given one automatically, but only for
+integer and string enums. Members of other enums must all be given a
+value.`,
+		Example: `enum Scale: f32 { Half = 0.5, Double }`,
+		Fixed:   `enum Scale: f32 { Half = 0.5, Double = 2.0 }`,
+	},
+	{
+		Code:  "E0077",
+		Title: "Enum has no member",
+		Description: `A member was accessed which the enum doesn't have. Check the spelling,
+or add the member to the enum.`,
+		Example: "enum Colour { Red, Green }\nlet colour = Colour.Blue",
+		Fixed:   "enum Colour { Red, Green, Blue }\nlet colour = Colour.Blue",
+	},
+	{
+		Code:  "E0078",
+		Title: "If-else branches must yield matching types",
+		Description: `When an "if" expression is used as a value, each of its branches must
+give a value of the same type.`,
+		Example: `let value = if true { 10 } else { "twenty" }`,
+		Fixed:   `let value = if true { 10 } else { 20 }`,
+	},
+	{
+		Code:  "E0079",
+		Title: "Functions marked external cannot have bodies",
+		Description: `An "@extern" function is defined outside of the program, such as in a
+C library, so it can't have a body.`,
+		Example: "@extern\nfn add(a, b: i32): i32 { a + b }",
+		Fixed:   "@extern\nfn add(a, b: i32): i32",
+	},
+	{
+		Code:  "E0080",
+		Title: "Only functions marked extern can be imported from a module",
+		Description: `"@import_module" and "@import_name" give the WebAssembly module and
+name an external function is imported from, so they can only be used on
+"@extern" functions.`,
+		Example: "@import_module env\nfn log() {}",
+		Fixed:   "@extern\n@import_module env\nfn log()",
+	},
+	{
+		Code:  "E0081",
+		Title: "Functions must have bodies or be marked extern",
+		Description: `A function without a body must be marked "@extern", to say that it is
+defined outside of the program.`,
+		Example: `fn random(): f32`,
+		Fixed:   "@extern\nfn random(): f32",
+	},
+	{
+		Code:  "E0082",
+		Title: "Not a method generator",
+		Description: `"@gen" must be given a method generator. That is either one of the built-in
+generators, "derive_eq", "derive_hash" and "derive_debug", or a function
+which takes a type and returns a function, like
+"fn(ty: Type): fn(ty): string".`,
+		Example: "@gen(10)\nstruct Point { x, y: i32 }",
+		Fixed:   "@gen(derive_eq)\nstruct Point { x, y: i32 }",
+	},
+	{
+		Code:  "E0083",
+		Title: "Cannot generate method for type",
+		Description: `A method generator can only generate methods for types whose fields
+support it. For example, "derive_hash" can't hash strings yet.`,
+		Example: "@gen(derive_hash)\nstruct User { id: i32, name: string }",
+		Fixed:   "@gen(derive_eq)\nstruct User { id: i32, name: string }",
+	},
+	{
+		Code:  "E0092",
+		Title: "Generator not defined",
+		Description: `The generator given to "@gen" is not a built-in generator, and there
+is no function with that name in scope.`,
+		Example: "@gen(derive_eqq)\nstruct Point { x, y: i32 }",
+		Fixed:   "@gen(derive_eq)\nstruct Point { x, y: i32 }",
+	},
+	{
+		Code:  "E0093",
+		Title: "Statement cannot be run at compile time",
+		Description: `Generators are run while the program is compiled, so they can only
+declare constants, check conditions which are known at compile time and
+return a function. Move any other code into the function they return.`,
+		Example: "fn derive_size(ty: Type): fn(): i32 {\n  mut size = 0\n  return fn(): i32 { size }\n}\n@gen(derive_size)\nstruct Point { x, y: i32 }",
+		Fixed:   "fn derive_size(ty: Type): fn(): i32 {\n  let size = 0\n  return fn(): i32 { size }\n}\n@gen(derive_size)\nstruct Point { x, y: i32 }",
+	},
+	{
+		Code:  "E0094",
+		Title: "Generator does not return a function literal",
+		Description: `The function a generator returns becomes a method of the type it is run
+for, so it must be written out as a function literal.`,
+		Example: "fn zero(): i32 { 0 }\nfn derive_zero(ty: Type): fn(): i32 {\n  return zero\n}\n@gen(derive_zero)\nstruct Point { x, y: i32 }",
+		Fixed:   "fn derive_zero(ty: Type): fn(): i32 {\n  return fn(): i32 { 0 }\n}\n@gen(derive_zero)\nstruct Point { x, y: i32 }",
+	},
+	{
+		Code:  "E0095",
+		Title: "Generator called at runtime",
+		Description: `Generators are only run while the program is compiled, to add methods
+to a type with "@gen". Use the methods they generate instead.`,
+		Example: "fn derive_zero(ty: Type): fn(): i32 {\n  return fn(): i32 { 0 }\n}\nlet zero = derive_zero(i32)",
+		Fixed:   "fn derive_zero(ty: Type): fn(): i32 {\n  return fn(): i32 { 0 }\n}\n@gen(derive_zero)\nstruct Point { x, y: i32 }\nlet zero = Point.zero()",
+	},
+	{
+		Code:  "E0084",
+		Title: "Field can only be accessed while building",
+		Description: `Fields marked with "~" can only be accessed through a builder, before
+it is finalised into the finished value.`,
+		Example: "struct Items { ~list: i32[], len: i32 }\nlet items = Items { list: [1, 2], len: 2 }\nlet list = items.list",
+		Fixed:   "struct Items { ~list: i32[], len: i32 }\nlet builder: ~Items = Items { list: [1, 2], len: 2 }\nlet list = builder.list",
+	},
+	{
+		Code:        "E0085",
+		Title:       "Type is not a struct, so cannot be built",
+		Description: `Builder types, written with "~", can only be made from structs.`,
+		Example:     `let builder: ~i32 = 1`,
+		Fixed:       "struct Counter { count: i32 }\nlet builder: ~Counter = Counter { count: 1 }",
+	},
+	{
+		Code:  "E0086",
+		Title: "Builder cannot be used after it has been finalised",
+		Description: `Calling a method which turns a builder into its finished value, or
+converting it to the type it builds, finalises the builder. After that,
+it can't be used again on any path through the function unless it is
+reassigned. Use the finished value instead.`,
+		Example: "struct Items { ~list: i32[], len: i32 }\nfn (~Items) finish(): Items { this }\nlet builder: ~Items = Items { list: [], len: 0 }\nlet items = builder.finish()\nlet again = builder.finish()",
+		Fixed:   "struct Items { ~list: i32[], len: i32 }\nfn (~Items) finish(): Items { this }\nlet builder: ~Items = Items { list: [], len: 0 }\nlet items = builder.finish()\nlet again = items",
+	},
+	{
+		Code:  "E0091",
+		Title: "Unfinished declaration used in a release build",
+		Description: `A declaration marked "@todo" was used while building for release with
+"--release". Unfinished code may not work as expected, so it can't be
+used in release builds. Finish the declaration and remove its "@todo"
+attribute, or stop using it.`,
+		Example: "@todo Handle negative numbers\nfn square_root(x: f32): f32 { x }\nlet root = square_root(4.0)",
+		Fixed:   "fn square(x: f32): f32 { x * x }\nlet squared = square(4.0)",
+	},
+
+	// Warnings
+	{
+		Code:  "W0001",
+		Title: "Use of deprecated declaration",
+		Description: `A declaration marked "@deprecated" was used. It may be removed in the
+future, so use the replacement given in its message instead.`,
+		Example: "@deprecated Use add\nfn plus(a, b: i32): i32 { a + b }\nfn add(a, b: i32): i32 { a + b }\nlet sum = plus(1, 2)",
+		Fixed:   "@deprecated Use add\nfn plus(a, b: i32): i32 { a + b }\nfn add(a, b: i32): i32 { a + b }\nlet sum = add(1, 2)",
+	},
+	{
+		Code:  "W0002",
+		Title: "Use of unfinished declaration",
+		Description: `A declaration marked "@todo" was used, so the program may not work as
+expected. Unfinished code can't be used in release builds, where this
+is an error.`,
+		Example: "@todo Handle negative numbers\nfn square_root(x: f32): f32 { x }\nlet root = square_root(4.0)",
+		Fixed:   "fn square(x: f32): f32 { x * x }\nlet squared = square(4.0)",
+	},
+	{
+		Code:  "W0003",
+		Title: "Unused variable",
+		Description: `A variable or parameter is never used. It may be a mistake, or it can
+be removed. Prefix its name with an underscore if it is unused on
+purpose.`,
+		Example: "fn main() {\n  let unused = 1\n}",
+		Fixed:   "fn main() {\n  let _unused = 1\n}",
+	},
+	{
+		Code:  "W0004",
+		Title: "Variable is assigned to, but never read",
+		Description: `A variable is given values, but they are never used, so the variable
+can be removed.`,
+		Example: "fn count(): i32 {\n  mut total = 1\n  total = 2\n  return 2\n}",
+		Fixed:   "fn count(): i32 {\n  mut total = 1\n  total = 2\n  return total\n}",
+	},
+	{
+		Code:  "W0005",
+		Title: "Variable is declared as mutable, but is never modified",
+		Description: `A variable or parameter is marked "mut", but is never changed. Declare
+it with "let", or remove "mut" from the parameter.`,
+		Example: "fn one(): i32 {\n  mut value = 1\n  return value\n}",
+		Fixed:   "fn one(): i32 {\n  let value = 1\n  return value\n}",
+	},
+	{
+		Code:  "W0006",
+		Title: "Unused function",
+		Description: `A function is never called or exported, so it can be removed. Prefix
+its name with an underscore if it is unused on purpose.`,
+		Example: "fn helper() {}\nfn main() {}",
+		Fixed:   "fn helper() {}\nfn main() {\n  helper()\n}",
+	},
+	{
+		Code:  "W0007",
+		Title: "Unused type",
+		Description: `A type is never used or exported, so it can be removed. Prefix its name
+with an underscore if it is unused on purpose.`,
+		Example: "struct Empty\nfn main() {}",
+		Fixed:   "struct Empty\nfn main() {\n  let _empty = Empty\n}",
+	},
+	{
+		Code:        "W0008",
+		Title:       "Unused import",
+		Description: `A module is imported, but never used, so the import can be removed.`,
+		Example:     "import \"maths\"\nfn main() {}",
+		Fixed:       "import \"maths\"\nfn main() {\n  let _sum = maths.add(1, 2)\n}",
+	},
+	{
+		Code:  "W0009",
+		Title: "Unused imported symbol",
+		Description: `A member of a module is imported, but never used, so it can be removed
+from the import.`,
+		Example: "import { add, sub } from \"maths\"\nfn main() {\n  let _sum = add(1, 2)\n}",
+		Fixed:   "import { add } from \"maths\"\nfn main() {\n  let _sum = add(1, 2)\n}",
+	},
+	{
+		Code:  "W0010",
+		Title: "Unknown warning code",
+		Description: `"@allow" and "@deny" must be given the codes of warnings, such as
+"W0003". Errors can't be allowed or denied, as they always stop the
+program from compiling.`,
+		Example: "@allow(W0030)\nfn helper() {}",
+		Fixed:   "@allow(W0006)\nfn helper() {}",
+	},
+
+	// Lowerer diagnostics
+	{
+		Code:  "E0087",
+		Title: "Not all code paths return a value",
+		Description: `A function with a return type must return a value however it finishes.
+An "if" without an "else" may not run, so returning in its body is not
+enough.`,
+		Example: "fn sign(x: i32): i32 {\n  if x < 0 {\n    return -1\n  }\n}",
+		Fixed:   "fn sign(x: i32): i32 {\n  if x < 0 {\n    return -1\n  }\n  return 1\n}",
+	},
+	{
+		Code:  "E0088",
+		Title: "Only declarations may be in module scope",
+		Description: `Statements at the top level of a file are run as the main function.
+If a "main" function is declared explicitly, statements must be moved
+into it.`,
+		Example: "let greeting = \"Hello\"\nfn main() {}",
+		Fixed:   "fn main() {\n  let _greeting = \"Hello\"\n}",
+	},
+	{
+		Code:  "E0089",
+		Title: "Values cannot be passed to or from external functions",
+		Description: `Structs and unions are passed to external functions according to the
+calling convention of the target, which isn't supported for every
+target. Pass the fields separately, or a pointer to the value.`,
+		Example: "struct Colour { r, g, b: u8 }\n@extern\nfn set_colour(c: Colour)",
+		Fixed:   "@extern\nfn set_colour(r, g, b: u8)",
+	},
+
+	// Codegen diagnostics
+	{
+		Code:  "E0090",
+		Title: "Internal compiler error",
+		Description: `The compiler reached a state it didn't expect. This is a bug in the
+compiler rather than a problem with the program, so please report it
+along with the code which caused it.`,
+	},
+}
@@ -0,0 +1,135 @@
+package diagnostics_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/lowerer"
+	"github.com/gearsdatapacks/libra/module"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	typechecker "github.com/gearsdatapacks/libra/type_checker"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// The module which examples can import
+const mathsSrc = `pub fn add(a, b: i32): i32 { a + b }
+pub fn sub(a, b: i32): i32 { a - b }
+pub struct Counter { pub count: i32, step: i32 }
+pub fn counter(): Counter { Counter { count: 0, step: 1 } }`
+
+const defaultTarget = "x86_64-unknown-linux-gnu"
+
+// For loops can't be lowered yet, so only examples of diagnostics
+// reported by the lowerer are lowered, for the target they need
+var loweredExamples = map[string]string{
+	"E0087": defaultTarget,
+	"E0088": defaultTarget,
+	"E0089": "riscv64-unknown-linux-gnu",
+}
+
+// Examples of diagnostics which are only reported when building for release
+var releaseExamples = map[string]bool{
+	"E0091": true,
+}
+
+func compileExample(t *testing.T, code, src string) []diagnostics.Diagnostic {
+	t.Helper()
+
+	dir := t.TempDir()
+	utils.AssertEq(t, os.Mkdir(filepath.Join(dir, "maths"), 0o755), nil)
+	utils.AssertEq(t, os.WriteFile(filepath.Join(dir, "maths", "maths.lb"), []byte(mathsSrc), 0o644), nil)
+	utils.AssertEq(t, os.WriteFile(filepath.Join(dir, "main.lb"), []byte(src), 0o644), nil)
+
+	triple, lower := loweredExamples[code]
+	if !lower {
+		triple = defaultTarget
+	}
+	target := types.TargetFor(triple)
+
+	mod, diags := module.LoadWithOverlay(filepath.Join(dir, "main.lb"), module.Overlay{})
+	if diags.HasErrors() {
+		return diags
+	}
+	pkg, diags := typechecker.TypeCheck(mod, typechecker.Options{Target: target, Release: releaseExamples[code]}, diags)
+	if diags.HasErrors() || !lower {
+		return diags
+	}
+	_, diags = lowerer.Lower(pkg, target, diags)
+	return diags
+}
+
+func codes(diags []diagnostics.Diagnostic) []string {
+	result := []string{}
+	for _, diag := range diags {
+		result = append(result, diag.Code)
+	}
+	return result
+}
+
+func TestExplanationExamples(t *testing.T) {
+	for _, explanation := range diagnostics.Explanations {
+		if explanation.Example == "" {
+			continue
+		}
+
+		diags := compileExample(t, explanation.Code, explanation.Example)
+		if !slices.Contains(codes(diags), explanation.Code) {
+			t.Errorf("%s: Expected the example to cause the diagnostic, found [%s]",
+				explanation.Code, strings.Join(codes(diags), ", "))
+		}
+
+		diags = compileExample(t, explanation.Code, explanation.Fixed)
+		if diagnostics.Manager(diags).HasErrors() || slices.Contains(codes(diags), explanation.Code) {
+			t.Errorf("%s: Expected the fixed example to compile, found [%s]",
+				explanation.Code, strings.Join(codes(diags), ", "))
+		}
+	}
+}
+
+func TestEveryCodeExplained(t *testing.T) {
+	source, err := os.ReadFile("diagnostic_manager.go")
+	utils.AssertEq(t, err, nil)
+
+	used := regexp.MustCompile(`"[EW]\d{4}"`).FindAllString(string(source), -1)
+	utils.Assert(t, len(used) != 0, "Expected diagnostics to have codes")
+	for _, code := range used {
+		code = strings.Trim(code, `"`)
+		_, ok := diagnostics.Explain(code)
+		utils.Assert(t, ok, "Expected an explanation for "+code)
+	}
+
+	seen := map[string]bool{}
+	for _, explanation := range diagnostics.Explanations {
+		utils.Assert(t, !seen[explanation.Code], "Duplicate explanation for "+explanation.Code)
+		seen[explanation.Code] = true
+	}
+}
+
+func TestExplain(t *testing.T) {
+	explanation, ok := diagnostics.Explain("e0036")
+	utils.Assert(t, ok, "Expected codes to be case insensitive")
+	utils.AssertEq(t, explanation.String(), `E0036: Cannot modify value, it is immutable
+
+Variables declared with "let" and parameters not marked "mut" can't be
+changed after they are declared. Declare the variable with "mut" to
+allow it to change.
+
+Erroneous code example:
+
+    let count = 0
+    count = 1
+
+Fixed code:
+
+    mut count = 0
+    count = 1
+`)
+
+	_, ok = diagnostics.Explain("E9999")
+	utils.Assert(t, !ok, "Expected unknown codes to have no explanation")
+}
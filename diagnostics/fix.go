@@ -0,0 +1,80 @@
+package diagnostics
+
+import (
+	"slices"
+
+	"github.com/gearsdatapacks/libra/text"
+)
+
+// The fixes which can be applied to a set of diagnostics. Only fixes
+// which are machine-applicable are included, unless `all` is set.
+// Only the first fix of each diagnostic is used, as the others would
+// be different ways of fixing the same problem.
+func Fixes(diagnostics []Diagnostic, all bool) []Fix {
+	fixes := []Fix{}
+	for _, diagnostic := range diagnostics {
+		for _, fix := range diagnostic.Fixes {
+			if fix.MachineApplicable || all {
+				fixes = append(fixes, fix)
+				break
+			}
+		}
+	}
+	return fixes
+}
+
+// Applies fixes to the source code, and returns the new contents of
+// each file which changed, along with the number of fixes applied.
+// Fixes which overlap one that has already been applied are skipped.
+func ApplyFixes(fixes []Fix) (map[*text.SourceFile]string, int) {
+	edits := map[*text.SourceFile][]Edit{}
+	applied := 0
+
+	for _, fix := range fixes {
+		overlaps := false
+		for _, edit := range fix.Edits {
+			for _, other := range edits[edit.Location.File] {
+				if overlap(edit.Location.Span, other.Location.Span) {
+					overlaps = true
+				}
+			}
+		}
+		if overlaps {
+			continue
+		}
+
+		for _, edit := range fix.Edits {
+			edits[edit.Location.File] = append(edits[edit.Location.File], edit)
+		}
+		applied++
+	}
+
+	files := map[*text.SourceFile]string{}
+	for file, fileEdits := range edits {
+		// Edits are applied from the end of the file, so that
+		// applying one doesn't move the others
+		slices.SortFunc(fileEdits, func(a, b Edit) int {
+			return b.Location.Span.Start - a.Location.Span.Start
+		})
+
+		contents := file.Text
+		for _, edit := range fileEdits {
+			span := edit.Location.Span
+			contents = contents[:span.Start] + edit.Replacement + contents[span.End:]
+		}
+		if contents != file.Text {
+			files[file] = contents
+		}
+	}
+
+	return files, applied
+}
+
+// Whether two spans overlap. Insertions at the same
+// position overlap, as their order would be ambiguous.
+func overlap(a, b text.Span) bool {
+	if a.Start == b.Start {
+		return true
+	}
+	return a.Start < b.End && b.Start < a.End
+}
@@ -0,0 +1,62 @@
+package diagnostics_test
+
+import (
+	"testing"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/text"
+)
+
+func TestSuggestions(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		suggestion string
+	}{
+		{"valeu", []string{"value", "values", "other"}, `Did you mean "value"?`},
+		{"Lenght", []string{"length", "width"}, `Did you mean "length"?`},
+		{"x", []string{"xs", "index"}, `Did you mean "xs"?`},
+		{"count", []string{"amount", "total"}, ""},
+		{"a", []string{"bcd"}, ""},
+		{"value", []string{}, ""},
+	}
+
+	for _, test := range tests {
+		file := text.NewFile("test.lb", test.name)
+		location := text.Location{File: file, Span: text.NewSpan(0, len(test.name))}
+		diag := diagnostics.VariableUndefined(location, test.name, test.candidates)
+
+		if test.suggestion == "" {
+			utils.AssertEq(t, len(diag.Fixes), 0)
+			continue
+		}
+		fix := utils.AssertSingle(t, diag.Fixes)
+		utils.AssertEq(t, fix.Message, test.suggestion)
+		utils.AssertEq(t, fix.MachineApplicable, false)
+	}
+}
+
+func TestApplyFixes(t *testing.T) {
+	file := text.NewFile("test.lb", "mut x = 1\ny = valeu\n")
+	at := func(start, end int) text.Location {
+		return text.Location{File: file, Span: text.NewSpan(start, end)}
+	}
+
+	var manager diagnostics.Manager
+	manager.Report(diagnostics.VariableUndefined(at(14, 19), "valeu", []string{"value"}))
+	manager.Report(diagnostics.VariableUndefined(at(14, 19), "valeu", []string{"values"}))
+	manager.Report(diagnostics.UnnecessaryMut(
+		at(4, 5), "x",
+		diagnostics.MakeImmutable("x", at(0, 3), at(4, 5), false),
+	))
+
+	files, applied := diagnostics.ApplyFixes(diagnostics.Fixes(manager, false))
+	utils.AssertEq(t, applied, 1)
+	utils.AssertEq(t, files[file], "let x = 1\ny = valeu\n")
+
+	// The second suggestion overlaps the first, so it is skipped
+	files, applied = diagnostics.ApplyFixes(diagnostics.Fixes(manager, true))
+	utils.AssertEq(t, applied, 2)
+	utils.AssertEq(t, files[file], "let x = 1\ny = value\n")
+}
@@ -0,0 +1,334 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gearsdatapacks/libra/text"
+)
+
+// How diagnostics are written. JSON and SARIF are meant to be
+// read by other programs, such as CI systems and editors.
+type Format int
+
+const (
+	HumanFormat Format = iota
+	JSONFormat
+	SARIFFormat
+)
+
+func ParseFormat(format string) (Format, error) {
+	switch format {
+	case "human":
+		return HumanFormat, nil
+	case "json":
+		return JSONFormat, nil
+	case "sarif":
+		return SARIFFormat, nil
+	default:
+		return HumanFormat, fmt.Errorf("Unknown diagnostics format %q", format)
+	}
+}
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Writes diagnostics in the given format. In JSON and SARIF,
+// the labels of a diagnostic are its related locations, and
+// its fixes are written as structured edits.
+func Write(to io.Writer, format Format, diagnostics []Diagnostic, printColour bool) error {
+	switch format {
+	case JSONFormat:
+		return writeJSON(to, diagnostics)
+	case SARIFFormat:
+		return writeSARIF(to, diagnostics)
+	default:
+		for _, diagnostic := range diagnostics {
+			diagnostic.WriteTo(to, printColour)
+		}
+		return nil
+	}
+}
+
+// A location in a source file. Lines and columns start at 1,
+// and the end column is one past the last character.
+type jsonLocation struct {
+	File        string `json:"file"`
+	StartLine   int    `json:"start_line"`
+	StartColumn int    `json:"start_column"`
+	EndLine     int    `json:"end_line"`
+	EndColumn   int    `json:"end_column"`
+}
+
+type jsonRelated struct {
+	Message  string        `json:"message"`
+	Location *jsonLocation `json:"location"`
+}
+
+type jsonEdit struct {
+	Location    *jsonLocation `json:"location"`
+	Replacement string        `json:"replacement"`
+}
+
+type jsonFix struct {
+	Message           string     `json:"message"`
+	MachineApplicable bool       `json:"machine_applicable"`
+	Edits             []jsonEdit `json:"edits"`
+}
+
+type jsonDiagnostic struct {
+	Kind     string        `json:"kind"`
+	Code     string        `json:"code,omitempty"`
+	Message  string        `json:"message"`
+	Location *jsonLocation `json:"location"`
+	Related  []jsonRelated `json:"related"`
+	Notes    []string      `json:"notes"`
+	Help     []string      `json:"help"`
+	Fixes    []jsonFix     `json:"fixes"`
+}
+
+// Diagnostics not caused by any part of the source code have no location
+func toJSONLocation(location text.Location) *jsonLocation {
+	if location.File == nil {
+		return nil
+	}
+	span := location.Span.ToLineSpan(location.File)
+	return &jsonLocation{
+		File:        location.File.FileName,
+		StartLine:   span.StartLine + 1,
+		StartColumn: span.StartColumn + 1,
+		EndLine:     span.EndLine + 1,
+		EndColumn:   span.EndColumn + 1,
+	}
+}
+
+func writeJSON(to io.Writer, diagnostics []Diagnostic) error {
+	result := []jsonDiagnostic{}
+	for _, diagnostic := range diagnostics {
+		converted := jsonDiagnostic{
+			Kind:     diagnostic.Kind.String(),
+			Code:     diagnostic.Code,
+			Message:  diagnostic.Message,
+			Location: toJSONLocation(diagnostic.Location),
+			Related:  []jsonRelated{},
+			Notes:    append([]string{}, diagnostic.Notes...),
+			Help:     append([]string{}, diagnostic.Help...),
+			Fixes:    []jsonFix{},
+		}
+		for _, label := range diagnostic.Labels {
+			converted.Related = append(converted.Related, jsonRelated{
+				Message:  label.Message,
+				Location: toJSONLocation(label.Location),
+			})
+		}
+		for _, fix := range diagnostic.Fixes {
+			edits := []jsonEdit{}
+			for _, edit := range fix.Edits {
+				edits = append(edits, jsonEdit{
+					Location:    toJSONLocation(edit.Location),
+					Replacement: edit.Replacement,
+				})
+			}
+			converted.Fixes = append(converted.Fixes, jsonFix{
+				Message:           fix.Message,
+				MachineApplicable: fix.MachineApplicable,
+				Edits:             edits,
+			})
+		}
+		result = append(result, converted)
+	}
+
+	encoder := json.NewEncoder(to)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// The subset of the SARIF 2.1.0 format which is needed to
+// describe diagnostics. See https://sarifweb.azurewebsites.net.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name string `json:"name"`
+}
+
+type sarifResult struct {
+	RuleId           string          `json:"ruleId,omitempty"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion       `json:"deletedRegion"`
+	InsertedContent sarifArtifactText `json:"insertedContent"`
+}
+
+type sarifArtifactText struct {
+	Text string `json:"text"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	Id               *int                  `json:"id,omitempty"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+func toSARIFLocation(textLocation text.Location) (sarifLocation, bool) {
+	location := toJSONLocation(textLocation)
+	if location == nil {
+		return sarifLocation{}, false
+	}
+	return sarifLocation{
+		PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{Uri: location.File},
+			Region: sarifRegion{
+				StartLine:   location.StartLine,
+				StartColumn: location.StartColumn,
+				EndLine:     location.EndLine,
+				EndColumn:   location.EndColumn,
+			},
+		},
+	}, true
+}
+
+// Each file changed by a fix is a separate artifact change
+func toSARIFFix(fix Fix) sarifFix {
+	converted := sarifFix{
+		Description:     sarifMessage{Text: fix.Message},
+		ArtifactChanges: []sarifArtifactChange{},
+	}
+	changes := map[string]int{}
+	for _, edit := range fix.Edits {
+		location, ok := toSARIFLocation(edit.Location)
+		if !ok {
+			continue
+		}
+		uri := location.PhysicalLocation.ArtifactLocation.Uri
+		index, ok := changes[uri]
+		if !ok {
+			index = len(converted.ArtifactChanges)
+			changes[uri] = index
+			converted.ArtifactChanges = append(converted.ArtifactChanges, sarifArtifactChange{
+				ArtifactLocation: location.PhysicalLocation.ArtifactLocation,
+				Replacements:     []sarifReplacement{},
+			})
+		}
+		change := &converted.ArtifactChanges[index]
+		change.Replacements = append(change.Replacements, sarifReplacement{
+			DeletedRegion:   location.PhysicalLocation.Region,
+			InsertedContent: sarifArtifactText{Text: edit.Replacement},
+		})
+	}
+	return converted
+}
+
+func writeSARIF(to io.Writer, diagnostics []Diagnostic) error {
+	results := []sarifResult{}
+	for _, diagnostic := range diagnostics {
+		level := "note"
+		switch diagnostic.Kind {
+		case Error:
+			level = "error"
+		case Warning:
+			level = "warning"
+		}
+
+		// SARIF has no notes, so they are added to the message
+		message := diagnostic.Message
+		for _, note := range diagnostic.Notes {
+			message += "\nnote: " + note
+		}
+		for _, help := range diagnostic.Help {
+			message += "\nhelp: " + help
+		}
+
+		result := sarifResult{
+			RuleId:    diagnostic.Code,
+			Level:     level,
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{},
+		}
+		if location, ok := toSARIFLocation(diagnostic.Location); ok {
+			result.Locations = append(result.Locations, location)
+		}
+		for _, label := range diagnostic.Labels {
+			location, ok := toSARIFLocation(label.Location)
+			if !ok {
+				continue
+			}
+			id := len(result.RelatedLocations)
+			location.Id = &id
+			location.Message = &sarifMessage{Text: label.Message}
+			result.RelatedLocations = append(result.RelatedLocations, location)
+		}
+		for _, fix := range diagnostic.Fixes {
+			result.Fixes = append(result.Fixes, toSARIFFix(fix))
+		}
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(to)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "libra"}},
+			Results: results,
+		}},
+	})
+}
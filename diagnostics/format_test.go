@@ -0,0 +1,89 @@
+package diagnostics_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/text"
+)
+
+func testDiagnostics() []diagnostics.Diagnostic {
+	file := text.NewFile("test.lb", "let in = 1\nfor i in [1] {}")
+	var manager diagnostics.Manager
+	manager.Report(diagnostics.KeywordOverwritten(
+		text.Location{File: file, Span: text.NewSpan(17, 19)},
+		"in",
+		text.Location{File: file, Span: text.NewSpan(4, 6)},
+	))
+	return manager
+}
+
+func TestJSON(t *testing.T) {
+	var output bytes.Buffer
+	err := diagnostics.Write(&output, diagnostics.JSONFormat, testDiagnostics(), false)
+	utils.AssertEq(t, err, nil)
+
+	var result []struct {
+		Kind     string
+		Code     string
+		Message  string
+		Location struct {
+			File        string
+			StartLine   int `json:"start_line"`
+			StartColumn int `json:"start_column"`
+			EndLine     int `json:"end_line"`
+			EndColumn   int `json:"end_column"`
+		}
+		Related []struct {
+			Message  string
+			Location struct {
+				StartLine   int `json:"start_line"`
+				StartColumn int `json:"start_column"`
+			}
+		}
+	}
+	utils.AssertEq(t, json.Unmarshal(output.Bytes(), &result), nil)
+
+	diagnostic := utils.AssertSingle(t, result)
+	utils.AssertEq(t, diagnostic.Kind, "error")
+	utils.AssertEq(t, diagnostic.Code, "E0015")
+	utils.AssertEq(t, diagnostic.Location.File, "test.lb")
+	utils.AssertEq(t, diagnostic.Location.StartLine, 2)
+	utils.AssertEq(t, diagnostic.Location.StartColumn, 7)
+	utils.AssertEq(t, diagnostic.Location.EndLine, 2)
+	utils.AssertEq(t, diagnostic.Location.EndColumn, 9)
+
+	related := utils.AssertSingle(t, diagnostic.Related)
+	utils.AssertEq(t, related.Location.StartLine, 1)
+	utils.AssertEq(t, related.Location.StartColumn, 5)
+}
+
+func TestSARIF(t *testing.T) {
+	var output bytes.Buffer
+	err := diagnostics.Write(&output, diagnostics.SARIFFormat, testDiagnostics(), false)
+	utils.AssertEq(t, err, nil)
+
+	var result struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleId           string
+				Level            string
+				Locations        []any
+				RelatedLocations []any
+			}
+		}
+	}
+	utils.AssertEq(t, json.Unmarshal(output.Bytes(), &result), nil)
+	utils.AssertEq(t, result.Version, "2.1.0")
+
+	run := utils.AssertSingle(t, result.Runs)
+	diagnostic := utils.AssertSingle(t, run.Results)
+	utils.AssertEq(t, diagnostic.RuleId, "E0015")
+	utils.AssertEq(t, diagnostic.Level, "error")
+	utils.AssertEq(t, len(diagnostic.Locations), 1)
+	utils.AssertEq(t, len(diagnostic.RelatedLocations), 1)
+}
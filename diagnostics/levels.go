@@ -0,0 +1,151 @@
+package diagnostics
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/text"
+)
+
+// How a warning is reported
+type Level int
+
+const (
+	Warn Level = iota
+	// Allowed warnings aren't reported
+	Allow
+	// Denied warnings are reported as errors
+	Deny
+)
+
+// A part of the source code in which a warning is reported at a
+// different level, set with an `@allow` or `@deny` attribute
+type Scope struct {
+	Location  text.Location
+	Attribute text.Location
+	Code      string
+	Level     Level
+}
+
+func (s Scope) contains(location text.Location) bool {
+	return s.Location.File == location.File &&
+		s.Location.Span.Start <= location.Span.Start &&
+		location.Span.End <= s.Location.Span.End
+}
+
+// Decides how warnings are reported. Levels set in the source code take
+// precedence over those set on the command line, with the innermost
+// scope taking precedence over the ones it is inside.
+type Levels struct {
+	// Whether warnings are reported as errors, unless their code has
+	// a level of its own
+	WarningsAsErrors bool
+	// The levels of codes set on the command line
+	Codes  map[string]Level
+	Scopes []Scope
+}
+
+// Parses a warning flag from the command line, without its `-W` prefix.
+// `-Werror` reports all warnings as errors, and `-Werror=<code>` reports
+// one kind of warning as an error. `-Wno-<code>` disables a warning, and
+// `-W<code>` reports it as a warning again.
+func (l *Levels) ParseFlag(flag string) error {
+	if flag == "error" {
+		l.WarningsAsErrors = true
+		return nil
+	}
+
+	level := Warn
+	code := flag
+	if denied, ok := strings.CutPrefix(flag, "error="); ok {
+		level = Deny
+		code = denied
+	} else if allowed, ok := strings.CutPrefix(flag, "no-"); ok {
+		level = Allow
+		code = allowed
+	}
+
+	code = strings.ToUpper(code)
+	if !IsWarningCode(code) {
+		return fmt.Errorf("Unknown warning code %q", code)
+	}
+	if l.Codes == nil {
+		l.Codes = map[string]Level{}
+	}
+	l.Codes[code] = level
+	return nil
+}
+
+// The level of a warning, and the scope which set it, if any
+func (l Levels) level(diagnostic Diagnostic) (Level, *Scope) {
+	var innermost *Scope
+	for i, scope := range l.Scopes {
+		if scope.Code != diagnostic.Code || !scope.contains(diagnostic.Location) {
+			continue
+		}
+		if innermost == nil || scope.Location.Span.Start >= innermost.Location.Span.Start {
+			innermost = &l.Scopes[i]
+		}
+	}
+	if innermost != nil {
+		return innermost.Level, innermost
+	}
+
+	if level, ok := l.Codes[diagnostic.Code]; ok {
+		return level, nil
+	}
+	if l.WarningsAsErrors {
+		return Deny, nil
+	}
+	return Warn, nil
+}
+
+// Removes allowed warnings, and turns denied warnings into errors.
+// Errors are always reported, so are left as they are.
+func (m Manager) WithLevels(levels Levels) Manager {
+	result := Manager{}
+	for _, diagnostic := range m {
+		if diagnostic.Kind != Warning {
+			result = append(result, diagnostic)
+			continue
+		}
+
+		level, scope := levels.level(diagnostic)
+		switch level {
+		case Allow:
+			continue
+		case Deny:
+			// The original diagnostic is left unchanged, as the
+			// levels may be applied to it again
+			diagnostic.Kind = Error
+			diagnostic.Labels = slices.Clone(diagnostic.Labels)
+			diagnostic.Notes = slices.Clone(diagnostic.Notes)
+			if scope != nil {
+				diagnostic.WithLabel(scope.Attribute, "Denied here")
+			} else if _, ok := levels.Codes[diagnostic.Code]; ok {
+				diagnostic.WithNote(fmt.Sprintf("Reported as an error because of %q", "-Werror="+diagnostic.Code))
+			} else {
+				diagnostic.WithNote(fmt.Sprintf("Reported as an error because of %q", "-Werror"))
+			}
+		}
+		result = append(result, diagnostic)
+	}
+	return result
+}
+
+// Whether a code belongs to a kind of warning, which can be
+// allowed or denied
+func IsWarningCode(code string) bool {
+	return slices.Contains(warningCodes(), code)
+}
+
+func warningCodes() []string {
+	codes := []string{}
+	for _, explanation := range Explanations {
+		if strings.HasPrefix(explanation.Code, "W") {
+			codes = append(codes, explanation.Code)
+		}
+	}
+	return codes
+}
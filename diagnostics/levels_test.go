@@ -0,0 +1,90 @@
+package diagnostics_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/module"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/text"
+	typechecker "github.com/gearsdatapacks/libra/type_checker"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+func TestParseFlag(t *testing.T) {
+	var levels diagnostics.Levels
+	utils.AssertEq(t, levels.ParseFlag("error"), nil)
+	utils.AssertEq(t, levels.ParseFlag("no-w0003"), nil)
+	utils.AssertEq(t, levels.ParseFlag("error=W0006"), nil)
+	utils.AssertEq(t, levels.ParseFlag("W0007"), nil)
+
+	utils.Assert(t, levels.WarningsAsErrors, "Expected warnings to be errors")
+	utils.AssertEq(t, levels.Codes["W0003"], diagnostics.Allow)
+	utils.AssertEq(t, levels.Codes["W0006"], diagnostics.Deny)
+	utils.AssertEq(t, levels.Codes["W0007"], diagnostics.Warn)
+
+	utils.Assert(t, levels.ParseFlag("no-E0001") != nil, "Expected errors not to be allowed")
+	utils.Assert(t, levels.ParseFlag("error=W9999") != nil, "Expected unknown codes to be rejected")
+}
+
+func TestWithLevels(t *testing.T) {
+	file := text.NewFile("test.lb", "let a = 1\nlet b = 2\n")
+	at := func(start, end int) text.Location {
+		return text.Location{File: file, Span: text.NewSpan(start, end)}
+	}
+
+	var manager diagnostics.Manager
+	manager.Report(diagnostics.UnusedVariable(at(4, 5), "a"))
+	manager.Report(diagnostics.UnusedVariable(at(14, 15), "b"))
+	manager.Report(diagnostics.UnusedFunction(at(14, 15), "b"))
+
+	levels := diagnostics.Levels{
+		Codes: map[string]diagnostics.Level{"W0006": diagnostics.Allow},
+		Scopes: []diagnostics.Scope{{
+			Location:  at(10, 19),
+			Attribute: at(10, 13),
+			Code:      "W0003",
+			Level:     diagnostics.Deny,
+		}},
+	}
+	result := manager.WithLevels(levels)
+	utils.AssertEq(t, len(result), 2)
+	utils.AssertEq(t, result[0].Kind, diagnostics.Warning)
+	utils.AssertEq(t, result[1].Kind, diagnostics.Error)
+	utils.AssertEq(t, result[1].Labels[len(result[1].Labels)-1].Message, "Denied here")
+	// The original diagnostics are left unchanged
+	utils.AssertEq(t, manager[1].Kind, diagnostics.Warning)
+
+	levels = diagnostics.Levels{WarningsAsErrors: true}
+	result = manager.WithLevels(levels)
+	utils.AssertEq(t, len(result), 3)
+	utils.Assert(t, result.HasErrors(), "Expected warnings to be errors")
+}
+
+const levelsSrc = `@allow(W0006)
+fn helper() {}
+
+@deny(W0003)
+fn main() {
+  let unused = 1
+  @allow(W0003) {
+    let allowed = 2
+  }
+}`
+
+func TestLevelAttributes(t *testing.T) {
+	dir := t.TempDir()
+	utils.AssertEq(t, os.WriteFile(filepath.Join(dir, "main.lb"), []byte(levelsSrc), 0o644), nil)
+
+	mod, diags := module.LoadWithOverlay(filepath.Join(dir, "main.lb"), module.Overlay{})
+	utils.AssertEq(t, len(diags), 0)
+	_, diags = typechecker.TypeCheck(mod, typechecker.Options{Target: types.TargetFor(defaultTarget)}, diags)
+
+	diags = diags.WithLevels(diagnostics.Levels{Scopes: mod.DiagnosticScopes()})
+	diag := utils.AssertSingle(t, diags)
+	utils.AssertEq(t, diag.Code, "W0003")
+	utils.AssertEq(t, diag.Kind, diagnostics.Error)
+	utils.AssertEq(t, diag.Message, `Variable "unused" is never used`)
+}
@@ -0,0 +1,129 @@
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/text"
+)
+
+// Finds the name which was most likely meant instead of a misspelled
+// one, if any of the candidates are similar enough to it
+func closestMatch(name string, candidates []string) (string, bool) {
+	// Short names are only one or two edits away from many others,
+	// so fewer edits are allowed for them
+	maxDistance := max(len(name)/3, 1)
+	best := ""
+	bestDistance := maxDistance + 1
+
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance {
+			best = candidate
+			bestDistance = distance
+		}
+	}
+	return best, best != ""
+}
+
+// The number of characters which need to be inserted, removed or
+// replaced, or pairs of adjacent characters which need to be swapped,
+// to turn one string into the other
+func editDistance(a, b string) int {
+	left, right := []rune(a), []rune(b)
+	distances := make([][]int, len(left)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(right)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(left); i++ {
+		for j := 1; j <= len(right); j++ {
+			cost := 1
+			if left[i-1] == right[j-1] {
+				cost = 0
+			}
+			distances[i][j] = min(
+				distances[i-1][j]+1,
+				distances[i][j-1]+1,
+				distances[i-1][j-1]+cost,
+			)
+			if i > 1 && j > 1 && left[i-1] == right[j-2] && left[i-2] == right[j-1] {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+	return distances[len(left)][len(right)]
+}
+
+func didYouMean(location text.Location, name string) Fix {
+	return Fix{
+		Message: fmt.Sprintf("Did you mean %q?", name),
+		Edits:   []Edit{{Location: location, Replacement: name}},
+	}
+}
+
+// Suggests a name which was likely meant instead of the given one
+func (d *Diagnostic) suggest(name string, candidates []string) *Diagnostic {
+	if match, ok := closestMatch(name, candidates); ok {
+		d.WithFix(didYouMean(d.Location, match))
+	}
+	return d
+}
+
+func (p *Partial) suggest(name string, candidates []string) *Partial {
+	if match, ok := closestMatch(name, candidates); ok {
+		p.suggestion = match
+	}
+	return p
+}
+
+// Makes a variable mutable, by declaring it with `mut` instead of
+// `let`, or by adding `mut` before a parameter
+func MakeMutable(name string, keyword, nameLocation text.Location) Fix {
+	edit := Edit{Location: keyword, Replacement: "mut"}
+	if keyword.File == nil {
+		edit = Edit{Location: insertAt(nameLocation), Replacement: "mut "}
+	}
+	return Fix{
+		Message:           fmt.Sprintf("Declare %q as mutable", name),
+		Edits:             []Edit{edit},
+		MachineApplicable: true,
+	}
+}
+
+// Makes a variable immutable, by declaring it with `let` instead
+// of `mut`, or by removing `mut` from before a parameter
+func MakeImmutable(name string, keyword, nameLocation text.Location, parameter bool) Fix {
+	if parameter {
+		return Fix{
+			Message: fmt.Sprintf("Remove %q from %q", "mut", name),
+			Edits: []Edit{{
+				Location: text.Location{
+					File: keyword.File,
+					Span: text.NewSpan(keyword.Span.Start, nameLocation.Span.Start),
+				},
+				Replacement: "",
+			}},
+			MachineApplicable: true,
+		}
+	}
+	return Fix{
+		Message:           fmt.Sprintf("Declare %q with %q instead", name, "let"),
+		Edits:             []Edit{{Location: keyword, Replacement: "let"}},
+		MachineApplicable: true,
+	}
+}
+
+func insertAt(location text.Location) text.Location {
+	return text.Location{
+		File: location.File,
+		Span: text.NewSpan(location.Span.Start, location.Span.Start),
+	}
+}
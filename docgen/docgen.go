@@ -0,0 +1,416 @@
+package docgen
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"github.com/gearsdatapacks/libra/type_checker/values"
+)
+
+// The documentation of a package, with a page for each module
+type Site struct {
+	Pages []*Page
+	// Where each documented type can be found, used to link to it
+	types map[string][]link
+}
+
+type Page struct {
+	Module    string
+	FileName  string
+	Types     []*Item
+	Functions []*Item
+}
+
+// A documented declaration
+type Item struct {
+	Kind          string
+	Name          string
+	Anchor        string
+	Signature     string
+	Documentation ir.Documentation
+	Methods       []*Item
+}
+
+type link struct {
+	page   *Page
+	anchor string
+}
+
+// Collects the documentation of every exported declaration in a package
+func New(pkg *ir.Package) *Site {
+	site := &Site{types: map[string][]link{}}
+	// The index pages use this name
+	fileNames := map[string]int{"index": 1}
+
+	for _, modPath := range sortedKeys(pkg.Modules) {
+		mod := pkg.Modules[modPath]
+		page := &Page{Module: mod.Name, FileName: mod.Name}
+		// Different modules can have the same name
+		if count := fileNames[mod.Name]; count != 0 {
+			page.FileName = fmt.Sprintf("%s_%d", mod.Name, count+1)
+		}
+		fileNames[mod.Name]++
+
+		typeItems := map[string]*Item{}
+		methods := []*ir.FunctionDeclaration{}
+
+		for _, stmt := range mod.Statements {
+			switch decl := stmt.(type) {
+			case *ir.TypeDeclaration:
+				if !decl.Exported {
+					continue
+				}
+				item := &Item{
+					Kind:          typeKind(decl.Type),
+					Name:          decl.Name,
+					Anchor:        "type." + decl.Name,
+					Signature:     typeSignature(decl.Name, decl.Type),
+					Documentation: decl.Documentation,
+				}
+				page.Types = append(page.Types, item)
+				typeItems[decl.Name] = item
+				site.types[decl.Name] = append(site.types[decl.Name], link{page, item.Anchor})
+
+			case *ir.FunctionDeclaration:
+				if !decl.Exported {
+					continue
+				}
+				if decl.MethodOf != nil || decl.MemberOf != nil {
+					methods = append(methods, decl)
+					continue
+				}
+				page.Functions = append(page.Functions, &Item{
+					Kind:          "function",
+					Name:          decl.Name,
+					Anchor:        "fn." + decl.Name,
+					Signature:     functionSignature(decl),
+					Documentation: decl.Documentation,
+				})
+			}
+		}
+
+		// Methods are listed with their type if it is documented on
+		// the same page, and with the other functions otherwise
+		for _, method := range methods {
+			owner := method.MemberOf
+			if owner == nil {
+				owner = method.MethodOf
+			}
+			if pointer, ok := owner.(*types.Pointer); ok {
+				owner = pointer.Underlying
+			}
+
+			item := &Item{
+				Kind:          "method",
+				Name:          method.Name,
+				Signature:     functionSignature(method),
+				Documentation: method.Documentation,
+			}
+			if typeItem, ok := typeItems[owner.String()]; ok {
+				item.Anchor = "method." + typeItem.Name + "." + method.Name
+				typeItem.Methods = append(typeItem.Methods, item)
+			} else {
+				item.Anchor = "method." + owner.String() + "." + method.Name
+				page.Functions = append(page.Functions, item)
+			}
+		}
+
+		site.Pages = append(site.Pages, page)
+	}
+
+	return site
+}
+
+// Writes the HTML and Markdown documentation to a directory,
+// with an index page and a page for each module
+func (s *Site) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"index.html": s.HTMLIndex(),
+		"index.md":   s.MarkdownIndex(),
+	}
+	for _, page := range s.Pages {
+		files[page.FileName+".html"] = s.HTML(page)
+		files[page.FileName+".md"] = s.Markdown(page)
+	}
+
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func typeKind(ty types.Type) string {
+	switch ty.(type) {
+	case *types.Struct, *types.TupleStruct, *types.UnitStruct:
+		return "struct"
+	case *types.Union:
+		return "union"
+	case *types.Enum:
+		return "enum"
+	case *types.Interface:
+		return "interface"
+	case *types.Tag:
+		return "tag"
+	default:
+		return "type"
+	}
+}
+
+func typeSignature(name string, ty types.Type) string {
+	switch t := ty.(type) {
+	case *types.Struct:
+		fields := []string{}
+		private := false
+		for _, fieldName := range fieldNames(t) {
+			field := t.Fields[fieldName]
+			if field.Exported {
+				fields = append(fields, fieldName+": "+field.Type.String())
+			} else {
+				private = true
+			}
+		}
+		if private {
+			fields = append(fields, "// Some fields are private")
+		}
+		return "struct " + name + body(fields, true)
+	case *types.TupleStruct:
+		fields := []string{}
+		for _, field := range t.Types {
+			fields = append(fields, field.String())
+		}
+		return "struct " + name + body(fields, false)
+	case *types.UnitStruct:
+		return "struct " + name
+
+	case *types.Union:
+		members := []string{}
+		for _, memberName := range sortedKeys(t.Members) {
+			member := t.Members[memberName]
+			variant, ok := member.(*types.UnionVariant)
+			if !ok {
+				members = append(members, memberName)
+			} else if compound, ok := variant.Type.(*types.Struct); ok && compound.Name == memberName {
+				fields := []string{}
+				for _, fieldName := range fieldNames(compound) {
+					fields = append(fields, fieldName+": "+compound.Fields[fieldName].Type.String())
+				}
+				members = append(members, memberName+body(fields, false))
+			} else {
+				members = append(members, memberName+": "+variant.Type.String())
+			}
+		}
+		signature := "union " + name + body(members, true)
+		if t.Untagged {
+			signature = "@untagged\n" + signature
+		}
+		return signature
+
+	case *types.Enum:
+		members := []string{}
+		for _, memberName := range enumMembers(t) {
+			members = append(members, memberName+" = "+constString(t.Members[memberName]))
+		}
+		return "enum " + name + ": " + t.Underlying.String() + body(members, true)
+
+	case *types.Interface:
+		methods := []string{}
+		for _, methodName := range sortedKeys(t.Methods) {
+			methods = append(methods, methodName+strings.TrimPrefix(t.Methods[methodName].String(), "fn"))
+		}
+		return "interface " + name + body(methods, true)
+
+	case *types.Tag:
+		members := []string{}
+		for _, member := range t.Types {
+			members = append(members, member.String())
+		}
+		return "tag " + name + body(members, false)
+
+	case *types.Explicit:
+		return "explicit type " + name + " = " + t.Type.String()
+	case *types.Alias:
+		return "type " + name + " = " + t.Type.String()
+	default:
+		return "type " + name + " = " + ty.String()
+	}
+}
+
+// The members of an enum, in order of their values. Members with
+// the same value are ordered by name.
+func enumMembers(enum *types.Enum) []string {
+	members := sortedKeys(enum.Members)
+	slices.SortStableFunc(members, func(a, b string) int {
+		return compareConst(enum.Members[a], enum.Members[b])
+	})
+	return members
+}
+
+func compareConst(a, b values.ConstValue) int {
+	switch a := a.(type) {
+	case values.IntValue:
+		if b, ok := b.(values.IntValue); ok {
+			return cmp.Compare(a.Value, b.Value)
+		}
+	case values.UintValue:
+		if b, ok := b.(values.UintValue); ok {
+			return cmp.Compare(a.Value, b.Value)
+		}
+	case values.FloatValue:
+		if b, ok := b.(values.FloatValue); ok {
+			return cmp.Compare(a.Value, b.Value)
+		}
+	case values.StringValue:
+		if b, ok := b.(values.StringValue); ok {
+			return cmp.Compare(a.Value, b.Value)
+		}
+	}
+	return 0
+}
+
+// Formats a constant value as it would be written in source code
+func constString(value values.ConstValue) string {
+	switch value := value.(type) {
+	case values.IntValue:
+		return strconv.FormatInt(value.Value, 10)
+	case values.UintValue:
+		return strconv.FormatUint(value.Value, 10)
+	case values.FloatValue:
+		formatted := strconv.FormatFloat(value.Value, 'g', -1, 64)
+		if !strings.ContainsAny(formatted, ".eEnN") {
+			formatted += ".0"
+		}
+		return formatted
+	case values.StringValue:
+		return strconv.Quote(value.Value)
+	case values.BoolValue:
+		return strconv.FormatBool(value.Value)
+	default:
+		return "..."
+	}
+}
+
+// The fields of a struct, in the order they were declared if it is known.
+// The compound members of unions don't store their order.
+func fieldNames(struc *types.Struct) []string {
+	if len(struc.FieldOrder) == len(struc.Fields) {
+		return struc.FieldOrder
+	}
+	return sortedKeys(struc.Fields)
+}
+
+// Prints the body of a declaration, either on one line
+// or with each element on its own line
+func body(elements []string, multiline bool) string {
+	if len(elements) == 0 {
+		return " {}"
+	}
+	if !multiline {
+		return " { " + strings.Join(elements, ", ") + " }"
+	}
+
+	var result strings.Builder
+	result.WriteString(" {\n")
+	for _, element := range elements {
+		result.WriteString("  " + element)
+		if !strings.HasPrefix(element, "//") {
+			result.WriteByte(',')
+		}
+		result.WriteByte('\n')
+	}
+	result.WriteByte('}')
+	return result.String()
+}
+
+func functionSignature(fn *ir.FunctionDeclaration) string {
+	var result strings.Builder
+	result.WriteString("fn ")
+	if fn.MethodOf != nil {
+		result.WriteString("(" + fn.MethodOf.String() + ") ")
+	}
+	if fn.MemberOf != nil {
+		result.WriteString(fn.MemberOf.String() + ".")
+	}
+	result.WriteString(fn.Name + "(")
+	for i, param := range fn.Type.Parameters {
+		if i != 0 {
+			result.WriteString(", ")
+		}
+		if i < len(fn.Parameters) {
+			result.WriteString(fn.Parameters[i] + ": ")
+		}
+		result.WriteString(param.String())
+	}
+	result.WriteByte(')')
+	if fn.Type.ReturnType != types.Void {
+		result.WriteString(": " + fn.Type.ReturnType.String())
+	}
+	return result.String()
+}
+
+var identifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// Splits a signature into the text between documented types, and links
+// to those types. Types on the current page are preferred over others
+// with the same name.
+func (s *Site) linkTypes(current *Page, item *Item, signature string, text func(string), typeLink func(name string, page *Page, anchor string)) {
+	last := 0
+	for _, match := range identifier.FindAllStringIndex(signature, -1) {
+		name := signature[match[0]:match[1]]
+		target, ok := s.lookupType(current, name)
+		if !ok || target.anchor == item.Anchor {
+			continue
+		}
+		text(signature[last:match[0]])
+		typeLink(name, target.page, target.anchor)
+		last = match[1]
+	}
+	text(signature[last:])
+}
+
+func (s *Site) lookupType(current *Page, name string) (link, bool) {
+	links := s.types[name]
+	for _, link := range links {
+		if link.page == current {
+			return link, true
+		}
+	}
+	if len(links) == 0 {
+		return link{}, false
+	}
+	return links[0], true
+}
+
+// The documented types used in a signature, other than the item itself
+func (s *Site) references(current *Page, item *Item) []link {
+	links := []link{}
+	s.linkTypes(current, item, item.Signature, func(string) {}, func(name string, page *Page, anchor string) {
+		target := link{page, anchor}
+		if !slices.Contains(links, target) {
+			links = append(links, target)
+		}
+	})
+	return links
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,173 @@
+package docgen_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gearsdatapacks/libra/docgen"
+	"github.com/gearsdatapacks/libra/module"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	typechecker "github.com/gearsdatapacks/libra/type_checker"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+const mainSrc = `import "geometry"
+
+@doc Moves a point
+pub fn move(p: geometry.Point, dx: i32): geometry.Point { p }
+
+fn private() {}
+`
+
+const geometrySrc = `@doc
+A point in space.
+
+It has two coordinates.
+@end
+pub struct Point { pub x: i32, y: i32 }
+
+@doc Creates a point at the origin
+pub fn Point.origin(): Point { Point { x: 0, y: 0 } }
+
+@deprecated Use Point
+pub struct Pair { i32, f32 }
+
+@todo
+pub union Shape { circle: f32, square { side: f32 } }
+
+pub enum Colour { Red, Green, Blue = 10, Amber = 5 }
+
+pub interface Sized { size(): i32 }
+
+pub tag Marker { Point, Pair }
+
+pub explicit type Id = u64
+
+struct Hidden
+`
+
+func document(t *testing.T) *docgen.Site {
+	t.Helper()
+	dir := t.TempDir()
+	utils.AssertEq(t, os.Mkdir(filepath.Join(dir, "geometry"), 0755), nil)
+	utils.AssertEq(t, os.WriteFile(filepath.Join(dir, "main.lb"), []byte(mainSrc), 0644), nil)
+	utils.AssertEq(t, os.WriteFile(filepath.Join(dir, "geometry", "geometry.lb"), []byte(geometrySrc), 0644), nil)
+
+	mod, diags := module.LoadWithOverlay(filepath.Join(dir, "main.lb"), module.Overlay{})
+	utils.AssertEq(t, len(diags), 0)
+	pkg, diags := typechecker.TypeCheck(mod, typechecker.Options{Target: types.TargetFor("x86_64-unknown-linux-gnu")}, diags)
+	// `Pair` is deprecated, so using it is a warning
+	utils.Assert(t, !diags.HasErrors(), "Expected no errors")
+	return docgen.New(pkg)
+}
+
+func page(t *testing.T, site *docgen.Site, name string) *docgen.Page {
+	t.Helper()
+	for _, page := range site.Pages {
+		if page.Module == name {
+			return page
+		}
+	}
+	t.Fatalf("No page for module %q", name)
+	return nil
+}
+
+func assertContains(t *testing.T, text, expected string) {
+	t.Helper()
+	utils.Assert(t, strings.Contains(text, expected), fmt.Sprintf("Expected output to contain:\n%s\nGot:\n%s", expected, text))
+}
+
+func TestItems(t *testing.T) {
+	site := document(t)
+	geometry := page(t, site, "geometry")
+
+	names := []string{}
+	for _, item := range geometry.Types {
+		names = append(names, item.Kind+" "+item.Name)
+	}
+	utils.AssertEq(t, strings.Join(names, ", "),
+		"struct Point, struct Pair, union Shape, enum Colour, interface Sized, tag Marker, type Id")
+
+	point := geometry.Types[0]
+	utils.AssertEq(t, point.Signature, "struct Point {\n  x: i32,\n  // Some fields are private\n}")
+	utils.AssertEq(t, point.Documentation.Text, "A point in space.\n\nIt has two coordinates.")
+	utils.AssertEq(t, len(point.Methods), 1)
+	utils.AssertEq(t, point.Methods[0].Signature, "fn Point.origin(): Point")
+	utils.AssertEq(t, point.Methods[0].Anchor, "method.Point.origin")
+
+	utils.AssertEq(t, geometry.Types[1].Signature, "struct Pair { i32, f32 }")
+	utils.AssertEq(t, geometry.Types[2].Signature, "union Shape {\n  circle: f32,\n  square { side: f32 },\n}")
+	utils.AssertEq(t, geometry.Types[3].Signature, "enum Colour: i32 {\n  Red = 0,\n  Green = 1,\n  Amber = 5,\n  Blue = 10,\n}")
+	utils.AssertEq(t, geometry.Types[4].Signature, "interface Sized {\n  size(): i32,\n}")
+	utils.AssertEq(t, geometry.Types[5].Signature, "tag Marker { Point, Pair }")
+	utils.AssertEq(t, geometry.Types[6].Signature, "explicit type Id = u64")
+}
+
+func TestFunctions(t *testing.T) {
+	site := document(t)
+	var main *docgen.Page
+	for _, page := range site.Pages {
+		if page.Module != "geometry" {
+			main = page
+		}
+	}
+
+	utils.AssertEq(t, len(main.Types), 0)
+	utils.AssertEq(t, len(main.Functions), 1)
+	move := main.Functions[0]
+	utils.AssertEq(t, move.Signature, "fn move(p: Point, dx: i32): Point")
+	utils.AssertEq(t, move.Anchor, "fn.move")
+	utils.AssertEq(t, move.Documentation.Text, "Moves a point")
+}
+
+func TestHTML(t *testing.T) {
+	site := document(t)
+	geometry := page(t, site, "geometry")
+	html := site.HTML(geometry)
+
+	assertContains(t, html, `<section id="type.Point">`)
+	assertContains(t, html, "<p>A point in space.</p>\n<p>It has two coordinates.</p>")
+	assertContains(t, html, `fn <a href="#type.Point">Point</a>.origin(): <a href="#type.Point">Point</a>`)
+	assertContains(t, html, `<p class="note"><strong>Deprecated</strong>: Use Point</p>`)
+	assertContains(t, html, `<p class="note"><strong>Todo</strong></p>`)
+	utils.Assert(t, !strings.Contains(html, "Hidden"), "Private types should not be documented")
+
+	for _, page := range site.Pages {
+		if page != geometry {
+			// Links to types in other modules
+			assertContains(t, site.HTML(page), `<a href="geometry.html#type.Point">Point</a>`)
+			assertContains(t, site.HTMLIndex(), `<a href="`+page.FileName+`.html">`)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	site := document(t)
+	geometry := page(t, site, "geometry")
+	markdown := site.Markdown(geometry)
+
+	assertContains(t, markdown, "<a id=\"type.Marker\"></a>\n\n### tag `Marker`\n\n```libra\ntag Marker { Point, Pair }\n```")
+	assertContains(t, markdown, "References: [Point](#type.Point), [Pair](#type.Pair)")
+	assertContains(t, markdown, "> **Deprecated**: Use Point")
+	assertContains(t, site.MarkdownIndex(), "- [geometry](geometry.md)")
+
+	for _, page := range site.Pages {
+		if page != geometry {
+			assertContains(t, site.Markdown(page), "References: [Point](geometry.md#type.Point)")
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	site := document(t)
+	dir := filepath.Join(t.TempDir(), "docs")
+	utils.AssertEq(t, site.Write(dir), nil)
+
+	for _, name := range []string{"index.html", "index.md", "geometry.html", "geometry.md"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		utils.AssertEq(t, err, nil)
+	}
+}
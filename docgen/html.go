@@ -0,0 +1,126 @@
+package docgen
+
+import (
+	"html"
+	"strings"
+)
+
+const style = `body { font-family: sans-serif; max-width: 50rem; margin: auto; padding: 1rem; }
+pre { background: #f4f4f4; padding: 0.5rem; overflow-x: auto; }
+pre a { color: inherit; }
+.method { margin-left: 2rem; }
+.note { border-left: 3px solid #d08000; padding-left: 0.5rem; }`
+
+// Renders the HTML documentation of a module
+func (s *Site) HTML(page *Page) string {
+	var result strings.Builder
+	htmlHeader(&result, "Module "+page.Module)
+	result.WriteString(`<p><a href="index.html">Index</a></p>` + "\n")
+	result.WriteString("<h1>Module " + html.EscapeString(page.Module) + "</h1>\n")
+
+	if len(page.Types) != 0 {
+		result.WriteString("<h2>Types</h2>\n")
+		for _, item := range page.Types {
+			s.htmlItem(&result, page, item, "h3", "")
+			for _, method := range item.Methods {
+				s.htmlItem(&result, page, method, "h4", "method")
+			}
+		}
+	}
+	if len(page.Functions) != 0 {
+		result.WriteString("<h2>Functions</h2>\n")
+		for _, item := range page.Functions {
+			s.htmlItem(&result, page, item, "h3", "")
+		}
+	}
+
+	result.WriteString("</body>\n</html>\n")
+	return result.String()
+}
+
+// Renders an HTML page linking to the documentation of each module
+func (s *Site) HTMLIndex() string {
+	var result strings.Builder
+	htmlHeader(&result, "Modules")
+	result.WriteString("<h1>Modules</h1>\n<ul>\n")
+	for _, page := range s.Pages {
+		result.WriteString(`<li><a href="` + html.EscapeString(page.FileName) + `.html">`)
+		result.WriteString(html.EscapeString(page.Module) + "</a></li>\n")
+	}
+	result.WriteString("</ul>\n</body>\n</html>\n")
+	return result.String()
+}
+
+func htmlHeader(result *strings.Builder, title string) {
+	result.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	result.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	result.WriteString("<style>\n" + style + "\n</style>\n</head>\n<body>\n")
+}
+
+func (s *Site) htmlItem(result *strings.Builder, page *Page, item *Item, heading, class string) {
+	result.WriteString("<section")
+	if class != "" {
+		result.WriteString(` class="` + class + `"`)
+	}
+	result.WriteString(` id="` + html.EscapeString(item.Anchor) + `">` + "\n")
+	result.WriteString("<" + heading + ">" + item.Kind + " " + html.EscapeString(item.Name) + "</" + heading + ">\n")
+
+	result.WriteString("<pre><code>")
+	s.linkTypes(page, item, item.Signature, func(text string) {
+		result.WriteString(html.EscapeString(text))
+	}, func(name string, target *Page, anchor string) {
+		result.WriteString(`<a href="` + html.EscapeString(href(page, target, ".html", anchor)) + `">`)
+		result.WriteString(html.EscapeString(name) + "</a>")
+	})
+	result.WriteString("</code></pre>\n")
+
+	docs := item.Documentation
+	if docs.Deprecated != nil {
+		htmlNote(result, "Deprecated", *docs.Deprecated)
+	}
+	if docs.Todo != nil {
+		htmlNote(result, "Todo", *docs.Todo)
+	}
+	for _, paragraph := range paragraphs(docs.Text) {
+		result.WriteString("<p>" + html.EscapeString(paragraph) + "</p>\n")
+	}
+
+	result.WriteString("</section>\n")
+}
+
+func htmlNote(result *strings.Builder, title, message string) {
+	result.WriteString(`<p class="note"><strong>` + title + "</strong>")
+	if message != "" {
+		result.WriteString(": " + html.EscapeString(message))
+	}
+	result.WriteString("</p>\n")
+}
+
+// A link from one page to an anchor on another
+func href(from, to *Page, extension, anchor string) string {
+	if from == to {
+		return "#" + anchor
+	}
+	return to.FileName + extension + "#" + anchor
+}
+
+// Splits documentation text into paragraphs, separated by blank lines
+func paragraphs(text string) []string {
+	result := []string{}
+	current := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(current) != 0 {
+				result = append(result, strings.Join(current, " "))
+				current = []string{}
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) != 0 {
+		result = append(result, strings.Join(current, " "))
+	}
+	return result
+}
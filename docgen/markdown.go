@@ -0,0 +1,75 @@
+package docgen
+
+import "strings"
+
+// Renders the Markdown documentation of a module. Code blocks
+// can't contain links, so the documented types used in each
+// signature are linked to below it.
+func (s *Site) Markdown(page *Page) string {
+	var result strings.Builder
+	result.WriteString("[Index](index.md)\n\n")
+	result.WriteString("# Module " + page.Module + "\n")
+
+	if len(page.Types) != 0 {
+		result.WriteString("\n## Types\n")
+		for _, item := range page.Types {
+			s.markdownItem(&result, page, item, "###")
+			for _, method := range item.Methods {
+				s.markdownItem(&result, page, method, "####")
+			}
+		}
+	}
+	if len(page.Functions) != 0 {
+		result.WriteString("\n## Functions\n")
+		for _, item := range page.Functions {
+			s.markdownItem(&result, page, item, "###")
+		}
+	}
+
+	return result.String()
+}
+
+// Renders a Markdown page linking to the documentation of each module
+func (s *Site) MarkdownIndex() string {
+	var result strings.Builder
+	result.WriteString("# Modules\n\n")
+	for _, page := range s.Pages {
+		result.WriteString("- [" + page.Module + "](" + page.FileName + ".md)\n")
+	}
+	return result.String()
+}
+
+func (s *Site) markdownItem(result *strings.Builder, page *Page, item *Item, heading string) {
+	result.WriteString("\n<a id=\"" + item.Anchor + "\"></a>\n\n")
+	result.WriteString(heading + " " + item.Kind + " `" + item.Name + "`\n\n")
+	result.WriteString("```libra\n" + item.Signature + "\n```\n")
+
+	references := s.references(page, item)
+	if len(references) != 0 {
+		links := []string{}
+		for _, reference := range references {
+			name := reference.anchor[strings.IndexByte(reference.anchor, '.')+1:]
+			links = append(links, "["+name+"]("+href(page, reference.page, ".md", reference.anchor)+")")
+		}
+		result.WriteString("\nReferences: " + strings.Join(links, ", ") + "\n")
+	}
+
+	docs := item.Documentation
+	if docs.Deprecated != nil {
+		markdownNote(result, "Deprecated", *docs.Deprecated)
+	}
+	if docs.Todo != nil {
+		markdownNote(result, "Todo", *docs.Todo)
+	}
+	for _, paragraph := range paragraphs(docs.Text) {
+		result.WriteString("\n" + paragraph + "\n")
+	}
+}
+
+func markdownNote(result *strings.Builder, title, message string) {
+	result.WriteString("\n> **" + title + "**")
+	if message != "" {
+		result.WriteString(": " + message)
+	}
+	result.WriteByte('\n')
+}
@@ -0,0 +1,244 @@
+package formatter
+
+import (
+	"fmt"
+
+	"github.com/gearsdatapacks/libra/parser/ast"
+)
+
+func (f *formatter) expression(expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		f.write(e.Token.Value)
+	case *ast.FloatLiteral:
+		f.write(e.Token.Value)
+	case *ast.BooleanLiteral:
+		f.write(fmt.Sprint(e.Value))
+	case *ast.StringLiteral:
+		f.write(e.Token.Value)
+	case *ast.Identifier:
+		f.write(e.Name)
+
+	case *ast.BinaryExpression:
+		f.expression(e.Left)
+		f.write(" " + e.Operator.Value + " ")
+		f.expression(e.Right)
+	case *ast.AssignmentExpression:
+		f.expression(e.Assignee)
+		f.write(" " + e.Operator.Value + " ")
+		f.expression(e.Value)
+	case *ast.ParenthesisedExpression:
+		f.write("(")
+		f.expression(e.Expression)
+		f.write(")")
+	case *ast.PrefixExpression:
+		f.write(f.text(e.Location))
+		f.expression(e.Operand)
+	case *ast.PostfixExpression:
+		f.expression(e.Operand)
+		f.write(f.text(e.OperatorLocation))
+	case *ast.DerefExpression:
+		f.expression(e.Operand)
+		f.write(".*")
+	case *ast.PointerType:
+		f.write("*")
+		f.mutable(e.Mutable)
+		f.expression(e.Operand)
+	case *ast.RefExpression:
+		f.write("&")
+		f.mutable(e.Mutable)
+		f.expression(e.Operand)
+	case *ast.OptionType:
+		f.write("?")
+		f.expression(e.Operand)
+	case *ast.BuilderType:
+		f.write("~")
+		f.expression(e.Operand)
+	case *ast.CastExpression:
+		f.expression(e.Left)
+		f.write(" -> ")
+		f.expression(e.Type)
+	case *ast.TypeCheckExpression:
+		f.expression(e.Left)
+		f.write(" is ")
+		f.expression(e.Type)
+	case *ast.RangeExpression:
+		f.expression(e.Start)
+		f.write("..")
+		f.expression(e.End)
+
+	case *ast.ListLiteral:
+		f.list(e.Location.Span.Start, len(e.Values), func(i int) {
+			f.expression(e.Values[i])
+		})
+	case *ast.TupleExpression:
+		f.list(e.Location.Span.Start, len(e.Values), func(i int) {
+			f.expression(e.Values[i])
+		})
+	case *ast.MapLiteral:
+		f.list(e.Location.Span.Start, len(e.KeyValues), func(i int) {
+			f.expression(e.KeyValues[i].Key)
+			f.write(": ")
+			f.expression(e.KeyValues[i].Value)
+		})
+	case *ast.FunctionCall:
+		f.expression(e.Callee)
+		f.list(f.endOf(e.Callee), len(e.Arguments), func(i int) {
+			f.expression(e.Arguments[i])
+		})
+	case *ast.IndexExpression:
+		f.expression(e.Left)
+		f.write("[")
+		if e.Index != nil {
+			f.expression(e.Index)
+		}
+		f.write("]")
+	case *ast.MemberExpression:
+		if _, inferred := e.Left.(*ast.InferredExpression); !inferred {
+			f.expression(e.Left)
+		}
+		f.write("." + e.Member)
+	case *ast.InferredExpression:
+		f.write(".")
+	case *ast.StructExpression:
+		f.expression(e.Struct)
+		if _, inferred := e.Struct.(*ast.InferredExpression); !inferred {
+			f.write(" ")
+		}
+		f.list(f.endOf(e.Struct), len(e.Members), func(i int) {
+			member := e.Members[i]
+			if member.Name == nil {
+				f.expression(member.Value)
+				return
+			}
+			f.write(*member.Name)
+			if member.Value != nil {
+				f.write(": ")
+				f.expression(member.Value)
+			}
+		})
+
+	case *ast.FunctionExpression:
+		f.write("fn")
+		f.parameters(e.Location.Span.End, e.Parameters)
+		f.typeAnnotation(e.ReturnType)
+		if e.Body != nil {
+			f.write(" ")
+			f.block(e.Body)
+		}
+	case *ast.Block:
+		f.block(e)
+	case *ast.IfExpression:
+		f.write("if ")
+		f.expression(e.Condition)
+		f.write(" ")
+		f.block(e.Body)
+		if e.ElseBranch != nil {
+			f.write(" else ")
+			f.expression(e.ElseBranch)
+		}
+	case *ast.WhileLoop:
+		f.write("while ")
+		f.expression(e.Condition)
+		f.write(" ")
+		f.block(e.Body)
+	case *ast.ForLoop:
+		f.write("for " + e.Variable + " in ")
+		f.expression(e.Iterator)
+		f.write(" ")
+		f.block(e.Body)
+
+	default:
+		panic(fmt.Sprintf("TODO: Format %T", expr))
+	}
+}
+
+func (f *formatter) mutable(mutable bool) {
+	if mutable {
+		f.write("mut ")
+	}
+}
+
+// The offset of the end of an expression in the source code
+func (f *formatter) endOfExpression(expr ast.Expression) int {
+	switch e := expr.(type) {
+	case *ast.IntegerLiteral:
+		return e.Token.Location.Span.End
+	case *ast.FloatLiteral:
+		return e.Token.Location.Span.End
+	case *ast.StringLiteral:
+		return e.Token.Location.Span.End
+	case *ast.BooleanLiteral:
+		return e.Location.Span.End
+	case *ast.Identifier:
+		return e.Location.Span.End
+	case *ast.InferredExpression:
+		return e.Location.Span.End
+
+	case *ast.BinaryExpression:
+		return f.endOf(e.Right)
+	case *ast.AssignmentExpression:
+		return f.endOf(e.Value)
+	case *ast.PrefixExpression:
+		return f.endOf(e.Operand)
+	case *ast.PostfixExpression:
+		return e.OperatorLocation.Span.End
+	case *ast.DerefExpression:
+		// The `.*` following the operand
+		return f.tokens[f.tokenAt(f.endOf(e.Operand))].Location.Span.End
+	case *ast.PointerType:
+		return f.endOf(e.Operand)
+	case *ast.RefExpression:
+		return f.endOf(e.Operand)
+	case *ast.OptionType:
+		return f.endOf(e.Operand)
+	case *ast.BuilderType:
+		return f.endOf(e.Operand)
+	case *ast.CastExpression:
+		return f.endOf(e.Type)
+	case *ast.TypeCheckExpression:
+		return f.endOf(e.Type)
+	case *ast.RangeExpression:
+		return f.endOf(e.End)
+	case *ast.MemberExpression:
+		return e.MemberLocation.Span.End
+
+	case *ast.ParenthesisedExpression:
+		return f.closeAfter(e.Location.Span.Start)
+	case *ast.ListLiteral:
+		return f.closeAfter(e.Location.Span.Start)
+	case *ast.TupleExpression:
+		return f.closeAfter(e.Location.Span.Start)
+	case *ast.MapLiteral:
+		return f.closeAfter(e.Location.Span.Start)
+	case *ast.IndexExpression:
+		return f.closeAfter(e.Location.Span.Start)
+	case *ast.Block:
+		return f.closeAfter(e.Location.Span.Start)
+	case *ast.FunctionCall:
+		return f.closeAfter(f.endOf(e.Callee))
+	case *ast.StructExpression:
+		return f.closeAfter(f.endOf(e.Struct))
+
+	case *ast.FunctionExpression:
+		if e.Body != nil {
+			return f.endOf(e.Body)
+		}
+		if e.ReturnType != nil {
+			return f.endOf(e.ReturnType)
+		}
+		return f.closeAfter(e.Location.Span.End)
+	case *ast.IfExpression:
+		if e.ElseBranch != nil {
+			return f.endOf(e.ElseBranch)
+		}
+		return f.endOf(e.Body)
+	case *ast.WhileLoop:
+		return f.endOf(e.Body)
+	case *ast.ForLoop:
+		return f.endOf(e.Body)
+
+	default:
+		panic(fmt.Sprintf("TODO: Format %T", expr))
+	}
+}
@@ -0,0 +1,383 @@
+package formatter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/lexer"
+	"github.com/gearsdatapacks/libra/lexer/token"
+	"github.com/gearsdatapacks/libra/parser"
+	"github.com/gearsdatapacks/libra/parser/ast"
+	"github.com/gearsdatapacks/libra/text"
+)
+
+const indentation = "  "
+
+// Formats a source file, returning its canonical form. Files which
+// contain syntax errors are not formatted, and their diagnostics are
+// returned instead.
+//
+// Statements are placed one per line, and at most one blank line is kept
+// between them. Comma-separated lists and blocks are kept on one line if
+// they were written on one line, and otherwise each line of a list is
+// indented and ends in a comma, including the last. Comments are kept
+// next to the statement or list element they were written next to.
+func Format(file *text.SourceFile) (string, diagnostics.Manager) {
+	lex := lexer.New(file)
+	tokens := lex.Tokenise()
+	if len(lex.Diagnostics) != 0 {
+		return "", lex.Diagnostics
+	}
+
+	p := parser.New(tokens, lex.Diagnostics)
+	program := p.Parse()
+	if len(p.Diagnostics) != 0 {
+		return "", p.Diagnostics
+	}
+
+	f := newFormatter(file.Text, tokens, program.Comments)
+	f.statements(program.Statements, 0, len(file.Text))
+	return f.output.String(), nil
+}
+
+type formatter struct {
+	source string
+	// Tokens which affect the layout of the code, excluding newlines,
+	// semicolons and comments
+	tokens []token.Token
+	// The index of the token closing each opening bracket
+	closers     map[int]int
+	comments    []ast.Comment
+	nextComment int
+
+	output      strings.Builder
+	indent      int
+	atLineStart bool
+	// The end of the last thing printed, used to preserve blank lines.
+	// This is -1 at the start of a list, where blank lines are removed.
+	last int
+}
+
+func newFormatter(source string, tokens []token.Token, comments []ast.Comment) *formatter {
+	f := &formatter{
+		source:      source,
+		tokens:      []token.Token{},
+		closers:     map[int]int{},
+		comments:    comments,
+		atLineStart: true,
+		last:        -1,
+	}
+
+	openers := []int{}
+	for _, tok := range tokens {
+		switch tok.Kind {
+		case token.NEWLINE, token.SEMICOLON, token.COMMENT, token.EOF:
+			continue
+		case token.LEFT_PAREN, token.LEFT_BRACE, token.LEFT_SQUARE:
+			openers = append(openers, len(f.tokens))
+		case token.RIGHT_PAREN, token.RIGHT_BRACE, token.RIGHT_SQUARE:
+			if len(openers) > 0 {
+				f.closers[openers[len(openers)-1]] = len(f.tokens)
+				openers = openers[:len(openers)-1]
+			}
+		}
+		f.tokens = append(f.tokens, tok)
+	}
+
+	return f
+}
+
+func (f *formatter) write(text string) {
+	if f.atLineStart {
+		f.output.WriteString(strings.Repeat(indentation, f.indent))
+		f.atLineStart = false
+	}
+	f.output.WriteString(text)
+}
+
+func (f *formatter) newline() {
+	f.output.WriteByte('\n')
+	f.atLineStart = true
+}
+
+// Keeps a blank line before something starting at `offset`,
+// if there was one in the source code
+func (f *formatter) blankLine(offset int) {
+	if f.last < 0 || offset <= f.last {
+		return
+	}
+	lines := strings.Split(f.source[f.last:offset], "\n")
+	for i := 1; i < len(lines)-1; i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			f.newline()
+			return
+		}
+	}
+}
+
+func (f *formatter) text(location text.Location) string {
+	return f.source[location.Span.Start:location.Span.End]
+}
+
+func (f *formatter) sameLine(start, end int) bool {
+	return !strings.Contains(f.source[start:end], "\n")
+}
+
+// The index of the first token starting at or after `offset`
+func (f *formatter) tokenAt(offset int) int {
+	return sort.Search(len(f.tokens), func(i int) bool {
+		return f.tokens[i].Location.Span.Start >= offset
+	})
+}
+
+// The start of the first token at or after `offset`
+func (f *formatter) startAfter(offset int) int {
+	index := f.tokenAt(offset)
+	if index == len(f.tokens) {
+		return len(f.source)
+	}
+	return f.tokens[index].Location.Span.Start
+}
+
+// The index of the token closing the bracket at `opener`
+func (f *formatter) closer(opener int) int {
+	if closer, ok := f.closers[opener]; ok {
+		return closer
+	}
+	return len(f.tokens) - 1
+}
+
+// The end of the bracketed group opened by the first token at or after `offset`
+func (f *formatter) closeAfter(offset int) int {
+	return f.tokens[f.closer(f.tokenAt(offset))].Location.Span.End
+}
+
+func (f *formatter) pendingComment(before int) bool {
+	return f.nextComment < len(f.comments) &&
+		f.comments[f.nextComment].Location.Span.Start < before
+}
+
+// Prints the comments before `offset` on their own lines
+func (f *formatter) leadingComments(offset int) {
+	for f.pendingComment(offset) {
+		comment := f.comments[f.nextComment]
+		f.nextComment++
+
+		f.blankLine(comment.Location.Span.Start)
+		f.write(strings.TrimRight(comment.Text, " \t\r"))
+		f.newline()
+		f.last = comment.Location.Span.End
+	}
+}
+
+// Prints the comments inside something ending at `end`, which
+// can't be printed anywhere else, and those following it on the
+// same line, at the end of the current line. Returns whether
+// any comments were printed.
+func (f *formatter) trailingComments(end, limit int) bool {
+	printed := false
+	lineComment := false
+
+	for f.nextComment < len(f.comments) {
+		comment := f.comments[f.nextComment]
+		start := comment.Location.Span.Start
+		if start >= end && (start >= limit || !f.sameLine(end, start)) {
+			break
+		}
+		f.nextComment++
+
+		if lineComment {
+			f.newline()
+		} else {
+			f.write(" ")
+		}
+		f.write(strings.TrimRight(comment.Text, " \t\r"))
+		lineComment = strings.HasPrefix(comment.Text, "//")
+		printed = true
+
+		if comment.Location.Span.End > f.last {
+			f.last = comment.Location.Span.End
+		}
+	}
+
+	return printed
+}
+
+// Prints statements, one per line. `start` and `end` are the
+// offsets surrounding the statements, which are used to find
+// the comments inside them.
+func (f *formatter) statements(stmts []ast.Statement, start, end int) {
+	f.last = -1
+	previousEnd := start
+
+	for i, stmt := range stmts {
+		stmtStart := f.startAfter(previousEnd)
+		f.leadingComments(stmtStart)
+		// Comments between the attributes and the keyword
+		// of a declaration are moved above the attributes
+		if _, ok := stmt.(ast.AcceptsAttributes); ok {
+			f.leadingComments(stmt.GetLocation().Span.Start)
+		}
+		f.blankLine(stmtStart)
+
+		f.statement(stmt)
+
+		stmtEnd := f.endOf(stmt)
+		f.last = stmtEnd
+		limit := end
+		if i+1 < len(stmts) {
+			limit = f.startAfter(stmtEnd)
+		}
+		f.trailingComments(stmtEnd, limit)
+		f.newline()
+		previousEnd = stmtEnd
+	}
+
+	f.leadingComments(end)
+}
+
+func (f *formatter) block(block *ast.Block) {
+	opener := f.tokenAt(block.Location.Span.Start)
+	start := f.tokens[opener].Location.Span.End
+	end := f.tokens[f.closer(opener)].Location.Span.Start
+
+	multiline := len(block.Statements) > 1 ||
+		!f.sameLine(start, end) ||
+		f.pendingComment(end)
+
+	if !multiline {
+		if len(block.Statements) == 0 {
+			f.write("{}")
+			return
+		}
+		f.write("{ ")
+		f.statement(block.Statements[0])
+		f.write(" }")
+		return
+	}
+
+	last := f.last
+	f.write("{")
+	f.newline()
+	f.indent++
+	f.statements(block.Statements, start, end)
+	f.indent--
+	f.write("}")
+	f.last = last
+}
+
+type segment struct {
+	start, end int
+}
+
+// Splits the contents of a bracketed list into its elements
+func (f *formatter) segments(opener, closer int) []segment {
+	segments := []segment{}
+	depth := 0
+	first := -1
+
+	for i := opener + 1; i < closer; i++ {
+		tok := f.tokens[i]
+		switch tok.Kind {
+		case token.LEFT_PAREN, token.LEFT_BRACE, token.LEFT_SQUARE:
+			depth++
+		case token.RIGHT_PAREN, token.RIGHT_BRACE, token.RIGHT_SQUARE:
+			depth--
+		case token.COMMA:
+			if depth == 0 {
+				if first != -1 {
+					segments = append(segments, segment{
+						start: f.tokens[first].Location.Span.Start,
+						end:   f.tokens[i-1].Location.Span.End,
+					})
+				}
+				first = -1
+				continue
+			}
+		}
+		if first == -1 {
+			first = i
+		}
+	}
+	if first != -1 {
+		segments = append(segments, segment{
+			start: f.tokens[first].Location.Span.Start,
+			end:   f.tokens[closer-1].Location.Span.End,
+		})
+	}
+
+	return segments
+}
+
+// Prints a comma-separated list of `length` elements, surrounded
+// by the bracket starting at `offset`. Braces have spaces inside
+// them when the list is printed on one line.
+func (f *formatter) list(offset int, length int, element func(i int)) {
+	opener := f.tokenAt(offset)
+	closer := f.closer(opener)
+	open := f.tokens[opener]
+	close := f.tokens[closer]
+	start := open.Location.Span.End
+	end := close.Location.Span.Start
+
+	segments := f.segments(opener, closer)
+	multiline := (!f.sameLine(start, end) || f.pendingComment(end)) &&
+		len(segments) == length
+
+	if !multiline {
+		f.write(open.Value)
+		if length == 0 {
+			f.write(close.Value)
+			return
+		}
+		if open.Kind == token.LEFT_BRACE {
+			f.write(" ")
+		}
+		for i := range length {
+			if i != 0 {
+				f.write(", ")
+			}
+			element(i)
+		}
+		if open.Kind == token.LEFT_BRACE {
+			f.write(" ")
+		}
+		f.write(close.Value)
+		return
+	}
+
+	last := f.last
+	f.write(open.Value)
+	f.newline()
+	f.indent++
+	f.last = -1
+
+	for i, seg := range segments {
+		f.leadingComments(seg.start)
+		f.blankLine(seg.start)
+
+		element(i)
+		f.write(",")
+		f.last = seg.end
+
+		limit := end
+		if i+1 < len(segments) {
+			limit = segments[i+1].start
+		}
+		// Elements which were written on the same line are kept together
+		if !f.trailingComments(seg.end, limit) &&
+			i+1 < len(segments) &&
+			f.sameLine(seg.end, limit) &&
+			!f.pendingComment(limit) {
+			f.write(" ")
+		} else {
+			f.newline()
+		}
+	}
+
+	f.leadingComments(end)
+	f.indent--
+	f.write(close.Value)
+	f.last = last
+}
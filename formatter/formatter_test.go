@@ -0,0 +1,137 @@
+package formatter_test
+
+import (
+	"testing"
+
+	"github.com/gearsdatapacks/libra/formatter"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/text"
+)
+
+func format(t *testing.T, src string) string {
+	t.Helper()
+	formatted, diags := formatter.Format(text.NewFile("test.lb", src))
+	utils.AssertEq(t, len(diags), 0)
+	return formatted
+}
+
+// Checks that the source is formatted as expected,
+// and that formatting it again doesn't change it
+func assertFormat(t *testing.T, src, expected string) {
+	t.Helper()
+	utils.AssertEq(t, format(t, src), expected)
+	utils.AssertEq(t, format(t, expected), expected)
+}
+
+func TestStatements(t *testing.T) {
+	assertFormat(t, "let x=1;mut y : f32=2.5\nconst s = \"hi\"", "let x = 1\nmut y: f32 = 2.5\nconst s = \"hi\"\n")
+	assertFormat(t, "return", "return\n")
+	assertFormat(t, "break  [1,2]", "break [1, 2]\n")
+	assertFormat(t, "explicit pub type Id=u64", "pub explicit type Id = u64\n")
+	assertFormat(t, `import {a,b} from "foo"; import * from "bar"`, "import { a, b } from \"foo\"\nimport * from \"bar\"\n")
+	assertFormat(t, `import "baz"   as qux`, "import \"baz\" as qux\n")
+	assertFormat(t, "fn (mut Point) move(dx:i32){this.x+=dx}", "fn (mut Point) move(dx: i32) { this.x += dx }\n")
+	assertFormat(t, "fn Point.new():Point{return .{x:0,y:0}}", "fn Point.new(): Point { return .{ x: 0, y: 0 } }\n")
+	assertFormat(t, "fn foo(mut a, b: i32 = 1)", "fn foo(mut a, b: i32 = 1)\n")
+	assertFormat(t, "struct Empty\ntag Tag{i32,f32}", "struct Empty\ntag Tag { i32, f32 }\n")
+	assertFormat(t, "union U{a:i32,b{x:i32}}", "union U { a: i32, b { x: i32 } }\n")
+	assertFormat(t, "interface Shape{area():f32,scale(f32):Shape}", "interface Shape { area(): f32, scale(f32): Shape }\n")
+}
+
+func TestExpressions(t *testing.T) {
+	assertFormat(t, "1+2*-x", "1 + 2 * -x\n")
+	assertFormat(t, "(a||b)&&!c", "(a || b) && !c\n")
+	assertFormat(t, "x++;y!;z?", "x++\ny!\nz?\n")
+	assertFormat(t, "let p=&mut x;let v=p.*", "let p = &mut x\nlet v = p.*\n")
+	assertFormat(t, "let t:*mut ?~i32=1", "let t: *mut ?~i32 = 1\n")
+	assertFormat(t, "x->f32 is f32", "x -> f32 is f32\n")
+	assertFormat(t, "a[1..2][]", "a[1..2][]\n")
+	assertFormat(t, "let m={1:true,2:false}\n{}", "let m = { 1: true, 2: false }\n{}\n")
+	assertFormat(t, "foo.bar(1,(2,3)).baz", "foo.bar(1, (2, 3)).baz\n")
+	assertFormat(t, "Point{x:1,y}", "Point { x: 1, y }\n")
+	assertFormat(t, "0xff+1_000", "0xff + 1_000\n")
+	assertFormat(t, "let f=fn(a:i32):i32{a}", "let f = fn(a: i32): i32 { a }\n")
+	assertFormat(t, "for i in 0..10 {x+=i}", "for i in 0..10 { x += i }\n")
+	assertFormat(t,
+		"if a>b {a} else if a<b {b}\nelse {0}",
+		"if a > b { a } else if a < b { b } else { 0 }\n",
+	)
+}
+
+func TestBlocks(t *testing.T) {
+	assertFormat(t, "fn f() {}", "fn f() {}\n")
+	assertFormat(t, "fn f() { a; b }", "fn f() {\n  a\n  b\n}\n")
+	assertFormat(t, "while true {\n    break\n}", "while true {\n  break\n}\n")
+	assertFormat(t,
+		"fn f() {\n\n\n  let a = 1\n\n\n\n  let b = 2\n  return a + b\n\n}\n\n\n\nf()",
+		"fn f() {\n  let a = 1\n\n  let b = 2\n  return a + b\n}\n\nf()\n",
+	)
+	assertFormat(t,
+		"fn f() {\nif x {\nreturn 1\n}\nreturn 2\n}",
+		"fn f() {\n  if x {\n    return 1\n  }\n  return 2\n}\n",
+	)
+}
+
+func TestLists(t *testing.T) {
+	assertFormat(t, "[1, 2, 3,]", "[1, 2, 3]\n")
+	assertFormat(t, "[\n1,\n2\n]", "[\n  1,\n  2,\n]\n")
+	assertFormat(t, "foo(a,\n  b)", "foo(\n  a,\n  b,\n)\n")
+	// Elements which are on the same line are kept together
+	assertFormat(t, "union Int {\n  i8, i16, i32, i64\n}", "union Int {\n  i8, i16, i32, i64,\n}\n")
+	assertFormat(t,
+		"enum Colour: u8 { Red = 1, Green,\n\n  Blue }",
+		"enum Colour: u8 {\n  Red = 1, Green,\n\n  Blue,\n}\n",
+	)
+	assertFormat(t,
+		"struct Big {\n pub x: i32,\n~y: i32\n}",
+		"struct Big {\n  pub x: i32,\n  ~y: i32,\n}\n",
+	)
+	assertFormat(t,
+		"let x = Point {\nx: [\n1,\n], y: 2 }",
+		"let x = Point {\n  x: [\n    1,\n  ], y: 2,\n}\n",
+	)
+}
+
+func TestComments(t *testing.T) {
+	assertFormat(t,
+		"// Leading\nlet x = 1 // Trailing\n/* Block */ let y = 2\n// Final",
+		"// Leading\nlet x = 1 // Trailing\n/* Block */\nlet y = 2\n// Final\n",
+	)
+	assertFormat(t,
+		"fn f() {\n    // Inside\n  a   // After a\n\n  // Before b\n  b\n  // End\n}",
+		"fn f() {\n  // Inside\n  a // After a\n\n  // Before b\n  b\n  // End\n}\n",
+	)
+	// Comments force blocks and lists onto multiple lines
+	assertFormat(t, "fn f() { /* Hi */ }", "fn f() {\n  /* Hi */\n}\n")
+	assertFormat(t, "foo(a /* A */, b)", "foo(\n  a, /* A */\n  b,\n)\n")
+	assertFormat(t,
+		"struct Point {\n  x: i32, // Horizontal\n  // Vertical\n  y: i32\n  // Nothing else\n}",
+		"struct Point {\n  x: i32, // Horizontal\n  // Vertical\n  y: i32,\n  // Nothing else\n}\n",
+	)
+	// Comments inside expressions are moved to the end of the line
+	assertFormat(t, "let x = 1 + // One\n  2", "let x = 1 + 2 // One\n")
+	assertFormat(t, "/* Multi\n   line */\nlet a = 1", "/* Multi\n   line */\nlet a = 1\n")
+}
+
+func TestAttributes(t *testing.T) {
+	assertFormat(t,
+		"@doc Adds numbers\n@deprecated Use plus\n@todo\npub fn add(a, b: i32): i32 { a + b }",
+		"@doc Adds numbers\n@deprecated Use plus\n@todo\npub fn add(a, b: i32): i32 { a + b }\n",
+	)
+	assertFormat(t,
+		"@doc\nLine one\n  Line two\n@end\nstruct S",
+		"@doc\nLine one\n  Line two\n@end\nstruct S\n",
+	)
+	assertFormat(t, "@extern\nfn abs(x: i32): i32", "@extern\nfn abs(x: i32): i32\n")
+	assertFormat(t, "@extern c_abs fn abs(x: i32): i32", "@extern c_abs\nfn abs(x: i32): i32\n")
+	assertFormat(t, "@extern\n@import_module console @import_name log fn log(x: i32)", "@extern\n@import_module console\n@import_name log\nfn log(x: i32)\n")
+	assertFormat(t, "@untagged union U { i32, f32 }", "@untagged\nunion U { i32, f32 }\n")
+	assertFormat(t, "@tag Tag\nstruct S { x: i32 }", "@tag Tag\nstruct S { x: i32 }\n")
+	assertFormat(t, "// Comment\n@doc Hi\n// Moved\nfn f()", "// Comment\n// Moved\n@doc Hi\nfn f()\n")
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	formatted, diags := formatter.Format(text.NewFile("test.lb", "let x = (1"))
+	utils.AssertEq(t, formatted, "")
+	utils.Assert(t, len(diags) > 0, "Expected syntax errors to be reported")
+}
@@ -0,0 +1,348 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/parser/ast"
+	"github.com/gearsdatapacks/libra/text"
+)
+
+func (f *formatter) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.VariableDeclaration:
+		f.attributes(s.Attributes)
+		f.write(s.Keyword.Value + " " + s.Name)
+		f.typeAnnotation(s.Type)
+		f.write(" = ")
+		f.expression(s.Value)
+
+	case *ast.FunctionDeclaration:
+		f.attributes(s.Attributes)
+		if s.Implements != nil {
+			f.attribute("impl", *s.Implements)
+		}
+		if s.Extern != nil {
+			// The name defaults to the name of the function
+			if *s.Extern == s.Name {
+				f.attribute("extern", "")
+			} else {
+				f.attribute("extern", *s.Extern)
+			}
+		}
+		if s.ImportModule != nil {
+			f.attribute("import_module", *s.ImportModule)
+		}
+		if s.ImportName != nil {
+			f.attribute("import_name", *s.ImportName)
+		}
+		f.exported(s.Exported)
+		f.write("fn ")
+		if s.MethodOf != nil {
+			f.write("(")
+			if s.MethodOf.Mutable {
+				f.write("mut ")
+			}
+			f.expression(s.MethodOf.Type)
+			f.write(") ")
+		}
+		if s.MemberOf != nil {
+			f.write(s.MemberOf.Name + ".")
+		}
+		f.write(s.Name)
+		f.parameters(s.NameLocation.Span.End, s.Parameters)
+		f.typeAnnotation(s.ReturnType)
+		if s.Body != nil {
+			f.write(" ")
+			f.block(s.Body)
+		}
+
+	case *ast.ReturnStatement:
+		f.keywordWithValue("return", s.Value)
+	case *ast.YieldStatement:
+		f.keywordWithValue("yield", s.Value)
+	case *ast.BreakStatement:
+		f.keywordWithValue("break", s.Value)
+	case *ast.ContinueStatement:
+		f.write("continue")
+
+	case *ast.TypeDeclaration:
+		f.attributes(s.Attributes)
+		f.tagAttributes(s.Tag, s.Generators)
+		f.exported(s.Exported)
+		if s.Explicit {
+			f.write("explicit ")
+		}
+		f.write("type " + s.Name + " = ")
+		f.expression(s.Type)
+
+	case *ast.StructDeclaration:
+		f.attributes(s.Attributes)
+		f.tagAttributes(s.Tag, s.Generators)
+		f.exported(s.Exported)
+		f.write("struct " + s.Name)
+		if s.Body != nil {
+			f.write(" ")
+			f.structFields(s.NameLocation.Span.End, s.Body)
+		}
+
+	case *ast.InterfaceDeclaration:
+		f.attributes(s.Attributes)
+		f.exported(s.Exported)
+		f.write("interface " + s.Name + " ")
+		f.list(f.bodyStart(s.Location), len(s.Members), func(i int) {
+			member := s.Members[i]
+			f.write(member.Name)
+			// Interface members don't store their location, so their
+			// parameters are found from the start of the member
+			name := f.tokenAt(f.memberStart(s.Location, i))
+			f.list(f.tokens[name+1].Location.Span.Start, len(member.Parameters), func(i int) {
+				f.expression(member.Parameters[i])
+			})
+			f.typeAnnotation(member.ReturnType)
+		})
+
+	case *ast.ImportStatement:
+		f.write("import ")
+		if s.Symbols != nil {
+			f.list(s.Location.Span.End, len(s.Symbols), func(i int) {
+				f.write(s.Symbols[i].Name)
+			})
+			f.write(" from ")
+		}
+		if s.All {
+			f.write("* from ")
+		}
+		f.write(s.Module.Value)
+		if s.Alias != nil {
+			f.write(" as " + *s.Alias)
+		}
+
+	case *ast.EnumDeclaration:
+		f.attributes(s.Attributes)
+		f.tagAttributes(s.Tag, nil)
+		f.exported(s.Exported)
+		f.write("enum " + s.Name)
+		f.typeAnnotation(s.ValueType)
+		f.write(" ")
+		bodyStart := f.bodyStart(s.Location)
+		if s.ValueType != nil {
+			bodyStart = f.endOf(s.ValueType)
+		}
+		f.list(bodyStart, len(s.Members), func(i int) {
+			member := s.Members[i]
+			f.write(member.Name)
+			if member.Value != nil {
+				f.write(" = ")
+				f.expression(member.Value)
+			}
+		})
+
+	case *ast.UnionDeclaration:
+		f.attributes(s.Attributes)
+		f.tagAttributes(s.Tag, nil)
+		if s.Untagged {
+			f.attribute("untagged", "")
+		}
+		f.exported(s.Exported)
+		f.write("union " + s.Name + " ")
+		f.list(f.bodyStart(s.Location), len(s.Members), func(i int) {
+			member := s.Members[i]
+			f.write(member.Name)
+			f.typeAnnotation(member.Type)
+			if member.Compound != nil {
+				f.write(" ")
+				f.structFields(member.NameLocation.Span.End, member.Compound)
+			}
+		})
+
+	case *ast.TagDeclaration:
+		f.attributes(s.Attributes)
+		f.exported(s.Exported)
+		f.write("tag " + s.Name)
+		if s.Body != nil {
+			f.write(" ")
+			f.list(f.bodyStart(s.Location), len(s.Body), func(i int) {
+				f.expression(s.Body[i])
+			})
+		}
+
+	case ast.Expression:
+		f.expression(s)
+
+	default:
+		panic(fmt.Sprintf("TODO: Format %T", stmt))
+	}
+}
+
+func (f *formatter) exported(exported bool) {
+	if exported {
+		f.write("pub ")
+	}
+}
+
+func (f *formatter) keywordWithValue(keyword string, value ast.Expression) {
+	f.write(keyword)
+	if value != nil {
+		f.write(" ")
+		f.expression(value)
+	}
+}
+
+func (f *formatter) typeAnnotation(ty ast.Expression) {
+	if ty != nil {
+		f.write(": ")
+		f.expression(ty)
+	}
+}
+
+func (f *formatter) typeOrIdent(t ast.TypeOrIdent) {
+	if t.Name == nil {
+		f.expression(t.Type)
+		return
+	}
+	f.write(*t.Name)
+	f.typeAnnotation(t.Type)
+}
+
+func (f *formatter) parameters(offset int, params []ast.Parameter) {
+	f.list(offset, len(params), func(i int) {
+		param := params[i]
+		if param.Mutable {
+			f.write("mut ")
+		}
+		f.typeOrIdent(param.TypeOrIdent)
+		if param.Default != nil {
+			f.write(" = ")
+			f.expression(param.Default)
+		}
+	})
+}
+
+func (f *formatter) structFields(offset int, fields []ast.StructField) {
+	f.list(offset, len(fields), func(i int) {
+		field := fields[i]
+		if field.Pub {
+			f.write("pub ")
+		}
+		if field.Builder {
+			f.write("~")
+		}
+		f.typeOrIdent(field.TypeOrIdent)
+	})
+}
+
+// The start of the body of a declaration, which follows its name
+func (f *formatter) bodyStart(keyword text.Location) int {
+	return f.tokens[f.tokenAt(keyword.Span.Start)+1].Location.Span.End
+}
+
+// The start of the `i`th member of an interface
+func (f *formatter) memberStart(keyword text.Location, i int) int {
+	opener := f.tokenAt(f.bodyStart(keyword))
+	segments := f.segments(opener, f.closer(opener))
+	return segments[i].start
+}
+
+func (f *formatter) attribute(name, value string) {
+	f.write("@" + name)
+	if value != "" {
+		f.write(" " + value)
+	}
+	f.newline()
+}
+
+func (f *formatter) attributes(attributes ast.DeclarationAttributes) {
+	if attributes.Documentation != "" {
+		if strings.Contains(attributes.Documentation, "\n") {
+			// Multiline documentation is written verbatim, so it isn't indented
+			f.write("@doc")
+			f.output.WriteString("\n" + attributes.Documentation + "\n@end\n")
+			f.atLineStart = true
+		} else {
+			f.attribute("doc", attributes.Documentation)
+		}
+	}
+	if attributes.DeprecatedMessage != nil {
+		f.attribute("deprecated", *attributes.DeprecatedMessage)
+	}
+	if attributes.TodoMessage != nil {
+		f.attribute("todo", *attributes.TodoMessage)
+	}
+}
+
+func (f *formatter) tagAttributes(tag ast.Expression, generators []ast.Expression) {
+	if tag != nil {
+		f.write("@tag ")
+		f.expression(tag)
+		f.newline()
+	}
+	for _, generator := range generators {
+		f.write("@gen ")
+		f.expression(generator)
+		f.newline()
+	}
+}
+
+// The offset of the end of a statement in the source code
+func (f *formatter) endOf(stmt ast.Statement) int {
+	switch s := stmt.(type) {
+	case *ast.VariableDeclaration:
+		return f.endOf(s.Value)
+	case *ast.FunctionDeclaration:
+		if s.Body != nil {
+			return f.endOf(s.Body)
+		}
+		if s.ReturnType != nil {
+			return f.endOf(s.ReturnType)
+		}
+		return f.closeAfter(s.NameLocation.Span.End)
+	case *ast.ReturnStatement:
+		return f.endOfKeyword(s.Location, s.Value)
+	case *ast.YieldStatement:
+		return f.endOf(s.Value)
+	case *ast.BreakStatement:
+		return f.endOfKeyword(s.Location, s.Value)
+	case *ast.ContinueStatement:
+		return s.Location.Span.End
+	case *ast.TypeDeclaration:
+		return f.endOf(s.Type)
+	case *ast.StructDeclaration:
+		if s.Body != nil {
+			return f.closeAfter(s.NameLocation.Span.End)
+		}
+		return s.NameLocation.Span.End
+	case *ast.InterfaceDeclaration:
+		return f.closeAfter(f.bodyStart(s.Location))
+	case *ast.ImportStatement:
+		index := f.tokenAt(s.Module.Location.Span.Start)
+		if s.Alias != nil {
+			// Skip over `as` and the alias
+			index += 2
+		}
+		return f.tokens[index].Location.Span.End
+	case *ast.EnumDeclaration:
+		if s.ValueType != nil {
+			return f.closeAfter(f.endOf(s.ValueType))
+		}
+		return f.closeAfter(f.bodyStart(s.Location))
+	case *ast.UnionDeclaration:
+		return f.closeAfter(f.bodyStart(s.Location))
+	case *ast.TagDeclaration:
+		if s.Body != nil {
+			return f.closeAfter(f.bodyStart(s.Location))
+		}
+		return f.bodyStart(s.Location)
+	case ast.Expression:
+		return f.endOfExpression(s)
+	default:
+		panic(fmt.Sprintf("TODO: Format %T", stmt))
+	}
+}
+
+func (f *formatter) endOfKeyword(keyword text.Location, value ast.Expression) int {
+	if value != nil {
+		return f.endOf(value)
+	}
+	return keyword.Span.End
+}
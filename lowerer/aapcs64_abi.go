@@ -0,0 +1,101 @@
+package lowerer
+
+import (
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// The AArch64 procedure call standard, described in
+// https://github.com/ARM-software/abi-aa/blob/main/aapcs64/aapcs64.rst
+type aapcs64 struct {
+	target types.TargetInfo
+}
+
+func (a aapcs64) passInfo(ty types.Type, isReturn bool) (ir.PassInfo, types.Type) {
+	// Scalars are already passed correctly by LLVM
+	if !isAggregate(ty) {
+		return directly(ty), nil
+	}
+
+	// Homogeneous floating-point aggregates are passed in consecutive
+	// floating-point registers, which LLVM does for arrays of floats
+	if base, count := homogeneousAggregate(ty); count >= 1 && count <= 4 &&
+		types.ByteSize(ty, a.target) == count*types.ByteSize(base, a.target) {
+		return coerced(ty, &types.ArrayType{ElemType: base, Length: count}), nil
+	}
+
+	// Large composites are copied to memory by the caller,
+	// and a pointer to the copy is passed instead
+	size := types.ByteSize(ty, a.target)
+	if size > 16 {
+		return indirectly(ty, false), nil
+	}
+
+	// Everything else is passed in one or two general-purpose registers
+	if types.Alignment(ty, a.target) == 16 {
+		return coerced(ty, types.Int(128)), nil
+	}
+	if size <= 8 {
+		// Small return values only use the bits they need
+		if isReturn {
+			return coerced(ty, types.Int(size*bytes)), nil
+		}
+		return coerced(ty, types.Int(64)), nil
+	}
+	return coerced(ty, &types.ArrayType{ElemType: types.Int(64), Length: 2}), nil
+}
+
+// Finds the type and number of the members of a composite, if all of its
+// members have the same floating-point type. If they don't, the count is 0.
+func homogeneousAggregate(ty types.Type) (types.Type, int) {
+	if types.IsFloat(ty) {
+		return types.Unwrap(ty), 1
+	}
+
+	var members []types.Type
+	switch ty := types.Unwrap(ty).(type) {
+	case *types.Struct:
+		for _, name := range ty.FieldOrder {
+			members = append(members, ty.Fields[name].Type)
+		}
+	case *types.TupleStruct:
+		members = ty.Types
+	case *types.TupleType:
+		members = ty.Types
+	case *types.ArrayType:
+		base, count := homogeneousAggregate(ty.ElemType)
+		return base, count * ty.Length
+
+	case *types.Union:
+		if !ty.Untagged {
+			return nil, 0
+		}
+		// Members of an untagged union overlap, so the largest one is used
+		var base types.Type
+		count := 0
+		for _, member := range ty.Members {
+			memberBase, memberCount := homogeneousAggregate(member)
+			if memberCount == 0 || (base != nil && memberBase != base) {
+				return nil, 0
+			}
+			base = memberBase
+			count = max(count, memberCount)
+		}
+		return base, count
+
+	default:
+		return nil, 0
+	}
+
+	var base types.Type
+	count := 0
+	for _, member := range members {
+		memberBase, memberCount := homogeneousAggregate(member)
+		if memberCount == 0 || (base != nil && memberBase != base) {
+			return nil, 0
+		}
+		base = memberBase
+		count += memberCount
+	}
+	return base, count
+}
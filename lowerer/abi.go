@@ -2,62 +2,67 @@ package lowerer
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/text"
 	"github.com/gearsdatapacks/libra/type_checker/ir"
 	"github.com/gearsdatapacks/libra/type_checker/types"
 )
 
-type abiClass int
-
-const (
-	noClass abiClass = iota
-	integer
-	sse
-	sseUp
-	// x87
-	// x87Up
-	// complexX87
-	memory
-)
+// The calling convention of a target, which decides how values are passed
+// between functions so that they are compatible with C
+type callingConvention interface {
+	// How a value is passed as a parameter, or returned from a function.
+	// If part of the value's type isn't supported yet, that type is returned.
+	passInfo(ty types.Type, isReturn bool) (ir.PassInfo, types.Type)
+}
+
+func callingConventionFor(target types.TargetInfo) callingConvention {
+	arch, _, _ := strings.Cut(target.Triple, "-")
+
+	switch arch {
+	case "x86_64", "amd64":
+		// Windows uses its own calling convention on x86-64
+		if strings.Contains(target.Triple, "windows") {
+			return nil
+		}
+		return sysV{target: target}
+	case "aarch64", "arm64":
+		return aapcs64{target: target}
+	default:
+		return nil
+	}
+}
+
+func (l *lowerer) fixAbi(pkg *ir.LoweredPackage) {
+	convention := callingConventionFor(l.target)
 
-func fixAbi(pkg *ir.LoweredPackage) {
 	for _, mod := range pkg.Modules {
 		for _, fn := range mod.Functions {
-			for i, param := range fn.Type.Parameters {
-				var low, high abiClass
-				classify(param, 0, &low, &high)
-
-				if types.IsStruct(param) {
-					if low == integer && high == noClass {
-						newType := types.Int(types.BitSize(param))
-						fn.Type.Parameters[i] = newType
-					} else if low == sse && high == noClass {
-						newType := types.Float(types.BitSize(param))
-						fn.Type.Parameters[i] = newType
-					}
-				}
+			if convention == nil {
+				l.checkAbiSupported(fn)
 			}
+			fn.Abi = l.functionAbi(convention, fn.Type.Parameters, fn.Type.ReturnType, fn.Location)
+
+			for i, param := range fn.Abi.Parameters {
+				fn.Type.Parameters[i] = param.AbiType
+			}
+			fn.Type.ReturnType = fn.Abi.Return.AbiType
 		}
 
 		for _, call := range mod.FunctionCalls {
+			argTypes := make([]types.Type, 0, len(call.Arguments))
+			for _, arg := range call.Arguments {
+				argTypes = append(argTypes, arg.Type())
+			}
+			call.Abi = l.functionAbi(convention, argTypes, call.ReturnType, call.Location)
+
 			for i, arg := range call.Arguments {
-				argType := arg.Type()
-				var low, high abiClass
-				classify(argType, 0, &low, &high)
-
-				if types.IsStruct(argType) {
-					if low == integer && high == noClass {
-						newType := types.Int(types.BitSize(argType))
-						call.Arguments[i] = &ir.BitCast{
-							Value: arg,
-							To:    newType,
-						}
-					} else if low == sse && high == noClass {
-						newType := types.Float(types.BitSize(argType))
-						call.Arguments[i] = &ir.BitCast{
-							Value: arg,
-							To:    newType,
-						}
+				if param := call.Abi.Parameters[i]; param.Kind == ir.PassCoerced {
+					call.Arguments[i] = &ir.BitCast{
+						Value: arg,
+						To:    param.AbiType,
 					}
 				}
 			}
@@ -65,129 +70,99 @@ func fixAbi(pkg *ir.LoweredPackage) {
 	}
 }
 
-const bits = 1
-const bytes = 8 * bits
-const eightBytes = 8 * bytes
-
-func classify(
-	ty types.Type,
-	offset int,
-	low, high *abiClass,
-) {
-	bitWidth := types.BitSize(ty)
-
-	current := low
-	if offset > 1*eightBytes {
-		current = high
-	}
-
-	*low = noClass
-	*high = noClass
-	*current = memory
-
-	if ty == types.Void {
-		*current = noClass
-		return
-	}
-
-	if types.IsBool(ty) {
-		*current = integer
-		return
-	}
-
-	// TODO: Not CStrings
-	if types.IsString(ty) {
-		*current = integer
+// Without a known calling convention, values are passed however LLVM decides
+// to. This is fine between our own functions, but aggregates passed to C
+// would silently be passed incorrectly.
+func (l *lowerer) checkAbiSupported(fn *ir.FunctionDeclaration) {
+	if fn.Extern == nil {
 		return
 	}
 
-	if types.IsInt(ty) {
-		if bitWidth <= 64 {
-			*current = integer
-		} else if bitWidth <= 128 {
-			*low = integer
-			*high = integer
-		} else {
-			panic("TODO: >128 bit-width ints")
+	for _, ty := range append([]types.Type{fn.Type.ReturnType}, fn.Type.Parameters...) {
+		if isAggregate(ty) {
+			l.diagnostics.Report(diagnostics.UnsupportedAbi(fn.Location, ty, l.target.Triple))
+			return
 		}
-
-		return
 	}
+}
 
-	if types.IsFloat(ty) {
-		if bitWidth <= 64 {
-			*current = sse
-		} else if bitWidth == 128 {
-			*low = sse
-			*high = sseUp
-		} else {
-			panic("TODO: >128 bit-width floats")
-		}
-
-		return
+func (l *lowerer) functionAbi(
+	convention callingConvention,
+	params []types.Type,
+	returnType types.Type,
+	location text.Location,
+) *ir.FunctionAbi {
+	abi := &ir.FunctionAbi{
+		Parameters: make([]ir.PassInfo, 0, len(params)),
+		Return:     l.passInfo(convention, returnType, true, location),
 	}
 
-	if types.IsPtr(ty) {
-		*low = integer
+	for _, param := range params {
+		abi.Parameters = append(abi.Parameters, l.passInfo(convention, param, false, location))
 	}
 
-	if structTy, ok := types.Unwrap(ty).(*types.Struct); ok {
-		if bitWidth > 8*eightBytes {
-			// current is already set to memory
-			return
-		}
-
-		fieldOffset := 0
-		*current = noClass
-
-		for _, field := range structTy.Fields {
-			fieldWidth := types.BitSize(field.Type)
-			currentOffset := fieldOffset
-			fieldOffset += fieldWidth
-
-			// TODO: Account for unaligned fields (when possible)
-			var fieldLow, fieldHigh abiClass
-			classify(field.Type, currentOffset, &fieldLow, &fieldHigh)
-
-			*low = merge(*low, fieldLow)
-			*high = merge(*high, fieldHigh)
+	// Values returned in memory are written to a pointer passed by the caller
+	if abi.Return.Kind == ir.PassIndirect {
+		abi.Return.AbiType = types.Void
+	}
 
-			// As soon as one field is passed in memory, the whole struct is
-			if *low == memory || *high == memory {
-				break
-			}
-		}
+	return abi
+}
 
-		postMerge(bitWidth, low, high)
-		return
+func (l *lowerer) passInfo(
+	convention callingConvention,
+	ty types.Type,
+	isReturn bool,
+	location text.Location,
+) ir.PassInfo {
+	if convention == nil || types.ByteSize(ty, l.target) == 0 {
+		return directly(ty)
 	}
-	panic(fmt.Sprintf("TODO: ABI for %T: %s", ty, ty.String()))
+	passInfo, unsupported := convention.passInfo(ty, isReturn)
+	if unsupported != nil {
+		l.diagnostics.Report(diagnostics.InternalError(location, fmt.Sprintf(
+			"Passing values of type %q to functions is not implemented yet for target %q",
+			unsupported.String(),
+			l.target.Triple,
+		)))
+	}
+	return passInfo
 }
 
-func merge(main, other abiClass) abiClass {
-	if main == noClass {
-		return other
-	} else if main == other || other == noClass {
-		return main
-	} else if main == memory || other == memory {
-		return memory
-	} else if main == integer || other == integer {
-		return integer
-	} else {
-		return sse
+func directly(ty types.Type) ir.PassInfo {
+	return ir.PassInfo{
+		Kind:    ir.PassDirect,
+		Type:    ty,
+		AbiType: ty,
 	}
 }
 
-func postMerge(bitWidth int, low, high *abiClass) {
-	if *high == memory {
-		*low = memory
+func coerced(ty, abiType types.Type) ir.PassInfo {
+	return ir.PassInfo{
+		Kind:    ir.PassCoerced,
+		Type:    ty,
+		AbiType: abiType,
 	}
+}
 
-	if bitWidth > 2*eightBytes && !(*low == sse && *high == sseUp) {
-		*low = memory
+func indirectly(ty types.Type, byVal bool) ir.PassInfo {
+	return ir.PassInfo{
+		Kind:    ir.PassIndirect,
+		Type:    ty,
+		AbiType: &types.Pointer{Underlying: ty, Mutable: false},
+		ByVal:   byVal,
 	}
+}
 
-	if *high == sseUp && *low != sse {
-		*high = sse
+func isAggregate(ty types.Type) bool {
+	switch types.Unwrap(ty).(type) {
+	case *types.Struct, *types.TupleStruct, *types.TupleType, *types.ArrayType,
+		*types.Union, *types.Option:
+		return true
+	default:
+		return false
 	}
 }
+
+const bits = 1
+const bytes = 8 * bits
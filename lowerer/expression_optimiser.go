@@ -382,7 +382,7 @@ func constValueToExpr(constValue values.ConstValue, ty types.Type) ir.Expression
 		}
 
 	case values.StructValue:
-		ty := ty.(*types.Struct)
+		ty := types.Unwrap(ty).(*types.Struct)
 		fields := make(map[string]ir.Expression, len(value.Members))
 		for name, field := range value.Members {
 			fields[name] = constValueToExpr(field, ty.Fields[name].Type)
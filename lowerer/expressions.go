@@ -41,6 +41,7 @@ func (l *lowerer) lowerBinaryExpression(binExpr *ir.BinaryExpression, statements
 		return binExpr
 	}
 	return &ir.BinaryExpression{
+		Location: binExpr.Location,
 		Left:     left,
 		Operator: binExpr.Operator,
 		Right:    right,
@@ -69,6 +70,7 @@ func (l *lowerer) lowerUnaryExpression(unExpr *ir.UnaryExpression, statements *[
 		return unExpr
 	}
 	return &ir.UnaryExpression{
+		Location: unExpr.Location,
 		Operator: unExpr.Operator,
 		Operand:  operand,
 	}
@@ -79,10 +81,19 @@ func (l *lowerer) lowerConversion(conversion *ir.Conversion, statements *[]ir.St
 	if expr == nil {
 		return nil
 	}
+	// Untagged unions have no tag to set, so the value is just reinterpreted
+	if types.IsUntaggedUnion(conversion.To) && !types.IsUntaggedUnion(expr.Type()) {
+		return &ir.BitCast{
+			Location: conversion.Location,
+			Value:    expr,
+			To:       conversion.To,
+		}
+	}
 	if expr == conversion.Expression {
 		return conversion
 	}
 	return &ir.Conversion{
+		Location:   conversion.Location,
 		Expression: expr,
 		To:         conversion.To,
 	}
@@ -107,6 +118,7 @@ func (l *lowerer) lowerArrayExpression(array *ir.ArrayExpression, statements *[]
 	}
 	if changed {
 		return &ir.ArrayExpression{
+			Location: array.Location,
 			DataType: array.DataType,
 			Elements: values,
 		}
@@ -129,8 +141,9 @@ func (l *lowerer) lowerIndexExpression(i *ir.IndexExpression, statements *[]ir.S
 		return i
 	}
 	return &ir.IndexExpression{
+		Location: i.Location,
 		Left:     left,
-		Index:    i,
+		Index:    index,
 		DataType: i.DataType,
 	}
 }
@@ -151,6 +164,7 @@ func (l *lowerer) lowerMapExpression(mapExpr *ir.MapExpression, statements *[]ir
 	}
 	if changed {
 		return &ir.MapExpression{
+			Location:  mapExpr.Location,
 			KeyValues: keyValues,
 			DataType:  mapExpr.DataType,
 		}
@@ -173,6 +187,7 @@ func (l *lowerer) lowerTupleExpression(tuple *ir.TupleExpression, statements *[]
 	}
 	if changed {
 		return &ir.TupleExpression{
+			Location: tuple.Location,
 			Values:   values,
 			DataType: tuple.DataType,
 		}
@@ -187,6 +202,7 @@ func (l *lowerer) lowerAssignment(assignment *ir.Assignment, statements *[]ir.St
 		return assignment
 	}
 	return &ir.Assignment{
+		Location: assignment.Location,
 		Assignee: assignee,
 		Value:    value,
 	}
@@ -201,6 +217,7 @@ func (l *lowerer) lowerTypeCheck(tc *ir.TypeCheck, statements *[]ir.Statement, u
 		return tc
 	}
 	return &ir.TypeCheck{
+		Location: tc.Location,
 		Value:    value,
 		DataType: tc.DataType,
 	}
@@ -213,9 +230,7 @@ func (l *lowerer) lowerFunctionCall(call *ir.FunctionCall, statements *[]ir.Stat
 		lowered := l.lowerExpression(arg, statements, true)
 		if !changed && lowered != arg {
 			changed = true
-			if i != 0 {
-				args = append(args, call.Arguments[:i-1]...)
-			}
+			args = append(args, call.Arguments[:i]...)
 		}
 		if changed {
 			args = append(args, lowered)
@@ -228,12 +243,14 @@ func (l *lowerer) lowerFunctionCall(call *ir.FunctionCall, statements *[]ir.Stat
 		result = call
 	} else if !changed {
 		result = &ir.FunctionCall{
+			Location:   call.Location,
 			Function:   function,
 			Arguments:  call.Arguments,
 			ReturnType: call.ReturnType,
 		}
 	} else {
 		result = &ir.FunctionCall{
+			Location:   call.Location,
 			Function:   function,
 			Arguments:  args,
 			ReturnType: call.ReturnType,
@@ -259,8 +276,9 @@ func (l *lowerer) lowerStructExpression(structExpr *ir.StructExpression, stateme
 		return structExpr
 	}
 	return &ir.StructExpression{
-		Struct: structExpr.Struct,
-		Fields: fields,
+		Location: structExpr.Location,
+		Struct:   structExpr.Struct,
+		Fields:   fields,
 	}
 }
 
@@ -281,8 +299,9 @@ func (l *lowerer) lowerTupleStructExpression(tuple *ir.TupleStructExpression, st
 		return tuple
 	}
 	return &ir.TupleStructExpression{
-		Struct: tuple.Struct,
-		Fields: fields,
+		Location: tuple.Location,
+		Struct:   tuple.Struct,
+		Fields:   fields,
 	}
 }
 
@@ -291,13 +310,23 @@ func (l *lowerer) lowerMemberExpression(member *ir.MemberExpression, statements
 	if left == nil {
 		return left
 	}
+	// Reading a variant of an untagged union reinterprets the union's memory
+	if types.IsUntaggedUnion(left.Type()) {
+		return &ir.BitCast{
+			Location: member.Location,
+			Value:    left,
+			To:       member.DataType,
+		}
+	}
 	if left == member.Left {
 		return member
 	}
 	return &ir.MemberExpression{
+		Location: member.Location,
 		Left:     left,
 		Member:   member.Member,
 		DataType: member.DataType,
+		TypeInfo: member.TypeInfo,
 	}
 }
 
@@ -489,8 +518,9 @@ func (l *lowerer) lowerRefExpression(ref *ir.RefExpression, statements *[]ir.Sta
 		return ref
 	}
 	return &ir.RefExpression{
-		Value:   value,
-		Mutable: ref.Mutable,
+		Location: ref.Location,
+		Value:    value,
+		Mutable:  ref.Mutable,
 	}
 }
 
@@ -503,6 +533,7 @@ func (l *lowerer) lowerDerefExpression(deref *ir.DerefExpression, statements *[]
 		return deref
 	}
 	return &ir.DerefExpression{
-		Value: value,
+		Location: deref.Location,
+		Value:    value,
 	}
 }
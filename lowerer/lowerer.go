@@ -12,6 +12,7 @@ import (
 type lowerer struct {
 	currentModule *ir.LoweredModule
 	diagnostics   diagnostics.Manager
+	target        types.TargetInfo
 	labelId       int
 	varId         int
 	scope         *scope
@@ -49,10 +50,27 @@ func makeMain() *ir.FunctionDeclaration {
 	}
 }
 
-func Lower(pkg *ir.Package, diagnostics diagnostics.Manager) (*ir.LoweredPackage, diagnostics.Manager) {
+func Lower(pkg *ir.Package, target types.TargetInfo, diagnostics diagnostics.Manager) (*ir.LoweredPackage, diagnostics.Manager) {
 	lowerer := lowerer{
 		diagnostics: diagnostics,
+		target:      target,
 	}
+	lowered := lowerer.lowerPackage(pkg)
+	lowerer.fixAbi(lowered)
+	return lowered, lowerer.diagnostics
+}
+
+// Lowers a package for a backend which follows the target's calling
+// convention itself, such as the C backend
+func LowerWithoutAbi(pkg *ir.Package, diagnostics diagnostics.Manager) (*ir.LoweredPackage, diagnostics.Manager) {
+	lowerer := lowerer{
+		diagnostics: diagnostics,
+	}
+	lowered := lowerer.lowerPackage(pkg)
+	return lowered, lowerer.diagnostics
+}
+
+func (l *lowerer) lowerPackage(pkg *ir.Package) *ir.LoweredPackage {
 
 	lowered := &ir.LoweredPackage{
 		Modules: map[string]*ir.LoweredModule{},
@@ -69,20 +87,19 @@ func Lower(pkg *ir.Package, diagnostics diagnostics.Manager) (*ir.LoweredPackage
 			Globals:      []*ir.VariableDeclaration{},
 		}
 		lowered.Modules[name] = mod
-		lowerer.currentModule = mod
+		l.currentModule = mod
 
 		for _, stmt := range module.Statements {
-			lowerer.lowerGlobal(stmt, mod, !definesMain)
+			l.lowerGlobal(stmt, mod, !definesMain)
 		}
 		// Only compile main if there are any statements there
 		if definesMain || len(mainFunction.Body.Statements) == 0 {
 			mod.Functions = mod.Functions[1:]
 		} else {
-			mainFunction.Body.Statements = lowerer.cfa(mainFunction.Body.Statements, nil, false)
+			mainFunction.Body.Statements = l.cfa(mainFunction.Body.Statements, nil, false)
 		}
 	}
-	fixAbi(lowered)
-	return lowered, lowerer.diagnostics
+	return lowered
 }
 
 func getMain(module *ir.Module) (*ir.FunctionDeclaration, bool) {
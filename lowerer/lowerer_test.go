@@ -67,6 +67,21 @@ func TestExpressionOptimisation(t *testing.T) {
 	)
 }
 
+func TestFunctionCalls(t *testing.T) {
+	utils.MatchLoweredSnaps(t,
+		`fn add(a, b: i32): i32 { a + b }
+mut a = 1
+let value = add(a, 1 + 2)`,
+		`fn sum(a, b, c: i32): i32 { a + b + c }
+mut a = 1
+mut b = 2
+let value = sum(a, b, 3 * 4)`,
+		`fn sum(a, b, c: i32): i32 { a + b + c }
+mut a = 1
+let value = sum(1 + 2, a, 3)`,
+	)
+}
+
 func TestUncertainReturns(t *testing.T) {
 	utils.MatchLowerErrors(t,
 		`fn add(a, b: i32): i32 {
@@ -131,3 +146,33 @@ i--`,
 let bar = 2`,
 	)
 }
+
+func TestUnsupportedAbi(t *testing.T) {
+	utils.MatchLowerErrorsForTarget(t, "riscv64-unknown-linux-gnu",
+		`@extern
+fn set_colour(c: Colour)
+
+struct Colour { r, g, b: u8 }`,
+		`@extern
+fn exit(code: i32)
+
+exit(1)`,
+	)
+}
+
+func TestFunctionPointerAbi(t *testing.T) {
+	utils.MatchLoweredSnaps(t,
+		"fn _apply(f: fn(i32): i32): i32 { return f(1) }",
+		`struct Callback { f: fn(i32): i32, data: i32 }
+
+fn _call(callback: Callback): i32 {
+	return callback.f(callback.data)
+}`,
+	)
+}
+
+func TestUnimplementedAbi(t *testing.T) {
+	utils.MatchLowerErrors(t,
+		"fn _either(_value: i32 | f32) {}",
+	)
+}
@@ -12,8 +12,9 @@ func (l *lowerer) lowerVariableDeclaration(varDecl *ir.VariableDeclaration, stat
 		return
 	}
 	*statements = append(*statements, &ir.VariableDeclaration{
-		Symbol: varDecl.Symbol,
-		Value:  value,
+		Location: varDecl.Location,
+		Symbol:   varDecl.Symbol,
+		Value:    value,
 	})
 }
 
@@ -29,13 +30,16 @@ func (l *lowerer) lowerFunctionDeclaration(funcDecl *ir.FunctionDeclaration) *ir
 	}
 
 	fn := &ir.FunctionDeclaration{
-		Name:       funcDecl.Name,
-		Parameters: funcDecl.Parameters,
-		Body:       body,
-		Type:       funcDecl.Type,
-		Exported:   funcDecl.Exported,
-		Extern:     funcDecl.Extern,
-		Location:   funcDecl.Location,
+		Name:         funcDecl.Name,
+		Parameters:   funcDecl.Parameters,
+		Body:         body,
+		Type:         funcDecl.Type,
+		MethodOf:     funcDecl.MethodOf,
+		Exported:     funcDecl.Exported,
+		Extern:       funcDecl.Extern,
+		ImportModule: funcDecl.ImportModule,
+		ImportName:   funcDecl.ImportName,
+		Location:     funcDecl.Location,
 	}
 	return fn
 }
@@ -52,7 +56,8 @@ func (l *lowerer) lowerReturnStatement(ret *ir.ReturnStatement, statements *[]ir
 		return
 	}
 	*statements = append(*statements, &ir.ReturnStatement{
-		Value: value,
+		Location: ret.Location,
+		Value:    value,
 	})
 }
 
@@ -0,0 +1,240 @@
+package lowerer
+
+import (
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// The System V x86-64 calling convention, described in section 3.2.3 of
+// https://gitlab.com/x86-psABIs/x86-64-ABI
+type sysV struct {
+	target types.TargetInfo
+}
+
+type abiClass int
+
+const (
+	noClass abiClass = iota
+	integer
+	sse
+	sseUp
+	// x87
+	// x87Up
+	// complexX87
+	memory
+)
+
+const eightBytes = 8 * bytes
+
+func (s sysV) passInfo(ty types.Type, isReturn bool) (ir.PassInfo, types.Type) {
+	low, high, unsupported := s.classify(ty)
+	bitWidth := types.BitSize(ty, s.target)
+
+	if unsupported != nil {
+		return directly(ty), unsupported
+	}
+
+	if low == memory {
+		return indirectly(ty, !isReturn), nil
+	}
+
+	// Scalars are already passed correctly by LLVM
+	if !isAggregate(ty) {
+		return directly(ty), nil
+	}
+
+	if high == noClass {
+		return coerced(ty, eightbyteType(low, bitWidth)), nil
+	}
+	return coerced(ty, &types.TupleType{Types: []types.Type{
+		eightbyteType(low, eightBytes),
+		eightbyteType(high, bitWidth-eightBytes),
+	}}), nil
+}
+
+// The type used to pass an eightbyte of the given class,
+// which contains `bitWidth` bits of data
+func eightbyteType(class abiClass, bitWidth int) types.Type {
+	if class == sse {
+		if bitWidth <= 32 {
+			return types.Float(32)
+		}
+		return types.Float(64)
+	}
+	return types.Int(bitWidth)
+}
+
+// The classes of the eightbytes of a value, and the first type found in it
+// which can't be classified yet
+type classes struct {
+	eightbytes  [2]abiClass
+	unsupported types.Type
+}
+
+// Classifies the two eightbytes of a value. Values larger than two
+// eightbytes are always passed in memory, as we don't support vector types.
+func (s sysV) classify(ty types.Type) (low, high abiClass, unsupported types.Type) {
+	bitWidth := types.BitSize(ty, s.target)
+	if bitWidth > 2*eightBytes {
+		return memory, memory, nil
+	}
+
+	var classes classes
+	s.classifyAt(ty, 0, &classes)
+	low, high = classes.eightbytes[0], classes.eightbytes[1]
+
+	postMerge(bitWidth, &low, &high)
+	return low, high, classes.unsupported
+}
+
+// Merges the classes of a value at the given offset (in bits) into the
+// classes of the eightbytes it occupies
+func (s sysV) classifyAt(ty types.Type, offset int, classes *classes) {
+	bitWidth := types.BitSize(ty, s.target)
+	index := offset / eightBytes
+
+	add := func(index int, class abiClass) {
+		classes.eightbytes[index] = merge(classes.eightbytes[index], class)
+	}
+
+	if bitWidth == 0 {
+		return
+	}
+
+	if offset+bitWidth > 2*eightBytes {
+		add(0, memory)
+		return
+	}
+
+	if types.IsBool(ty) {
+		add(index, integer)
+		return
+	}
+
+	// TODO: Not CStrings
+	if types.IsString(ty) {
+		add(index, integer)
+		return
+	}
+
+	if types.IsInt(ty) {
+		if bitWidth <= 64 {
+			add(index, integer)
+		} else {
+			add(0, integer)
+			add(1, integer)
+		}
+		return
+	}
+
+	if types.IsFloat(ty) {
+		if bitWidth <= 64 {
+			add(index, sse)
+		} else {
+			add(0, sse)
+			add(1, sseUp)
+		}
+		return
+	}
+
+	// Runtime types are pointers into the type-info table
+	if types.IsPtr(ty) || types.Unwrap(ty) == types.RuntimeType {
+		add(index, integer)
+		return
+	}
+
+	switch ty := types.Unwrap(ty).(type) {
+	// Functions are passed as pointers to their code
+	case *types.Function:
+		add(index, integer)
+
+	case *types.Enum:
+		s.classifyAt(ty.Underlying, offset, classes)
+
+	case *types.Struct:
+		fields := make([]types.Type, 0, len(ty.FieldOrder))
+		for _, name := range ty.FieldOrder {
+			fields = append(fields, ty.Fields[name].Type)
+		}
+		s.classifyFields(fields, offset, classes)
+
+	case *types.TupleStruct:
+		s.classifyFields(ty.Types, offset, classes)
+
+	case *types.TupleType:
+		s.classifyFields(ty.Types, offset, classes)
+
+	case *types.ArrayType:
+		elemWidth := types.BitSize(ty.ElemType, s.target)
+		for i := 0; i < ty.Length; i++ {
+			s.classifyAt(ty.ElemType, offset+i*elemWidth, classes)
+		}
+
+	case *types.Union:
+		members := make([]types.Type, 0, len(ty.Members))
+		for _, name := range ty.MemberNames() {
+			members = append(members, ty.Members[name])
+		}
+		if ty.Untagged {
+			// All members of an untagged union start at the same offset
+			for _, member := range members {
+				s.classifyAt(member, offset, classes)
+			}
+		} else {
+			s.classifyTagged(members, offset, classes)
+		}
+
+	case *types.Option:
+		s.classifyTagged([]types.Type{ty.SomeType}, offset, classes)
+
+	default:
+		if classes.unsupported == nil {
+			classes.unsupported = ty
+		}
+	}
+}
+
+func (s sysV) classifyFields(fields []types.Type, offset int, classes *classes) {
+	for i, fieldOffset := range types.FieldOffsets(fields, s.target) {
+		s.classifyAt(fields[i], offset+fieldOffset*bytes, classes)
+	}
+}
+
+// Tagged values store their payload, followed by a single byte tag
+func (s sysV) classifyTagged(payloads []types.Type, offset int, classes *classes) {
+	payloadWidth := 0
+	for _, payload := range payloads {
+		s.classifyAt(payload, offset, classes)
+		payloadWidth = max(payloadWidth, types.BitSize(payload, s.target))
+	}
+	s.classifyAt(types.Uint(8), offset+payloadWidth, classes)
+}
+
+func merge(main, other abiClass) abiClass {
+	if main == noClass {
+		return other
+	} else if main == other || other == noClass {
+		return main
+	} else if main == memory || other == memory {
+		return memory
+	} else if main == integer || other == integer {
+		return integer
+	} else {
+		return sse
+	}
+}
+
+func postMerge(bitWidth int, low, high *abiClass) {
+	if *low == memory || *high == memory {
+		*low = memory
+		*high = memory
+	}
+
+	if bitWidth > 2*eightBytes && !(*low == sse && *high == sseUp) {
+		*low = memory
+	}
+
+	if *high == sseUp && *low != sse {
+		*high = sse
+	}
+}
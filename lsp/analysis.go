@@ -0,0 +1,490 @@
+package lsp
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/module"
+	"github.com/gearsdatapacks/libra/text"
+	typechecker "github.com/gearsdatapacks/libra/type_checker"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/symbols"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// The result of type checking the module containing a document
+type analysis struct {
+	path        string
+	module      *ir.Module
+	diagnostics diagnostics.Manager
+}
+
+// Loads and type checks the module containing a file, using the
+// contents of open documents instead of the files on disk
+func analyse(filePath string, overlay module.Overlay, target types.TargetInfo) (result *analysis) {
+	result = &analysis{path: filePath}
+
+	// The compiler still panics on some invalid programs, which
+	// shouldn't bring down the whole server
+	defer func() {
+		if err := recover(); err != nil {
+			result.module = nil
+			result.diagnostics = append(result.diagnostics, *diagnostics.InternalError(
+				text.Location{},
+				fmt.Sprint(err),
+			))
+		}
+	}()
+
+	mod, diags := module.LoadWithOverlay(filePath, overlay)
+	// Warnings are allowed or denied by attributes in the source,
+	// as there are no command line flags to apply
+	levels := diagnostics.Levels{Scopes: mod.DiagnosticScopes()}
+	result.diagnostics = diags.WithLevels(levels)
+	if len(diags) != 0 {
+		return result
+	}
+
+	pkg, diags := typechecker.TypeCheck(mod, typechecker.Options{Target: target}, diags)
+	result.diagnostics = diags.WithLevels(levels)
+	result.module = pkg.Modules[mod.Path]
+	return result
+}
+
+// Whether a location is in the analysed file
+func (a *analysis) inFile(location text.Location) bool {
+	return location.File != nil && location.File.FileName == a.path
+}
+
+// Finds the innermost node which contains an offset in the file
+func (a *analysis) nodeAt(offset int) ir.Statement {
+	var found ir.Statement
+	var foundSpan text.Span
+	for _, stmt := range a.module.Statements {
+		ir.Inspect(stmt, func(stmt ir.Statement) bool {
+			location := nameLocation(stmt)
+			if a.inFile(location) && contains(location.Span, offset) &&
+				(found == nil || narrower(location.Span, foundSpan)) {
+				found = stmt
+				foundSpan = location.Span
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// The location of a node in the IR usually only covers its first token,
+// so declarations are located by their name instead
+func nameLocation(stmt ir.Statement) text.Location {
+	if decl, ok := stmt.(*ir.VariableDeclaration); ok && decl.Symbol.Location.File != nil {
+		return decl.Symbol.Location
+	}
+	return stmt.GetLocation()
+}
+
+// Finds the span of source code covered by a node and its children.
+// Blocks are only located by their opening brace, so the closing
+// brace is found in the source code.
+func (a *analysis) extent(stmt ir.Statement) text.Span {
+	var span text.Span
+	first := true
+	ir.Inspect(stmt, func(stmt ir.Statement) bool {
+		location := stmt.GetLocation()
+		if !a.inFile(location) {
+			return true
+		}
+		nodeSpan := location.Span
+		if _, ok := stmt.(*ir.Block); ok {
+			nodeSpan.End = closingBrace(location.File.Text, nodeSpan.Start)
+		}
+		if first {
+			span = nodeSpan
+			first = false
+		} else {
+			span.Start = min(span.Start, nodeSpan.Start)
+			span.End = max(span.End, nodeSpan.End)
+		}
+		return true
+	})
+	return span
+}
+
+// Finds the end of the block starting at an opening brace,
+// skipping over any braces in strings or comments
+func closingBrace(source string, start int) int {
+	depth := 0
+	for i := start; i < len(source); i++ {
+		switch source[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case '"':
+			for i++; i < len(source) && source[i] != '"'; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if i+1 < len(source) && source[i+1] == '/' {
+				for i < len(source) && source[i] != '\n' {
+					i++
+				}
+			}
+		}
+	}
+	return len(source)
+}
+
+// Identifiers are still hovered when the cursor is just after them
+func contains(span text.Span, offset int) bool {
+	return span.Start <= offset && offset <= span.End
+}
+
+func narrower(span, other text.Span) bool {
+	return span.End-span.Start <= other.End-other.Start
+}
+
+func (a *analysis) hover(offset int) *Hover {
+	node := a.nodeAt(offset)
+	if node == nil {
+		return nil
+	}
+
+	var description string
+	switch node := node.(type) {
+	case *ir.VariableExpression:
+		description = fmt.Sprintf("%s: %s", node.Symbol.Name, node.Symbol.Type)
+	case *ir.VariableDeclaration:
+		description = fmt.Sprintf("%s: %s", node.Symbol.Name, node.Symbol.Type)
+	case *ir.FunctionDeclaration:
+		description = fmt.Sprintf("fn %s: %s", node.Name, node.Type)
+	case *ir.TypeDeclaration:
+		description = fmt.Sprintf("type %s = %s", node.Name, node.Type)
+	case ir.Expression:
+		description = node.Type().String()
+	default:
+		return nil
+	}
+
+	nodeRange := toRange(nameLocation(node))
+	return &Hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: fmt.Sprintf("```libra\n%s\n```", description),
+		},
+		Range: &nodeRange,
+	}
+}
+
+func (a *analysis) definition(offset int) *text.Location {
+	var location text.Location
+	switch node := a.nodeAt(offset).(type) {
+	case *ir.VariableExpression:
+		location = node.Symbol.Location
+	case *ir.TypeExpression:
+		location = a.typeLocation(node.DataType)
+	case *ir.StructExpression:
+		location = a.typeLocation(node.Struct)
+	case *ir.TupleStructExpression:
+		location = a.typeLocation(node.Struct)
+	}
+
+	if location.File == nil {
+		return nil
+	}
+	return &location
+}
+
+// Finds where a named type was declared
+func (a *analysis) typeLocation(ty types.Type) text.Location {
+	symbol, ok := a.module.Symbols.Lookup(ty.String()).(*symbols.Type)
+	if !ok {
+		return text.Location{}
+	}
+	return symbol.Location
+}
+
+// Finds the local variables which are in scope at an offset. Top-level
+// symbols are stored in the module's symbol table, but local scopes are
+// discarded after type checking, so local variables are found by
+// walking the code surrounding the offset.
+func (a *analysis) locals(offset int) map[string]types.Type {
+	locals := map[string]types.Type{}
+	for _, stmt := range a.module.Statements {
+		ir.Inspect(stmt, func(stmt ir.Statement) bool {
+			location := stmt.GetLocation()
+			if location.File != nil && (!a.inFile(location) || !contains(a.extent(stmt), offset)) {
+				return false
+			}
+
+			switch stmt := stmt.(type) {
+			case *ir.FunctionDeclaration:
+				for i, param := range stmt.Parameters {
+					locals[param] = stmt.Type.Parameters[i]
+				}
+			case *ir.FunctionExpression:
+				for i, param := range stmt.Parameters {
+					locals[param] = stmt.DataType.Parameters[i]
+				}
+			case *ir.ForLoop:
+				locals[stmt.Variable.Name] = stmt.Variable.Type
+			case *ir.Block:
+				for _, inner := range stmt.Statements {
+					decl, ok := inner.(*ir.VariableDeclaration)
+					if ok && a.inFile(decl.Location) && decl.Location.Span.End <= offset {
+						locals[decl.Symbol.Name] = decl.Symbol.Type
+					}
+				}
+			}
+			return true
+		})
+	}
+	return locals
+}
+
+// Lists the identifiers which are in scope at an offset
+func (a *analysis) completeIdentifiers(offset int) []CompletionItem {
+	locals := a.locals(offset)
+
+	items := []CompletionItem{}
+	for _, symbol := range a.module.Symbols.Symbols() {
+		if _, shadowed := locals[symbol.GetName()]; shadowed {
+			continue
+		}
+		items = append(items, symbolCompletion(symbol))
+	}
+	for name, ty := range locals {
+		items = append(items, CompletionItem{
+			Label:  name,
+			Kind:   variableKind(ty),
+			Detail: ty.String(),
+		})
+	}
+	slices.SortFunc(items, func(a, b CompletionItem) int {
+		return strings.Compare(a.Label, b.Label)
+	})
+	return items
+}
+
+func symbolCompletion(symbol symbols.Symbol) CompletionItem {
+	if ty, ok := symbol.(*symbols.Type); ok {
+		return CompletionItem{
+			Label:  ty.Name,
+			Kind:   completionClass,
+			Detail: ty.Type.String(),
+		}
+	}
+	return CompletionItem{
+		Label:  symbol.GetName(),
+		Kind:   variableKind(symbol.GetType()),
+		Detail: symbol.GetType().String(),
+	}
+}
+
+func variableKind(ty types.Type) int {
+	switch ty.(type) {
+	case *types.Function:
+		return completionFunction
+	case *types.Module:
+		return completionModule
+	default:
+		return completionVariable
+	}
+}
+
+// Lists the members of the value written before a `.`, which is
+// given as a chain of names, such as `a.b` in `a.b.`
+func (a *analysis) completeMembers(offset int, chain []string) []CompletionItem {
+	ty := a.lookupType(offset, chain[0])
+	for _, name := range chain[1:] {
+		if ty == nil {
+			return nil
+		}
+		member, diag := types.Member(ty, name)
+		if diag != nil {
+			return nil
+		}
+		ty = member
+	}
+	if ty == nil {
+		return nil
+	}
+
+	items := []CompletionItem{}
+	if module, ok := ty.(*types.Module); ok {
+		if table, ok := module.Module.(*symbols.Table); ok {
+			for _, symbol := range table.Exports() {
+				items = append(items, symbolCompletion(symbol))
+			}
+		}
+		return items
+	}
+
+	for _, name := range types.MemberNames(ty) {
+		member, diag := types.Member(ty, name)
+		if diag != nil {
+			continue
+		}
+		items = append(items, CompletionItem{
+			Label:  name,
+			Kind:   completionField,
+			Detail: member.String(),
+		})
+	}
+	for _, name := range a.module.Symbols.MethodNames(ty, false) {
+		method := a.module.Symbols.LookupMethod(name, ty, false)
+		items = append(items, CompletionItem{
+			Label:  name,
+			Kind:   completionMethod,
+			Detail: method.String(),
+		})
+	}
+	return items
+}
+
+// Finds the type of a variable visible at an offset
+func (a *analysis) lookupType(offset int, name string) types.Type {
+	if ty, ok := a.locals(offset)[name]; ok {
+		return ty
+	}
+	if symbol := a.module.Symbols.Lookup(name); symbol != nil {
+		return symbol.GetType()
+	}
+	return nil
+}
+
+// Splits the text before the cursor into the chain of names being
+// accessed, and reports whether the cursor is after a `.`
+func memberChain(source string, offset int) ([]string, bool) {
+	end := offset
+	for end > 0 && isIdentifierByte(source[end-1]) {
+		end--
+	}
+	if end == 0 || source[end-1] != '.' {
+		return nil, false
+	}
+
+	start := end - 1
+	for start > 0 && (isIdentifierByte(source[start-1]) || source[start-1] == '.') {
+		start--
+	}
+	chain := strings.Split(source[start:end-1], ".")
+	for _, name := range chain {
+		if name == "" {
+			return nil, false
+		}
+	}
+	return chain, true
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// Finds the diagnostics which belong to a file. Diagnostics without
+// a location are shown at the start of every file in the module.
+func (a *analysis) diagnosticsFor(filePath string) []Diagnostic {
+	result := []Diagnostic{}
+	for _, diag := range a.diagnostics {
+		var diagRange Range
+		if diag.Location.File != nil {
+			if diag.Location.File.FileName != filePath {
+				continue
+			}
+			diagRange = toRange(diag.Location)
+		} else if path.Dir(filePath) != path.Dir(a.path) {
+			continue
+		}
+
+		severity := severityError
+		switch diag.Kind {
+		case diagnostics.Warning:
+			severity = severityWarning
+		case diagnostics.Info:
+			severity = severityInformation
+		}
+
+		message := diag.Message
+		for _, note := range diag.Notes {
+			message += "\nnote: " + note
+		}
+		for _, help := range diag.HelpMessages() {
+			message += "\nhelp: " + help
+		}
+
+		related := []DiagnosticRelatedInformation{}
+		for _, label := range diag.Labels {
+			if label.Location.File == nil {
+				continue
+			}
+			related = append(related, DiagnosticRelatedInformation{
+				Location: Location{Uri: pathToUri(label.Location.File.FileName), Range: toRange(label.Location)},
+				Message:  label.Message,
+			})
+		}
+
+		result = append(result, Diagnostic{
+			Range:              diagRange,
+			Severity:           severity,
+			Code:               diag.Code,
+			Source:             "libra",
+			Message:            message,
+			RelatedInformation: related,
+		})
+	}
+	return result
+}
+
+func toRange(location text.Location) Range {
+	return Range{
+		Start: toPosition(location.File, location.Span.Start),
+		End:   toPosition(location.File, location.Span.End),
+	}
+}
+
+// Converts a byte offset into a line and UTF-16 character
+func toPosition(file *text.SourceFile, offset int) Position {
+	for i, line := range file.Lines {
+		if offset < line.Span.End || i == len(file.Lines)-1 {
+			column := min(max(offset-line.Span.Start, 0), len(line.Text))
+			return Position{Line: i, Character: utf16Length(line.Text[:column])}
+		}
+	}
+	return Position{}
+}
+
+// Converts a line and UTF-16 character into a byte offset
+func toOffset(file *text.SourceFile, position Position) int {
+	if position.Line >= len(file.Lines) {
+		return len(file.Text)
+	}
+	line := file.Lines[position.Line]
+	units := 0
+	for i, char := range line.Text {
+		if units >= position.Character {
+			return line.Span.Start + i
+		}
+		units += len(utf16.Encode([]rune{char}))
+	}
+	return line.Span.Start + len(line.Text)
+}
+
+func utf16Length(s string) int {
+	length := 0
+	for len(s) > 0 {
+		char, size := utf8.DecodeRuneInString(s)
+		length += len(utf16.Encode([]rune{char}))
+		s = s[size:]
+	}
+	return length
+}
@@ -0,0 +1,253 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gearsdatapacks/libra/lsp"
+	utils "github.com/gearsdatapacks/libra/test_utils"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+type client struct {
+	t      *testing.T
+	input  bytes.Buffer
+	nextId int
+	uri    string
+}
+
+type received struct {
+	Id     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+func newClient(t *testing.T) *client {
+	c := &client{t: t, uri: "file://" + filepath.ToSlash(filepath.Join(t.TempDir(), "main.lb"))}
+	c.request("initialize", map[string]any{})
+	c.notify("initialized", map[string]any{})
+	return c
+}
+
+func (c *client) send(msg map[string]any) {
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	fmt.Fprintf(&c.input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (c *client) request(method string, params any) int {
+	c.nextId++
+	c.send(map[string]any{"id": c.nextId, "method": method, "params": params})
+	return c.nextId
+}
+
+func (c *client) notify(method string, params any) {
+	c.send(map[string]any{"method": method, "params": params})
+}
+
+func (c *client) open(src string) {
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": c.uri, "languageId": "libra", "version": 1, "text": src},
+	})
+}
+
+func (c *client) change(src string) {
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": c.uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": src}},
+	})
+}
+
+func (c *client) at(method string, line, character int) int {
+	return c.request(method, map[string]any{
+		"textDocument": map[string]any{"uri": c.uri},
+		"position":     map[string]any{"line": line, "character": character},
+	})
+}
+
+// Runs the server over everything sent so far, returning what it sent back
+func (c *client) run() []received {
+	c.t.Helper()
+	c.request("shutdown", nil)
+	c.notify("exit", nil)
+
+	var output bytes.Buffer
+	if err := lsp.NewServer(&c.input, &output, types.TargetFor("x86_64-unknown-linux-gnu")).Serve(); err != nil {
+		c.t.Fatal(err)
+	}
+
+	messages := []received{}
+	reader := bufio.NewReader(&output)
+	for {
+		header, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err != nil {
+			return messages
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := reader.Read(body); err != nil {
+			c.t.Fatal(err)
+		}
+		var msg received
+		if err := json.Unmarshal(body, &msg); err != nil {
+			c.t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func result[T any](t *testing.T, messages []received, id int) T {
+	t.Helper()
+	var value T
+	for _, msg := range messages {
+		if msg.Id != nil && *msg.Id == id && msg.Method == "" {
+			if err := json.Unmarshal(msg.Result, &value); err != nil {
+				t.Fatal(err)
+			}
+			return value
+		}
+	}
+	t.Fatalf("No response to request %d", id)
+	return value
+}
+
+func diagnostics(t *testing.T, messages []received) [][]lsp.Diagnostic {
+	t.Helper()
+	published := [][]lsp.Diagnostic{}
+	for _, msg := range messages {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params struct {
+			Diagnostics []lsp.Diagnostic `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		published = append(published, params.Diagnostics)
+	}
+	return published
+}
+
+func labels(items []lsp.CompletionItem) []string {
+	names := []string{}
+	for _, item := range items {
+		names = append(names, item.Label)
+	}
+	return names
+}
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	c.open("let x: i32 = true")
+	c.change("let x: i32 = 1")
+	c.change("let x = (")
+	published := diagnostics(t, c.run())
+
+	utils.AssertEq(t, len(published), 3)
+
+	diag := utils.AssertSingle(t, published[0])
+	utils.AssertEq(t, diag.Severity, 1)
+	utils.AssertEq(t, diag.Message, `Value of type "bool" is not assignable to type "i32"`)
+	utils.AssertEq(t, diag.Range, lsp.Range{
+		Start: lsp.Position{Line: 0, Character: 13},
+		End:   lsp.Position{Line: 0, Character: 17},
+	})
+
+	utils.AssertEq(t, len(published[1]), 0)
+	utils.Assert(t, len(published[2]) > 0, "Expected parser errors to be published")
+}
+
+func TestDiagnosticLevels(t *testing.T) {
+	c := newClient(t)
+	c.open("fn unused() {}\n@allow(W0006)\nfn allowed() {}\n@deny(W0006)\nfn denied() {}")
+	published := diagnostics(t, c.run())
+
+	diags := utils.AssertSingle(t, published)
+	utils.AssertEq(t, len(diags), 2)
+	utils.Assert(t, strings.HasPrefix(diags[0].Message, `Function "unused" is never used`), diags[0].Message)
+	utils.AssertEq(t, diags[0].Severity, 2)
+	utils.Assert(t, strings.HasPrefix(diags[1].Message, `Function "denied" is never used`), diags[1].Message)
+	utils.AssertEq(t, diags[1].Severity, 1)
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	c.open(`fn add(a, b: i32): i32 {
+	return a + b
+}
+let sum = add(1, 2)
+let flag = sum == 3`)
+	variable := c.at("textDocument/hover", 1, 8)
+	call := c.at("textDocument/hover", 3, 11)
+	comparison := c.at("textDocument/hover", 4, 15)
+	nothing := c.at("textDocument/hover", 2, 1)
+	messages := c.run()
+
+	utils.AssertEq(t, result[lsp.Hover](t, messages, variable).Contents.Value, "```libra\na: i32\n```")
+	utils.AssertEq(t, result[lsp.Hover](t, messages, call).Contents.Value, "```libra\nadd: fn(i32, i32): i32\n```")
+	utils.AssertEq(t, result[lsp.Hover](t, messages, comparison).Contents.Value, "```libra\nbool\n```")
+	utils.AssertEq(t, result[*lsp.Hover](t, messages, nothing), nil)
+}
+
+func TestDefinition(t *testing.T) {
+	c := newClient(t)
+	c.open(`struct Point { x, y: i32 }
+fn origin(): Point {
+	return Point { x: 0, y: 0 }
+}
+let point = origin()
+let x = point.x`)
+	function := c.at("textDocument/definition", 4, 14)
+	variable := c.at("textDocument/definition", 5, 9)
+	ty := c.at("textDocument/definition", 2, 9)
+	messages := c.run()
+
+	utils.AssertEq(t, result[lsp.Location](t, messages, function).Range.Start, lsp.Position{Line: 1, Character: 3})
+	utils.AssertEq(t, result[lsp.Location](t, messages, variable).Range.Start, lsp.Position{Line: 4, Character: 4})
+	location := result[lsp.Location](t, messages, ty)
+	utils.AssertEq(t, location.Range.Start, lsp.Position{Line: 0, Character: 7})
+	utils.Assert(t, strings.HasSuffix(location.Uri, "/main.lb"), "Expected definition to be in main.lb")
+}
+
+func TestCompletion(t *testing.T) {
+	src := `struct Point { x, y: i32 }
+fn (Point) length(): i32 { return this.x + this.y }
+let origin = Point { x: 0, y: 0 }
+fn use(value: i32): i32 {
+	let doubled = value * 2
+	return doubled
+}
+`
+	c := newClient(t)
+	c.open(src)
+	identifiers := c.at("textDocument/completion", 5, 8)
+	// The server keeps using the last version which type checked,
+	// so members can be completed even though `origin.` is invalid
+	c.change(src + "let a = origin.")
+	members := c.at("textDocument/completion", 7, 15)
+	messages := c.run()
+
+	names := labels(result[[]lsp.CompletionItem](t, messages, identifiers))
+	for _, expected := range []string{"doubled", "value", "origin", "use", "Point", "i32"} {
+		utils.Assert(t, strings.Contains(fmt.Sprint(names), expected), fmt.Sprintf("Expected %q in %v", expected, names))
+	}
+
+	items := result[[]lsp.CompletionItem](t, messages, members)
+	utils.AssertEq(t, fmt.Sprint(labels(items)), "[x y length]")
+	utils.AssertEq(t, items[0].Detail, "i32")
+}
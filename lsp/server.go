@@ -0,0 +1,252 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gearsdatapacks/libra/module"
+	"github.com/gearsdatapacks/libra/text"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// A language server, which communicates with an editor using JSON-RPC.
+// Open documents are kept in memory, and are type checked whenever
+// they change.
+type Server struct {
+	reader *bufio.Reader
+	writer io.Writer
+	// The contents of open documents, keyed by path
+	documents module.Overlay
+	// The most recent successful analysis of each document, which is used
+	// for hovers and completions while the document doesn't type check
+	analyses map[string]*analysis
+	// The target documents are type checked for
+	target types.TargetInfo
+}
+
+func NewServer(reader io.Reader, writer io.Writer, target types.TargetInfo) *Server {
+	return &Server{
+		reader:    bufio.NewReader(reader),
+		writer:    writer,
+		documents: module.Overlay{},
+		analyses:  map[string]*analysis{},
+		target:    target,
+	}
+}
+
+// Handles messages until the editor asks the server to exit
+// or closes its input
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.readMessage()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			s.respondError(nil, parseError, "Invalid JSON-RPC message")
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// Reads a message, returning nil if its contents are invalid
+func (s *Server) readMessage() (*message, error) {
+	header, err := textproto.NewReader(s.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Content-Length header: %q", header.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return nil, err
+	}
+
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, nil
+	}
+	return msg, nil
+}
+
+func (s *Server) write(value any) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) respond(id *json.RawMessage, result any) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.write(response{JsonRpc: "2.0", Id: id, Result: body})
+}
+
+func (s *Server) respondError(id *json.RawMessage, code int, message string) error {
+	return s.write(response{
+		JsonRpc: "2.0",
+		Id:      id,
+		Error:   &responseError{Code: code, Message: message},
+	})
+}
+
+func (s *Server) notify(method string, params any) error {
+	return s.write(notification{JsonRpc: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) handle(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		return s.respond(msg.Id, initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   syncFull,
+				HoverProvider:      true,
+				DefinitionProvider: true,
+				CompletionProvider: completionOptions{TriggerCharacters: []string{"."}},
+			},
+			ServerInfo: serverInfo{Name: "libra"},
+		})
+	case "shutdown":
+		return s.respond(msg.Id, nil)
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		return s.update(params.TextDocument.Uri, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		// The whole document is sent each time, so only the last change matters
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		return s.update(params.TextDocument.Uri, text)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		filePath := uriToPath(params.TextDocument.Uri)
+		delete(s.documents, filePath)
+		delete(s.analyses, filePath)
+		return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			Uri:         params.TextDocument.Uri,
+			Diagnostics: []Diagnostic{},
+		})
+
+	case "textDocument/hover":
+		return s.handlePosition(msg, func(a *analysis, offset int, _ *text.SourceFile) any {
+			return a.hover(offset)
+		})
+	case "textDocument/definition":
+		return s.handlePosition(msg, func(a *analysis, offset int, _ *text.SourceFile) any {
+			location := a.definition(offset)
+			if location == nil {
+				return nil
+			}
+			return Location{Uri: pathToUri(location.File.FileName), Range: toRange(*location)}
+		})
+	case "textDocument/completion":
+		return s.handlePosition(msg, func(a *analysis, offset int, file *text.SourceFile) any {
+			if chain, ok := memberChain(file.Text, offset); ok {
+				return a.completeMembers(offset, chain)
+			}
+			return a.completeIdentifiers(offset)
+		})
+
+	default:
+		// Notifications which the server doesn't support can be ignored
+		if msg.Id == nil {
+			return nil
+		}
+		return s.respondError(msg.Id, methodNotFound, fmt.Sprintf("Unsupported method %q", msg.Method))
+	}
+}
+
+// Responds to a request about a position in a document
+func (s *Server) handlePosition(
+	msg *message,
+	handler func(a *analysis, offset int, file *text.SourceFile) any,
+) error {
+	var params textDocumentPositionParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return s.respondError(msg.Id, invalidParams, err.Error())
+	}
+
+	filePath := uriToPath(params.TextDocument.Uri)
+	contents, open := s.documents[filePath]
+	result, analysed := s.analyses[filePath]
+	if !open || !analysed {
+		return s.respond(msg.Id, nil)
+	}
+
+	file := text.NewFile(filePath, contents)
+	return s.respond(msg.Id, handler(result, toOffset(file, params.Position), file))
+}
+
+// Type checks a document after it changes, and publishes the
+// diagnostics for it and the other open documents in its module
+func (s *Server) update(uri, contents string) error {
+	filePath := uriToPath(uri)
+	s.documents[filePath] = contents
+
+	result := analyse(filePath, s.documents, s.target)
+
+	for openPath := range s.documents {
+		if path.Dir(openPath) != path.Dir(filePath) {
+			continue
+		}
+		if result.module != nil {
+			s.analyses[openPath] = &analysis{
+				path:        openPath,
+				module:      result.module,
+				diagnostics: result.diagnostics,
+			}
+		}
+		err := s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			Uri:         pathToUri(openPath),
+			Diagnostics: result.diagnosticsFor(openPath),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uriToPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	return filepath.ToSlash(parsed.Path)
+}
+
+func pathToUri(filePath string) string {
+	return (&url.URL{Scheme: "file", Path: filePath}).String()
+}
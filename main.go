@@ -2,13 +2,23 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
+	cbackend "github.com/gearsdatapacks/libra/c_backend"
 	"github.com/gearsdatapacks/libra/codegen"
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/docgen"
+	"github.com/gearsdatapacks/libra/formatter"
 	"github.com/gearsdatapacks/libra/lowerer"
+	"github.com/gearsdatapacks/libra/lsp"
 	"github.com/gearsdatapacks/libra/module"
+	"github.com/gearsdatapacks/libra/text"
 	typechecker "github.com/gearsdatapacks/libra/type_checker"
+	typeIr "github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
 	"tinygo.org/x/go-llvm"
 )
 
@@ -22,28 +32,447 @@ const (
 	llir
 )
 
+type backend int
+
+const (
+	llvmBackend backend = iota
+	cBackend
+)
+
+type emitKind int
+
+const (
+	emitObject emitKind = iota
+	emitStaticLib
+	emitSharedLib
+	emitBitcode
+	emitLlvmIr
+)
+
+type options struct {
+	file      string
+	debugKind debugKind
+	target    string
+	cpu       string
+	features  string
+	backend   backend
+	emit      emitKind
+	optLevel  codegen.OptLevel
+	debugInfo bool
+	// Whether to build for release, which optimises the program
+	// and doesn't allow unfinished code
+	release bool
+	// Whether to run the program using the JIT, instead of compiling it
+	run bool
+	// How to print diagnostics, for people or for other programs
+	diagnosticsFormat diagnostics.Format
+	// Which warnings are disabled or reported as errors
+	levels diagnostics.Levels
+}
+
+// Parses the command line, which accepts flags either as
+// `--flag value` or `--flag=value`, after the file to compile.
+// Optimisation levels are passed like a C compiler's, e.g. `-O2`,
+// as is `-g` to generate debug information. `--release` builds for
+// release, optimising at `-O2` unless a level is given.
+// `--diagnostics-format` can be `human`, `json` or `sarif`.
+// `-Werror` reports warnings as errors, `-Werror=<code>` reports one
+// kind of warning as an error and `-Wno-<code>` disables it.
+// `libra run file.lb` runs the program instead of compiling it.
+// `libra lsp` starts a language server, `libra fmt` formats files,
+// `libra doc` generates documentation, `libra fix` applies fixes and
+// `libra explain` explains diagnostic codes, and these are handled
+// separately.
+func parseArgs(args []string) (options, error) {
+	opts := options{
+		debugKind: none,
+		backend:   llvmBackend,
+		target:    llvm.DefaultTargetTriple(),
+	}
+	if len(args) > 0 && args[0] == "run" {
+		opts.run = true
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return opts, fmt.Errorf("Expected a file to compile")
+	}
+	opts.file = args[0]
+
+	optLevelSet := false
+	for i := 1; i < len(args); i++ {
+		if args[i] == "-g" {
+			opts.debugInfo = true
+			continue
+		}
+		if args[i] == "--release" {
+			opts.release = true
+			continue
+		}
+		if flag, ok := strings.CutPrefix(args[i], "-W"); ok {
+			if err := opts.levels.ParseFlag(flag); err != nil {
+				return opts, err
+			}
+			continue
+		}
+		if level, ok := strings.CutPrefix(args[i], "-O"); ok {
+			optLevel, err := codegen.ParseOptLevel(level)
+			if err != nil {
+				return opts, err
+			}
+			opts.optLevel = optLevel
+			optLevelSet = true
+			continue
+		}
+
+		flag, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, fmt.Errorf("Expected a value for flag %q", flag)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "--debug":
+			switch value {
+			case "ast":
+				opts.debugKind = ast
+			case "ir":
+				opts.debugKind = ir
+			case "lowered":
+				opts.debugKind = lowered
+			case "llir":
+				opts.debugKind = llir
+			default:
+				return opts, fmt.Errorf("Unknown debug output %q", value)
+			}
+		case "--target":
+			opts.target = value
+		case "--cpu":
+			opts.cpu = value
+		case "--features":
+			opts.features = value
+		case "--emit":
+			switch value {
+			case "obj":
+				opts.emit = emitObject
+			case "staticlib":
+				opts.emit = emitStaticLib
+			case "sharedlib":
+				opts.emit = emitSharedLib
+			case "bc":
+				opts.emit = emitBitcode
+			case "ll":
+				opts.emit = emitLlvmIr
+			default:
+				return opts, fmt.Errorf("Unknown output kind %q", value)
+			}
+		case "--diagnostics-format":
+			format, err := diagnostics.ParseFormat(value)
+			if err != nil {
+				return opts, err
+			}
+			opts.diagnosticsFormat = format
+		case "--backend":
+			switch value {
+			case "llvm":
+				opts.backend = llvmBackend
+			case "c":
+				opts.backend = cBackend
+			default:
+				return opts, fmt.Errorf("Unknown backend %q", value)
+			}
+		default:
+			return opts, fmt.Errorf("Unknown flag %q", flag)
+		}
+	}
+
+	if opts.release && !optLevelSet {
+		opts.optLevel = codegen.O2
+	}
+
+	if opts.run {
+		if opts.target != llvm.DefaultTargetTriple() {
+			return opts, fmt.Errorf("Programs can only be run on the host target")
+		}
+		if opts.backend != llvmBackend {
+			return opts, fmt.Errorf("Programs can only be run using the LLVM backend")
+		}
+	}
+
+	return opts, nil
+}
+
+type formatMode int
+
+const (
+	formatPrint formatMode = iota
+	formatCheck
+	formatWrite
+)
+
+// Formats the given files, and the Libra files in the given directories.
+// By default the formatted code is printed. `--check` lists the files
+// which aren't formatted, and `--write` formats them in place.
+// Returns the exit code, which is non-zero if a file couldn't be
+// formatted, or if `--check` found any unformatted files.
+func formatFiles(args []string) int {
+	mode := formatPrint
+	paths := []string{}
+	for _, arg := range args {
+		switch arg {
+		case "--check":
+			mode = formatCheck
+		case "--write":
+			mode = formatWrite
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Printf("Unknown flag %q\n", arg)
+				return 2
+			}
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		fmt.Println("Expected a file to format")
+		return 2
+	}
+
+	files := []string{}
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && (path == entry.Name() || strings.HasSuffix(path, ".lb")) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	}
+
+	exitCode := 0
+	for _, path := range files {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Println(err)
+			exitCode = 1
+			continue
+		}
+
+		formatted, diags := formatter.Format(text.NewFile(path, string(contents)))
+		if len(diags) != 0 {
+			for _, diag := range diags {
+				diag.Print()
+			}
+			exitCode = 1
+			continue
+		}
+
+		switch mode {
+		case formatPrint:
+			fmt.Print(formatted)
+		case formatCheck:
+			if formatted != string(contents) {
+				fmt.Println(path)
+				exitCode = 1
+			}
+		case formatWrite:
+			if formatted == string(contents) {
+				continue
+			}
+			if err := os.WriteFile(path, []byte(formatted), 0o644); err != nil {
+				fmt.Println(err)
+				exitCode = 1
+			}
+		}
+	}
+
+	return exitCode
+}
+
+// Generates HTML and Markdown documentation for the exported
+// declarations of a program, written to `--out`, or `docs` by
+// default. Returns the exit code.
+func generateDocs(args []string) int {
+	file := ""
+	out := "docs"
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--out" && i+1 < len(args):
+			i++
+			out = args[i]
+		case strings.HasPrefix(arg, "--out="):
+			out = strings.TrimPrefix(arg, "--out=")
+		case strings.HasPrefix(arg, "-"):
+			fmt.Printf("Unknown flag %q\n", arg)
+			return 2
+		case file == "":
+			file = arg
+		default:
+			fmt.Println("Expected only one file to document")
+			return 2
+		}
+	}
+	if file == "" {
+		fmt.Println("Expected a file to document")
+		return 2
+	}
+
+	// Modules are named after their directory, so the path is made
+	// absolute to give the main module a name
+	file, err := filepath.Abs(file)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	reporter := &reporter{}
+	mod, diags := module.Load(file)
+	reporter.levels.Scopes = mod.DiagnosticScopes()
+	if reporter.report(diags) {
+		return 1
+	}
+
+	pkg, diags := typechecker.TypeCheck(mod, typechecker.Options{Target: types.TargetFor(llvm.DefaultTargetTriple())}, diags)
+	if reporter.report(diags) {
+		return 1
+	}
+
+	if err := docgen.New(pkg).Write(out); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// Applies the fixes suggested by diagnostics to a program's source
+// files. Only fixes which are certain to be correct are applied,
+// unless `--all` is passed. Returns the exit code.
+func fixFiles(args []string) int {
+	file := ""
+	all := false
+	for _, arg := range args {
+		switch {
+		case arg == "--all":
+			all = true
+		case strings.HasPrefix(arg, "-"):
+			fmt.Printf("Unknown flag %q\n", arg)
+			return 2
+		case file == "":
+			file = arg
+		default:
+			fmt.Println("Expected only one file to fix")
+			return 2
+		}
+	}
+	if file == "" {
+		fmt.Println("Expected a file to fix")
+		return 2
+	}
+
+	file, err := filepath.Abs(file)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	// Modules which fail to load can't be type checked, but
+	// their diagnostics can still suggest fixes
+	mod, diags := module.Load(file)
+	if !diags.HasErrors() {
+		_, diags = typechecker.TypeCheck(mod, typechecker.Options{Target: types.TargetFor(llvm.DefaultTargetTriple())}, diags)
+	}
+	// Allowed warnings aren't shown, so their fixes aren't applied
+	diags = diags.WithLevels(diagnostics.Levels{Scopes: mod.DiagnosticScopes()})
+
+	files, applied := diagnostics.ApplyFixes(diagnostics.Fixes(diags, all))
+	for file, contents := range files {
+		if err := os.WriteFile(file.FileName, []byte(contents), 0o644); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	}
+
+	if applied == 1 {
+		fmt.Println("Applied 1 fix")
+	} else {
+		fmt.Printf("Applied %d fixes\n", applied)
+	}
+	return 0
+}
+
+// Prints the long-form explanation of a diagnostic code, such as
+// `E0036`, with an example of code which causes it and how to fix it.
+// Returns the exit code.
+func explainCode(args []string) int {
+	if len(args) != 1 {
+		fmt.Println("Expected a diagnostic code to explain")
+		return 2
+	}
+
+	explanation, ok := diagnostics.Explain(args[0])
+	if !ok {
+		fmt.Printf("Unknown diagnostic code %q\n", args[0])
+		return 1
+	}
+	fmt.Print(explanation.String())
+	return 0
+}
+
 func main() {
-	mod, diags := module.Load(os.Args[1])
-	debugKind := none
-	if len(os.Args) > 3 && os.Args[2] == "--debug" {
-		switch os.Args[3] {
-		case "ast":
-			debugKind = ast
-		case "ir":
-			debugKind = ir
-		case "lowered":
-			debugKind = lowered
-		case "llir":
-			debugKind = llir
-		}
-	}
-
-	if len(diags) != 0 {
-		for _, diag := range diags {
-			diag.Print()
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		if err := lsp.NewServer(os.Stdin, os.Stdout, types.TargetFor(llvm.DefaultTargetTriple())).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(formatFiles(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doc" {
+		os.Exit(generateDocs(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fix" {
+		os.Exit(fixFiles(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "explain" {
+		os.Exit(explainCode(os.Args[2:]))
+	}
+
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	reporter := &reporter{format: opts.diagnosticsFormat, levels: opts.levels}
+	exitCode := compile(opts, reporter)
+	if err := reporter.flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// Compiles, or runs, the program, and returns the exit code
+func compile(opts options, reporter *reporter) int {
+	debugKind := opts.debugKind
+	target := types.TargetFor(opts.target)
+
+	mod, diags := module.Load(opts.file)
+	reporter.levels.Scopes = mod.DiagnosticScopes()
+
+	if reporter.report(diags) {
+		return 1
+	}
 
 	if debugKind == ast {
 		for _, file := range mod.Files {
@@ -51,75 +480,250 @@ func main() {
 			file.Ast.Print()
 			fmt.Println()
 		}
-		return
+		return 0
 	}
 
-	pkg, diags := typechecker.TypeCheck(mod, diags)
+	pkg, diags := typechecker.TypeCheck(mod, typechecker.Options{
+		Target:  target,
+		Release: opts.release,
+	}, diags)
 
-	if len(diags) != 0 {
-		for _, diag := range diags {
-			diag.Print()
-		}
-		return
+	if reporter.report(diags) {
+		return 1
 	}
 
 	if debugKind == ir {
 		pkg.Print()
 		fmt.Println()
-		return
+		return 0
+	}
+
+	if opts.backend == cBackend {
+		return compileC(pkg, debugKind, diags, reporter)
+	}
+
+	loweredPkg, diags := lowerer.Lower(pkg, target, diags)
+
+	if reporter.report(diags) {
+		return 1
+	}
+
+	if debugKind == lowered {
+		loweredPkg.Print()
+		fmt.Println()
+		return 0
+	}
+
+	machine, err := codegen.NewTargetMachine(target, opts.cpu, opts.features, opts.optLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
 
-	loweredPkg, diags := lowerer.Lower(pkg, diags)
+	module, diags := codegen.Compile(loweredPkg, target, opts.debugInfo, diags)
 
-	if len(diags) != 0 {
-		for _, diag := range diags {
+	if reporter.report(diags) {
+		return 1
+	}
+
+	if opts.optLevel != codegen.O0 {
+		err := codegen.Optimise(module, machine, opts.optLevel)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if debugKind == llir {
+		fmt.Println(module.String())
+	}
+
+	if opts.run {
+		return runCode(module, opts.optLevel)
+	}
+
+	if err := outputCode(module, machine, target, opts.emit); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Prints human-readable diagnostics to stderr as each stage of
+// compilation reports them. Other formats can't be written in parts,
+// so they are written once compilation finishes.
+type reporter struct {
+	printed     int
+	format      diagnostics.Format
+	diagnostics diagnostics.Manager
+	levels      diagnostics.Levels
+}
+
+// Prints the diagnostics which haven't already been printed, and returns
+// whether there are any errors. Warnings don't stop compilation, unless
+// they are reported as errors.
+func (r *reporter) report(diags diagnostics.Manager) bool {
+	diags = diags.WithLevels(r.levels)
+	r.diagnostics = diags
+	if r.format == diagnostics.HumanFormat {
+		for _, diag := range diags[r.printed:] {
 			diag.Print()
 		}
-		return
+		r.printed = len(diags)
+	}
+	return diags.HasErrors()
+}
+
+func (r *reporter) flush() error {
+	if r.format == diagnostics.HumanFormat {
+		return nil
+	}
+	return diagnostics.Write(os.Stderr, r.format, r.diagnostics, false)
+}
+
+func runCode(module llvm.Module, level codegen.OptLevel) int {
+	jit, err := codegen.NewJit(module, level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer jit.Dispose()
+
+	if err := jit.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// The C compiler handles the calling convention itself,
+// so the ABI lowering pass is skipped
+func compileC(pkg *typeIr.Package, debugKind debugKind, diags diagnostics.Manager, reporter *reporter) int {
+	loweredPkg, diags := lowerer.LowerWithoutAbi(pkg, diags)
+
+	if reporter.report(diags) {
+		return 1
 	}
 
 	if debugKind == lowered {
 		loweredPkg.Print()
 		fmt.Println()
-		return
+		return 0
 	}
 
-	module := codegen.Compile(loweredPkg)
+	code, diags := cbackend.Compile(loweredPkg, diags)
+
+	if reporter.report(diags) {
+		return 1
+	}
 
 	if debugKind == llir {
-		fmt.Println(module.String())
+		fmt.Println(code)
 	}
 
-	outputCode(module)
+	if err := os.WriteFile("out.c", []byte(code), os.ModePerm); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
 
-func outputCode(module llvm.Module) {
-	triple := llvm.DefaultTargetTriple()
-	llvm.InitializeAllTargetInfos()
-	llvm.InitializeAllTargets()
-	llvm.InitializeAllTargetMCs()
-	llvm.InitializeAllAsmParsers()
-	llvm.InitializeAllAsmPrinters()
-	target, err := llvm.GetTargetFromTriple(triple)
+func outputCode(module llvm.Module, machine llvm.TargetMachine, target types.TargetInfo, emit emitKind) error {
+	switch emit {
+	case emitBitcode:
+		return os.WriteFile("out.bc", codegen.EmitBitcode(module, machine), os.ModePerm)
+	case emitLlvmIr:
+		return os.WriteFile("out.ll", []byte(codegen.EmitIr(module, machine)), os.ModePerm)
+	}
+
+	object, err := codegen.EmitObject(module, machine)
 	if err != nil {
-		log.Fatal(err)
-	}
-	cpu := "generic"
-	features := ""
-	machine := target.CreateTargetMachine(
-		triple,
-		cpu,
-		features,
-		llvm.CodeGenLevelDefault,
-		llvm.RelocPIC,
-		llvm.CodeModelDefault,
-	)
-	module.SetTarget(triple)
-
-	buffer, err := machine.EmitToMemoryBuffer(module, llvm.ObjectFile)
+		return err
+	}
+	if err := os.WriteFile("out.o", object, os.ModePerm); err != nil {
+		return err
+	}
+
+	switch emit {
+	case emitStaticLib:
+		return archive("out.o", "out.a")
+	case emitSharedLib:
+		if target.IsWasm() {
+			return fmt.Errorf("Shared libraries are not supported for WebAssembly")
+		}
+		return linkShared("out.o", sharedLibName(target))
+	}
+
+	// WebAssembly objects have to be linked into a module before they can be run
+	if target.IsWasm() {
+		return linkWasm("out.o", "out.wasm", target)
+	}
+	return nil
+}
+
+func linkWasm(object, output string, target types.TargetInfo) error {
+	linker, err := exec.LookPath("wasm-ld")
 	if err != nil {
-		fmt.Println(err)
-		return
+		return fmt.Errorf("Cannot find wasm-ld, which is needed to link WebAssembly modules")
+	}
+
+	// External functions are provided by the host, so they are left as
+	// imports. Those without `@import_module` are imported from `env`.
+	args := []string{object, "-o", output, "--allow-undefined"}
+	// Only WASI has a `_start` entry point, so other modules export
+	// their public functions for the host to call instead
+	if !target.IsWasi() {
+		args = append(args, "--no-entry", "--export-dynamic")
+	}
+
+	linkOutput, err := exec.Command(linker, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to link WebAssembly module: %s", linkOutput)
+	}
+	return nil
+}
+
+// Bundles an object file into a static library, which C compilers can link
+func archive(object, output string) error {
+	archiver, err := exec.LookPath("llvm-ar")
+	if err != nil {
+		archiver, err = exec.LookPath("ar")
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot find ar or llvm-ar, which are needed to create static libraries")
+	}
+
+	// Archives are added to rather than replaced, so any old one is removed first
+	os.Remove(output)
+	archiveOutput, err := exec.Command(archiver, "rcs", output, object).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to create static library: %s", archiveOutput)
+	}
+	return nil
+}
+
+// Links an object file into a shared library. Only `pub`
+// functions are exported from it.
+func linkShared(object, output string) error {
+	linker, err := exec.LookPath("cc")
+	if err != nil {
+		return fmt.Errorf("Cannot find cc, which is needed to link shared libraries")
+	}
+
+	linkOutput, err := exec.Command(linker, "-shared", object, "-o", output).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Failed to link shared library: %s", linkOutput)
+	}
+	return nil
+}
+
+func sharedLibName(target types.TargetInfo) string {
+	switch {
+	case strings.Contains(target.Triple, "windows"):
+		return "out.dll"
+	case strings.Contains(target.Triple, "apple"), strings.Contains(target.Triple, "darwin"):
+		return "out.dylib"
+	default:
+		return "out.so"
 	}
-	os.WriteFile("out.o", buffer.Bytes(), os.ModePerm)
 }
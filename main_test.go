@@ -0,0 +1,206 @@
+package main
+
+import (
+	"debug/elf"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const librarySrc = `fn helper(a, b: i32): i32 { return a + b }
+pub fn add(a, b: i32): i32 { return helper(a, b) }`
+
+// Compiles a library with `--emit` set to the given kind, in a temporary
+// directory which is returned
+func emitLibrary(t *testing.T, kind string) string {
+	t.Helper()
+	return compileIn(t, librarySrc, "--emit="+kind)
+}
+
+// Compiles a program with the given flags, in a temporary directory
+// which is returned
+func compileIn(t *testing.T, src string, flags ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.lb")
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Output files are written to the working directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	opts, err := parseArgs(append([]string{file}, flags...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitCode := compile(opts, &reporter{}); exitCode != 0 {
+		t.Fatalf("Expected compilation to succeed, got exit code %d", exitCode)
+	}
+	return dir
+}
+
+func requireLinux(t *testing.T) string {
+	t.Helper()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("Only ELF output is checked")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("No C compiler found")
+	}
+	return cc
+}
+
+func TestEmitObject(t *testing.T) {
+	requireLinux(t)
+	dir := emitLibrary(t, "obj")
+
+	file, err := elf.Open(filepath.Join(dir, "out.o"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	symbols, err := file.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visibility := map[string]elf.SymVis{}
+	for _, symbol := range symbols {
+		visibility[symbol.Name] = elf.ST_VISIBILITY(symbol.Other)
+	}
+	if vis, ok := visibility["add"]; !ok || vis != elf.STV_DEFAULT {
+		t.Errorf("Expected `add` to be exported, got %v", vis)
+	}
+	if vis, ok := visibility["helper"]; !ok || vis != elf.STV_HIDDEN {
+		t.Errorf("Expected `helper` to be hidden, got %v", vis)
+	}
+}
+
+func TestEmitStaticLib(t *testing.T) {
+	cc := requireLinux(t)
+	dir := emitLibrary(t, "staticlib")
+
+	cSrc := `#include <stdio.h>
+#include <stdint.h>
+int32_t add(int32_t, int32_t);
+int main(void) { printf("%d\n", add(2, 3)); return 0; }`
+	cPath := filepath.Join(dir, "main.c")
+	exePath := filepath.Join(dir, "main")
+	if err := os.WriteFile(cPath, []byte(cSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command(cc, cPath, filepath.Join(dir, "out.a"), "-o", exePath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to link static library: %s\n%s", err, output)
+	}
+	output, err = exec.Command(exePath).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "5\n" {
+		t.Errorf("Expected output %q, got %q", "5\n", output)
+	}
+}
+
+func TestEmitSharedLib(t *testing.T) {
+	requireLinux(t)
+	dir := emitLibrary(t, "sharedlib")
+
+	file, err := elf.Open(filepath.Join(dir, "out.so"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	symbols, err := file.DynamicSymbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, symbol := range symbols {
+		names = append(names, symbol.Name)
+	}
+	if !slices.Contains(names, "add") {
+		t.Errorf("Expected `add` to be exported, got %v", names)
+	}
+	if slices.Contains(names, "helper") {
+		t.Errorf("Expected `helper` not to be exported, got %v", names)
+	}
+}
+
+func TestEmitBitcode(t *testing.T) {
+	dir := emitLibrary(t, "bc")
+
+	bitcode, err := os.ReadFile(filepath.Join(dir, "out.bc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(bitcode), "BC\xc0\xde") {
+		t.Errorf("Expected LLVM bitcode, got %q", bitcode[:min(len(bitcode), 4)])
+	}
+}
+
+func TestEmitLlvmIr(t *testing.T) {
+	dir := emitLibrary(t, "ll")
+
+	ir, err := os.ReadFile(filepath.Join(dir, "out.ll"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ir), "define i32 @add(") {
+		t.Errorf("Expected `add` to be defined, got:\n%s", ir)
+	}
+	if !strings.Contains(string(ir), "define hidden i32 @helper(") {
+		t.Errorf("Expected `helper` to be hidden, got:\n%s", ir)
+	}
+}
+
+func TestLinkWasm(t *testing.T) {
+	if _, err := exec.LookPath("wasm-ld"); err != nil {
+		t.Skip("wasm-ld not found")
+	}
+
+	tests := []struct {
+		target string
+		src    string
+		// Names which should be imported or exported by the module
+		names []string
+	}{
+		{"wasm32-wasi", "@extern\nfn print(s: string)\n\nprint(\"Hello\")", []string{"_start", "fd_write"}},
+		{"wasm64-wasi", "@extern\nfn print(s: string)\n\nprint(\"Hello\")", []string{"_start", "fd_write"}},
+		{"wasm32-unknown-unknown", "@extern\nfn log(value: i32)\n\npub fn add(a, b: i32): i32 { log(a); return a + b }", []string{"add", "env", "log"}},
+	}
+
+	for _, test := range tests {
+		dir := compileIn(t, test.src, "--target="+test.target)
+
+		module, err := os.ReadFile(filepath.Join(dir, "out.wasm"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(module), "\x00asm") {
+			t.Errorf("%s: Expected a WebAssembly module", test.target)
+		}
+		for _, name := range test.names {
+			if !strings.Contains(string(module), name) {
+				t.Errorf("%s: Expected the module to contain %q", test.target, name)
+			}
+		}
+	}
+}
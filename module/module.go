@@ -3,6 +3,7 @@ package module
 import (
 	"os"
 	"path"
+	"slices"
 	"strings"
 
 	"github.com/gearsdatapacks/libra/diagnostics"
@@ -15,41 +16,73 @@ import (
 type File struct {
 	Path string
 	Ast  *ast.Program
+	// The parts of the file in which warnings are allowed or denied
+	Scopes []diagnostics.Scope
 }
 
-func loadFile(path string) (*File, diagnostics.Manager) {
+// The contents of source files which have been loaded into memory, such
+// as files open in an editor, keyed by path. These are used in place of
+// the files on disk.
+type Overlay map[string]string
+
+type loader struct {
+	overlay Overlay
+	fetched map[string]*Module
+}
+
+func (l *loader) sourceFile(path string) *text.SourceFile {
+	if contents, ok := l.overlay[path]; ok {
+		return text.NewFile(path, contents)
+	}
+	return text.LoadFile(path)
+}
+
+func (l *loader) loadFile(path string) (*File, diagnostics.Manager) {
 	file := &File{Path: path}
-	l := lexer.New(text.LoadFile(path))
-	tokens := l.Tokenise()
-	if len(l.Diagnostics) != 0 {
-		return file, l.Diagnostics
+	lex := lexer.New(l.sourceFile(path))
+	tokens := lex.Tokenise()
+	if len(lex.Diagnostics) != 0 {
+		return file, lex.Diagnostics
 	}
 
-	p := parser.New(tokens, l.Diagnostics)
+	p := parser.New(tokens, lex.Diagnostics)
 	file.Ast = p.Parse()
+	file.Scopes = p.Scopes
 	return file, p.Diagnostics
 }
 
 var moduleId uint = 0
 
-func loadModule(modPath string) (*Module, diagnostics.Manager) {
+func (l *loader) loadModule(modPath string) (*Module, diagnostics.Manager) {
 	dir, err := os.ReadDir(modPath)
-	if err != nil {
+	if err != nil && len(l.overlay) == 0 {
 		panic(err)
 	}
 
-	files := []File{}
-	diagnostics := diagnostics.Manager{}
+	paths := []string{}
 	for _, entry := range dir {
 		if entry.IsDir() {
 			continue
 		}
 		if strings.HasSuffix(entry.Name(), ".lb") {
-			file, diags := loadFile(path.Join(modPath, entry.Name()))
-			files = append(files, *file)
-			diagnostics = append(diagnostics, diags...)
+			paths = append(paths, path.Join(modPath, entry.Name()))
 		}
 	}
+	// Files which haven't been saved yet only exist in the overlay
+	for filePath := range l.overlay {
+		if path.Dir(filePath) == modPath && !slices.Contains(paths, filePath) {
+			paths = append(paths, filePath)
+		}
+	}
+	slices.Sort(paths)
+
+	files := []File{}
+	diagnostics := diagnostics.Manager{}
+	for _, filePath := range paths {
+		file, diags := l.loadFile(filePath)
+		files = append(files, *file)
+		diagnostics = append(diagnostics, diags...)
+	}
 
 	_, name := path.Split(modPath)
 	moduleId++
@@ -72,19 +105,53 @@ type Module struct {
 	Imported map[string]*Module
 }
 
-var fetchedModules = map[string]*Module{}
+// The scopes set by `@allow` and `@deny` attributes in the module and
+// all the modules it imports
+func (m *Module) DiagnosticScopes() []diagnostics.Scope {
+	return m.diagnosticScopes(map[*Module]bool{})
+}
+
+func (m *Module) diagnosticScopes(visited map[*Module]bool) []diagnostics.Scope {
+	if visited[m] {
+		return nil
+	}
+	visited[m] = true
+
+	scopes := []diagnostics.Scope{}
+	for _, file := range m.Files {
+		scopes = append(scopes, file.Scopes...)
+	}
+	for _, imported := range m.Imported {
+		scopes = append(scopes, imported.diagnosticScopes(visited)...)
+	}
+	return scopes
+}
+
+var defaultLoader = &loader{fetched: map[string]*Module{}}
 
 func Load(filePath string) (*Module, diagnostics.Manager) {
+	return defaultLoader.load(filePath)
+}
+
+// Loads a module, using the contents of the overlay instead of the
+// files on disk where possible. Modules are always loaded from scratch,
+// so that changes to the overlay are picked up.
+func LoadWithOverlay(filePath string, overlay Overlay) (*Module, diagnostics.Manager) {
+	l := &loader{overlay: overlay, fetched: map[string]*Module{}}
+	return l.load(filePath)
+}
+
+func (l *loader) load(filePath string) (*Module, diagnostics.Manager) {
 	modPath := filePath
-	if !isDir(modPath) {
+	if !l.isDir(modPath) {
 		modPath = path.Dir(modPath)
 	}
-	if fetched, ok := fetchedModules[modPath]; ok {
+	if fetched, ok := l.fetched[modPath]; ok {
 		return fetched, diagnostics.Manager{}
 	}
 
-	mod, diagnostics := loadModule(modPath)
-	fetchedModules[modPath] = mod
+	mod, diagnostics := l.loadModule(modPath)
+	l.fetched[modPath] = mod
 
 	if len(diagnostics) != 0 {
 		return mod, diagnostics
@@ -94,7 +161,11 @@ func Load(filePath string) (*Module, diagnostics.Manager) {
 		for _, stmt := range file.Ast.Statements {
 			if importStmt, ok := stmt.(*ast.ImportStatement); ok {
 				importedPath := path.Join(modPath, importStmt.Module.ExtraValue)
-				imported, diags := Load(importedPath)
+				if !l.exists(importedPath) {
+					diagnostics = append(diagnostics, *l.moduleUndefined(modPath, importStmt))
+					continue
+				}
+				imported, diags := l.load(importedPath)
 				diagnostics = append(diagnostics, diags...)
 				mod.Imported[importStmt.Module.ExtraValue] = imported
 			}
@@ -104,7 +175,35 @@ func Load(filePath string) (*Module, diagnostics.Manager) {
 	return mod, diagnostics
 }
 
-func isDir(path string) bool {
+func (l *loader) exists(filePath string) bool {
+	for overlayPath := range l.overlay {
+		if overlayPath == filePath || path.Dir(overlayPath) == filePath {
+			return true
+		}
+	}
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+// Reports an import of a module which doesn't exist, suggesting
+// modules in the same directory which may have been meant
+func (l *loader) moduleUndefined(modPath string, importStmt *ast.ImportStatement) *diagnostics.Diagnostic {
+	importPath := importStmt.Module.ExtraValue
+	dir, _ := path.Split(importPath)
+	candidates := []string{}
+	entries, _ := os.ReadDir(path.Join(modPath, dir))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			candidates = append(candidates, dir+entry.Name())
+		}
+	}
+	return diagnostics.ModuleUndefined(importStmt.Module.Location, importPath, candidates)
+}
+
+func (l *loader) isDir(path string) bool {
+	if _, ok := l.overlay[path]; ok {
+		return false
+	}
 	info, err := os.Stat(path)
 	if err != nil {
 		panic(err)
@@ -18,8 +18,16 @@ type Expression interface {
 	expressionNode()
 }
 
+// A comment in the source code. Comments aren't part of the syntax
+// tree, but are kept so that they can be preserved by the formatter.
+type Comment struct {
+	Location text.Location
+	Text     string
+}
+
 type Program struct {
 	Statements []Statement
+	Comments   []Comment
 }
 
 // func (p *Program) Tokens() []token.Token {
@@ -36,3 +36,20 @@ type ExpressionAttribute struct {
 func (e *ExpressionAttribute) GetName() string {
 	return e.Name
 }
+
+// Sets how the warnings with the given codes are reported in the
+// statement it is attached to, such as `@allow(W0003)`
+type LevelAttribute struct {
+	Location text.Location
+	Name     string
+	Codes    []DiagnosticCode
+}
+
+func (l *LevelAttribute) GetName() string {
+	return l.Name
+}
+
+type DiagnosticCode struct {
+	Location text.Location
+	Code     string
+}
@@ -253,6 +253,23 @@ func (o *OptionType) Print(node *printer.Node) {
 		Node(o.Operand)
 }
 
+type BuilderType struct {
+	expression
+	Location text.Location
+	Operand  Expression
+}
+
+func (b *BuilderType) GetLocation() text.Location {
+	return b.Location
+}
+
+func (b *BuilderType) Print(node *printer.Node) {
+	node.
+		Text("%sBUILDER_TYPE", node.Colour(colour.NodeName)).
+		Location(b).
+		Node(b.Operand)
+}
+
 type DerefExpression struct {
 	expression
 	Operand Expression
@@ -632,10 +649,11 @@ func (w *WhileLoop) GetLocation() text.Location {
 
 type ForLoop struct {
 	expression
-	Location text.Location
-	Variable string
-	Iterator Expression
-	Body     *Block
+	Location         text.Location
+	Variable         string
+	VariableLocation text.Location
+	Iterator         Expression
+	Body             *Block
 }
 
 func (fl *ForLoop) Print(node *printer.Node) {
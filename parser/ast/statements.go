@@ -106,6 +106,8 @@ type FunctionDeclaration struct {
 	Body         *Block
 	Implements   *string
 	Extern       *string
+	ImportModule *string
+	ImportName   *string
 	Attributes   DeclarationAttributes
 }
 
@@ -146,6 +148,24 @@ func (fd *FunctionDeclaration) Print(node *printer.Node) {
 		)
 	}
 
+	if fd.ImportModule != nil {
+		node.Text(
+			" %simport_module %s%s",
+			node.Colour(colour.Attribute),
+			node.Colour(colour.Name),
+			*fd.ImportModule,
+		)
+	}
+
+	if fd.ImportName != nil {
+		node.Text(
+			" %simport_name %s%s",
+			node.Colour(colour.Attribute),
+			node.Colour(colour.Name),
+			*fd.ImportName,
+		)
+	}
+
 	node.
 		Location(fd).
 		OptionalNode(fd.MethodOf)
@@ -174,6 +194,12 @@ func (f *FunctionDeclaration) tryAddAttribute(attribute Attribute) bool {
 		}
 		f.Extern = &text
 		return true
+	case "import_module":
+		f.ImportModule = &attribute.(*TextAttribute).Text
+		return true
+	case "import_name":
+		f.ImportName = &attribute.(*TextAttribute).Text
+		return true
 	default:
 		return f.Attributes.tryAddAttribute(attribute)
 	}
@@ -246,6 +272,7 @@ type TypeDeclaration struct {
 	Name       string
 	Type       Expression
 	Tag        Expression
+	Generators []Expression
 	Attributes DeclarationAttributes
 }
 
@@ -267,6 +294,7 @@ func (t *TypeDeclaration) Print(node *printer.Node) {
 			node.Node(t.Tag)
 		}, node.Colour(colour.Attribute))
 	}
+	printGenerators(node, t.Generators)
 }
 
 func (t *TypeDeclaration) GetLocation() text.Location {
@@ -282,6 +310,10 @@ func (t *TypeDeclaration) tryAddAttribute(attribute Attribute) bool {
 		// TODO: Add a proper error message for this
 		return false
 	}
+	if attribute.GetName() == "gen" {
+		t.Generators = append(t.Generators, attribute.(*ExpressionAttribute).Expression)
+		return true
+	}
 
 	return t.Attributes.tryAddAttribute(attribute)
 }
@@ -289,6 +321,7 @@ func (t *TypeDeclaration) tryAddAttribute(attribute Attribute) bool {
 type StructField struct {
 	Location text.Location
 	Pub      bool
+	Builder  bool
 	TypeOrIdent
 }
 
@@ -296,6 +329,7 @@ func (s StructField) Print(node *printer.Node) {
 	node.
 		Text("%sSTRUCT_FIELD", node.Colour(colour.NodeName)).
 		TextIf(s.Pub, " %spub", node.Colour(colour.Attribute)).
+		TextIf(s.Builder, " %sbuilder", node.Colour(colour.Attribute)).
 		Node(&s.TypeOrIdent)
 }
 
@@ -306,6 +340,7 @@ type StructDeclaration struct {
 	Name         string
 	Body         []StructField
 	Tag          Expression
+	Generators   []Expression
 	Attributes   DeclarationAttributes
 }
 
@@ -331,6 +366,7 @@ func (s *StructDeclaration) Print(node *printer.Node) {
 			node.Node(s.Tag)
 		}, node.Colour(colour.Attribute))
 	}
+	printGenerators(node, s.Generators)
 }
 
 func (s *StructDeclaration) GetLocation() text.Location {
@@ -338,9 +374,13 @@ func (s *StructDeclaration) GetLocation() text.Location {
 }
 
 func (s *StructDeclaration) tryAddAttribute(attribute Attribute) bool {
-	if attribute.GetName() == "tag" {
+	switch attribute.GetName() {
+	case "tag":
 		s.Tag = attribute.(*ExpressionAttribute).Expression
 		return true
+	case "gen":
+		s.Generators = append(s.Generators, attribute.(*ExpressionAttribute).Expression)
+		return true
 	}
 
 	return s.Attributes.tryAddAttribute(attribute)
@@ -633,6 +673,14 @@ func TryAddAttribute(stmt Statement, attribute Attribute) bool {
 	return false
 }
 
+func printGenerators(node *printer.Node, generators []Expression) {
+	for _, generator := range generators {
+		node.FakeNode("%sgen", func(node *printer.Node) {
+			node.Node(generator)
+		}, node.Colour(colour.Attribute))
+	}
+}
+
 type DeclarationAttributes struct {
 	TodoMessage       *string
 	Documentation     string
@@ -30,14 +30,18 @@ func (p *parser) parseOptionalIdentAttribute() (ast.Attribute, *diagnostics.Diag
 	}, nil
 }
 
-// func (p *parser) parseExpressionAttribute() (ast.Attribute, *diagnostics.Diagnostic) {
-// 	tok := p.consume()
-// 	expression, err := p.parseExpression()
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	return &ast.ExpressionAttribute{Token: tok, Expression: expression}, nil
-// }
+func (p *parser) parseExpressionAttribute() (ast.Attribute, *diagnostics.Diagnostic) {
+	tok := p.consume()
+	expression, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ExpressionAttribute{
+		Location:   tok.Location,
+		Name:       tok.ExtraValue,
+		Expression: expression,
+	}, nil
+}
 
 func (p *parser) parseTypeAttribute() (ast.Attribute, *diagnostics.Diagnostic) {
 	tok := p.consume()
@@ -69,3 +73,25 @@ func (p *parser) parseAttributeWithOptionalBody() (ast.Attribute, *diagnostics.D
 		Text:     text,
 	}, nil
 }
+
+func (p *parser) parseLevelAttribute() (ast.Attribute, *diagnostics.Diagnostic) {
+	tok := p.consume()
+	p.expect(token.LEFT_PAREN)
+
+	codes := []ast.DiagnosticCode{}
+	for p.next().Kind == token.IDENTIFIER {
+		code := p.consume()
+		codes = append(codes, ast.DiagnosticCode{Location: code.Location, Code: code.Value})
+		if p.next().Kind != token.COMMA {
+			break
+		}
+		p.consume()
+	}
+	p.expect(token.RIGHT_PAREN)
+
+	return &ast.LevelAttribute{
+		Location: tok.Location,
+		Name:     tok.ExtraValue,
+		Codes:    codes,
+	}, nil
+}
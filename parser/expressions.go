@@ -164,6 +164,24 @@ func (p *parser) parseOptionType() (ast.Expression, *diagnostics.Diagnostic) {
 	}, nil
 }
 
+func (p *parser) parseBuilderType() (ast.Expression, *diagnostics.Diagnostic) {
+	// Outside of types, `~` is the bitwise not operator
+	if !p.typeExpr {
+		return p.parsePrefixExpression()
+	}
+
+	location := p.consume().Location
+	operand, err := p.parseSubExpression(Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.BuilderType{
+		Location: location,
+		Operand:  operand,
+	}, nil
+}
+
 func (p *parser) parseFunctionCall(callee ast.Expression) (ast.Expression, *diagnostics.Diagnostic) {
 	p.consume()
 	arguments := parseDelimExprList(p, token.RIGHT_PAREN, p.parseExpression)
@@ -488,8 +506,10 @@ func (p *parser) parseFunctionExpression() (ast.Expression, *diagnostics.Diagnos
 		return nil, err
 	}
 
+	// Function types don't have bodies, so a brace after a function type
+	// in a type annotation, like `fn f(): fn(): i32 {`, starts the next block
 	var body *ast.Block
-	if p.canContinue() && p.next().Kind == token.LEFT_BRACE {
+	if !p.typeExpr && p.canContinue() && p.next().Kind == token.LEFT_BRACE {
 		var err *diagnostics.Diagnostic
 		body, err = p.parseBlock(true)
 		if err != nil {
@@ -590,7 +610,7 @@ func (p *parser) parseForLoop() (ast.Expression, *diagnostics.Diagnostic) {
 	location := p.consume().Location
 	defer p.exitScope(p.enterScope())
 
-	variable := p.declareIdentifier().Value
+	variable := p.declareIdentifier()
 	p.expectKeyword("in")
 
 	p.noBraces = true
@@ -609,9 +629,10 @@ func (p *parser) parseForLoop() (ast.Expression, *diagnostics.Diagnostic) {
 	}
 
 	return &ast.ForLoop{
-		Location: location,
-		Variable: variable,
-		Iterator: iterator,
-		Body:     body,
+		Location:         location,
+		Variable:         variable.Value,
+		VariableLocation: variable.Location,
+		Iterator:         iterator,
+		Body:             body,
 	}, nil
 }
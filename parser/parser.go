@@ -22,6 +22,8 @@ type parser struct {
 	typeExpr     bool
 	bracketLevel uint
 	Diagnostics  diagnostics.Manager
+	// The parts of the source in which warnings are allowed or denied
+	Scopes []diagnostics.Scope
 }
 
 func New(tokens []token.Token, diagnostics diagnostics.Manager) *parser {
@@ -43,7 +45,10 @@ func New(tokens []token.Token, diagnostics diagnostics.Manager) *parser {
 }
 
 func (p *parser) Parse() *ast.Program {
-	program := &ast.Program{Statements: []ast.Statement{}}
+	program := &ast.Program{
+		Statements: []ast.Statement{},
+		Comments:   p.comments(),
+	}
 
 	for !p.eof() {
 		pos := p.pos
@@ -70,6 +75,19 @@ func (p *parser) Parse() *ast.Program {
 	return program
 }
 
+func (p *parser) comments() []ast.Comment {
+	comments := []ast.Comment{}
+	for _, tok := range p.tokens {
+		if tok.Kind == token.COMMENT {
+			comments = append(comments, ast.Comment{
+				Location: tok.Location,
+				Text:     tok.Value,
+			})
+		}
+	}
+	return comments
+}
+
 type nudFn func() (ast.Expression, *diagnostics.Diagnostic)
 type ledFn func(ast.Expression) (ast.Expression, *diagnostics.Diagnostic)
 type lookupFn func(ast.Expression) (opInfo, bool)
@@ -252,11 +270,16 @@ func (p *parser) register() {
 
 	p.registerAttribute("tag", p.parseTypeAttribute)
 	p.registerAttribute("extern", p.parseOptionalIdentAttribute)
+	p.registerAttribute("import_module", p.parseIdentifierAttribute)
+	p.registerAttribute("import_name", p.parseIdentifierAttribute)
 	p.registerAttribute("impl", p.parseIdentifierAttribute)
 	p.registerAttribute("untagged", p.parseFlagAttribute)
 	p.registerAttribute("todo", p.parseAttributeWithOptionalBody)
 	p.registerAttribute("doc", p.parseAttributeWithOptionalBody)
 	p.registerAttribute("deprecated", p.parseAttributeWithOptionalBody)
+	p.registerAttribute("gen", p.parseExpressionAttribute)
+	p.registerAttribute("allow", p.parseLevelAttribute)
+	p.registerAttribute("deny", p.parseLevelAttribute)
 
 	// Literals
 	p.registerNudFn(token.INTEGER, p.parseInteger)
@@ -313,7 +336,7 @@ func (p *parser) register() {
 	p.registerNudFn(token.QUESTION, p.parseOptionType)
 	p.registerNudFn(token.STAR, p.parsePtrOrRef)
 	p.registerNudFn(token.AMPERSAND, p.parsePtrOrRef)
-	p.registerNudFn(token.TILDE, p.parsePrefixExpression)
+	p.registerNudFn(token.TILDE, p.parseBuilderType)
 
 	// Assignment
 	p.registerLedOp(token.EQUALS, Assignment, p.parseAssignmentExpression, true)
@@ -526,7 +549,7 @@ func (p *parser) expectKeyword(keyword string) token.Token {
 	}
 
 	if p.next().Kind == token.IDENTIFIER && p.next().Value == keyword {
-		p.Diagnostics.ReportMany(diagnostics.KeywordOverwritten(p.next().Location, keyword, p.identifiers[keyword]))
+		p.Diagnostics.Report(diagnostics.KeywordOverwritten(p.next().Location, keyword, p.identifiers[keyword]))
 		return p.consume()
 	}
 
@@ -218,6 +218,7 @@ func TestTypeExpressions(t *testing.T) {
 		"*string", "*mut i32",
 		"?f32", "?(string[])",
 		"!u8", "!{u32: string}",
+		"type Built = ~Foo", "type Built = ~mod.Bar",
 
 		"?({string: Value}[10])",
 		"!Foo[]",
@@ -283,5 +284,7 @@ func TestParserDiagnostics(t *testing.T) {
 		"explicit fn func() {}",
 		"@nonexistent\nfn attributed() {}",
 		"@tag FunctionTag\nfn tagged() {}",
+		"@allow(W0030)\nfn allowed() {}",
+		"@deny W0003\nfn denied() {}",
 	)
 }
@@ -26,6 +26,7 @@ func TestFunctionDeclaration(t *testing.T) {
 		"fn (mut foo) bar(): foo { this }",
 		"fn add(a = 1, mut b: i64 = 2): i64 { c }",
 		`fn foo()`,
+		"fn adder(a: i32): fn(i32): i32 { return fn(b: i32): i32 { a + b } }",
 	)
 }
 
@@ -70,6 +71,7 @@ func TestStructDeclaration(t *testing.T) {
 		"struct Empty {}",
 		"struct Rect { w, h: i32 }",
 		"struct Vec2{x:f32,y:f32,}",
+		"struct Averages { ~values: i32[], pub ~count, mean: f32 }",
 	)
 }
 
@@ -138,5 +140,9 @@ it will close this block.
 fn do_things() {}`,
 		`@extern
 fn external()`,
+		"@extern fd_close\n@import_module wasi_snapshot_preview1\nfn close(fd: i32): i32",
+		"@extern\n@import_name console_log\nfn log(value: i32)",
+		"@gen(derive_eq)\nstruct Point { x, y: i32 }",
+		"@gen derive_debug\n@gen derive_hash\ntype Id = i32",
 	)
 }
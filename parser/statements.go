@@ -8,7 +8,76 @@ import (
 )
 
 func (p *parser) parseTopLevelStatement() (ast.Statement, *diagnostics.Diagnostic) {
+	start := p.next().Location
+	attributes, levels := p.parseAttributes()
+
+	for _, kwd := range p.keywords {
+		if p.isKeyword(kwd.Name) {
+			stmt, err := kwd.Fn()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, attribute := range attributes {
+				if !ast.TryAddAttribute(stmt, attribute) {
+					p.Diagnostics.Report(diagnostics.CannotAttribute(stmt.GetLocation(), attribute.GetName()))
+				}
+			}
+			p.addScopes(start, levels)
+
+			return stmt, nil
+		}
+	}
+
+	return p.parseUnattributedStatement(start, attributes, levels)
+}
+
+func (p *parser) parseStatement() (ast.Statement, *diagnostics.Diagnostic) {
+	start := p.next().Location
+	attributes, levels := p.parseAttributes()
+	return p.parseUnattributedStatement(start, attributes, levels)
+}
+
+// Parses a statement which can't have attributes other than `@allow`
+// and `@deny`, which apply to any statement
+func (p *parser) parseUnattributedStatement(
+	start text.Location,
+	attributes []ast.Attribute,
+	levels []*ast.LevelAttribute,
+) (ast.Statement, *diagnostics.Diagnostic) {
+	var stmt ast.Statement
+	var err *diagnostics.Diagnostic
+	isKeyword := false
+
+	for _, kwd := range p.keywords {
+		if p.isKeyword(kwd.Name) {
+			if kwd.Kind == decl {
+				p.Diagnostics.Report(diagnostics.OnlyTopLevelStatement(p.next().Location, kwd.StmtName))
+			}
+			stmt, err = kwd.Fn()
+			isKeyword = true
+			break
+		}
+	}
+	if !isKeyword {
+		stmt, err = p.parseExpression()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attribute := range attributes {
+		p.Diagnostics.Report(diagnostics.CannotAttribute(stmt.GetLocation(), attribute.GetName()))
+	}
+	p.addScopes(start, levels)
+
+	return stmt, nil
+}
+
+func (p *parser) parseAttributes() ([]ast.Attribute, []*ast.LevelAttribute) {
 	attributes := []ast.Attribute{}
+	levels := []*ast.LevelAttribute{}
+
 	for p.next().Kind == token.ATTRIBUTE_NAME {
 		found := false
 		name := p.next().ExtraValue
@@ -18,6 +87,8 @@ func (p *parser) parseTopLevelStatement() (ast.Statement, *diagnostics.Diagnosti
 				if err != nil {
 					p.Diagnostics.Report(err)
 					p.consumeUntil(token.NEWLINE, token.SEMICOLON)
+				} else if level, ok := attribute.(*ast.LevelAttribute); ok {
+					levels = append(levels, level)
 				} else {
 					attributes = append(attributes, attribute)
 				}
@@ -32,37 +103,37 @@ func (p *parser) parseTopLevelStatement() (ast.Statement, *diagnostics.Diagnosti
 		}
 	}
 
-	for _, kwd := range p.keywords {
-		if p.isKeyword(kwd.Name) {
-			stmt, err := kwd.Fn()
-			if err != nil {
-				return nil, err
-			}
-
-			for _, attribute := range attributes {
-				if !ast.TryAddAttribute(stmt, attribute) {
-					p.Diagnostics.Report(diagnostics.CannotAttribute(stmt.GetLocation(), attribute.GetName()))
-				}
-			}
+	return attributes, levels
+}
 
-			return stmt, nil
-		}
+// Records the part of the source which the `@allow` and `@deny`
+// attributes of the statement just parsed apply to
+func (p *parser) addScopes(start text.Location, levels []*ast.LevelAttribute) {
+	if len(levels) == 0 {
+		return
 	}
 
-	return p.parseStatement()
-}
+	location := start.To(p.tokens[p.pos-1].Location)
+	for _, attribute := range levels {
+		level := diagnostics.Allow
+		if attribute.Name == "deny" {
+			level = diagnostics.Deny
+		}
 
-func (p *parser) parseStatement() (ast.Statement, *diagnostics.Diagnostic) {
-	for _, kwd := range p.keywords {
-		if p.isKeyword(kwd.Name) {
-			if kwd.Kind == decl {
-				p.Diagnostics.Report(diagnostics.OnlyTopLevelStatement(p.next().Location, kwd.StmtName))
+		for _, code := range attribute.Codes {
+			if !diagnostics.IsWarningCode(code.Code) {
+				p.Diagnostics.Report(diagnostics.UnknownWarningCode(code.Location, code.Code))
+				continue
 			}
-			return kwd.Fn()
+
+			p.Scopes = append(p.Scopes, diagnostics.Scope{
+				Location:  location,
+				Attribute: attribute.Location,
+				Code:      code.Code,
+				Level:     level,
+			})
 		}
 	}
-
-	return p.parseExpression()
 }
 
 func (p *parser) parseVariableDeclaration() (ast.Statement, *diagnostics.Diagnostic) {
@@ -222,7 +293,7 @@ func (p *parser) parseFunctionDeclaration() (ast.Statement, *diagnostics.Diagnos
 	if len(params) > 0 {
 		lastParam := params[len(params)-1]
 		if lastParam.TypeOrIdent.Type == nil && lastParam.Default == nil {
-			p.Diagnostics.ReportMany(diagnostics.LastParameterMustHaveType(lastParam.TypeOrIdent.Location, name.Location))
+			p.Diagnostics.Report(diagnostics.LastParameterMustHaveType(lastParam.TypeOrIdent.Location, name.Location))
 		}
 	}
 
@@ -322,18 +393,32 @@ func (p *parser) parseStructField() (*ast.StructField, *diagnostics.Diagnostic)
 	if pub {
 		location = p.consume().Location
 	}
+
+	// `~name` marks a builder-only field, as opposed to a field with a builder type
+	p.consumeNewlines()
+	builder := p.next().Kind == token.TILDE &&
+		p.peek(1).Kind == token.IDENTIFIER &&
+		(p.peek(2).Kind == token.COLON || p.peek(2).Kind == token.COMMA)
+	if builder {
+		tilde := p.consume()
+		if !pub {
+			location = tilde.Location
+		}
+	}
+
 	typeOrIdent, err := p.parseTypeOrIdent(false)
 	if err != nil {
 		return nil, err
 	}
 
-	if !pub {
+	if !pub && !builder {
 		location = typeOrIdent.Location
 	}
 
 	return &ast.StructField{
 		Location:    location,
 		Pub:         pub,
+		Builder:     builder,
 		TypeOrIdent: *typeOrIdent,
 	}, nil
 }
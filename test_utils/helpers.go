@@ -6,7 +6,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/gearsdatapacks/libra/codegen"
+	cbackend "github.com/gearsdatapacks/libra/c_backend"
 	"github.com/gearsdatapacks/libra/diagnostics"
 	"github.com/gearsdatapacks/libra/lexer"
 	"github.com/gearsdatapacks/libra/lowerer"
@@ -16,10 +16,21 @@ import (
 	"github.com/gearsdatapacks/libra/text"
 	typechecker "github.com/gearsdatapacks/libra/type_checker"
 	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
 	"github.com/gkampitakis/go-snaps/snaps"
-	"tinygo.org/x/go-llvm"
 )
 
+// Programs which compile successfully can still produce warnings,
+// so only errors cause a test to fail
+func AssertNoErrors(t *testing.T, diags []diagnostics.Diagnostic) {
+	t.Helper()
+
+	for _, diag := range diags {
+		diag.Print()
+	}
+	Assert(t, !diagnostics.Manager(diags).HasErrors(), "Expected no errors")
+}
+
 func getAst(t *testing.T, input string) (*ast.Program, []diagnostics.Diagnostic) {
 	t.Helper()
 
@@ -32,7 +43,7 @@ func getAst(t *testing.T, input string) (*ast.Program, []diagnostics.Diagnostic)
 	return program, p.Diagnostics
 }
 
-func getIr(t *testing.T, input string) (*ir.Package, []diagnostics.Diagnostic) {
+func getIr(t *testing.T, options typechecker.Options, input string) (*ir.Package, []diagnostics.Diagnostic) {
 	t.Helper()
 
 	l := lexer.New(text.NewFile("test.lb", input))
@@ -40,10 +51,15 @@ func getIr(t *testing.T, input string) (*ir.Package, []diagnostics.Diagnostic) {
 
 	p := parser.New(tokens, l.Diagnostics)
 	program := p.Parse()
-	return typechecker.TypeCheck(fakeModule(program), p.Diagnostics)
+	return typechecker.TypeCheck(fakeModule(program), options, p.Diagnostics)
 }
 
-func getLowered(t *testing.T, input string) (*ir.LoweredPackage, []diagnostics.Diagnostic) {
+// Tests are compiled for the same target no matter which
+// machine they run on, so that their output is consistent
+const DefaultTarget = "x86_64-unknown-linux-gnu"
+
+// Lowers a program for a target, returning the diagnostics reported up to that point
+func Lower(t *testing.T, target, input string) (*ir.LoweredPackage, []diagnostics.Diagnostic) {
 	t.Helper()
 
 	l := lexer.New(text.NewFile("test.lb", input))
@@ -51,11 +67,20 @@ func getLowered(t *testing.T, input string) (*ir.LoweredPackage, []diagnostics.D
 
 	p := parser.New(tokens, l.Diagnostics)
 	program := p.Parse()
-	pkg, diags := typechecker.TypeCheck(fakeModule(program), p.Diagnostics)
-	return lowerer.Lower(pkg, diags)
+	targetInfo := types.TargetFor(target)
+	pkg, diags := typechecker.TypeCheck(fakeModule(program), typechecker.Options{Target: targetInfo}, p.Diagnostics)
+	return lowerer.Lower(pkg, targetInfo, diags)
 }
 
-func getCode(t *testing.T, input string) llvm.Module {
+func getC(t *testing.T, input string) string {
+	t.Helper()
+
+	code, diags := getCWithDiagnostics(t, input)
+	AssertNoErrors(t, diags)
+	return code
+}
+
+func getCWithDiagnostics(t *testing.T, input string) (string, diagnostics.Manager) {
 	t.Helper()
 
 	l := lexer.New(text.NewFile("test.lb", input))
@@ -63,18 +88,11 @@ func getCode(t *testing.T, input string) llvm.Module {
 
 	p := parser.New(tokens, l.Diagnostics)
 	program := p.Parse()
-	pkg, diags := typechecker.TypeCheck(fakeModule(program), p.Diagnostics)
-	lowered, diags := lowerer.Lower(pkg, diags)
-
-	if len(diags) != 0 {
-		for _, diag := range diags {
-			diag.Print()
-		}
-	}
-	AssertEq(t, len(diags), 0,
-		fmt.Sprintf("Expected no diagnostics (got %d)", len(diags)))
+	pkg, diags := typechecker.TypeCheck(fakeModule(program), typechecker.Options{Target: types.TargetFor(DefaultTarget)}, p.Diagnostics)
+	lowered, diags := lowerer.LowerWithoutAbi(pkg, diags)
+	AssertNoErrors(t, diags)
 
-	return codegen.Compile(lowered)
+	return cbackend.Compile(lowered, diags)
 }
 
 func fakeModule(program *ast.Program) *module.Module {
@@ -96,7 +114,9 @@ func (t *namedSnapshot) Name() string {
 	return t.name
 }
 
-func matchSnap(t *testing.T, src, output string) {
+// Matches the output for a source program against a snapshot named
+// after the test being run
+func MatchSnap(t *testing.T, src, output string) {
 	t.Helper()
 
 	var name string
@@ -3,7 +3,13 @@ package testutils
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
+
+	typechecker "github.com/gearsdatapacks/libra/type_checker"
+	"github.com/gearsdatapacks/libra/type_checker/types"
 )
 
 func Assert(t *testing.T, condition bool, msg ...string) {
@@ -44,21 +50,22 @@ func MatchAstSnaps(t *testing.T, tests ...string) {
 		AssertEq(t, len(diags), 0,
 			fmt.Sprintf("Expected no diagnostics (got %d)", len(diags)))
 
-		matchSnap(t, src, program.String())
+		MatchSnap(t, src, program.String())
 	}
 }
 
 func MatchIrSnaps(t *testing.T, tests ...string) {
 	t.Helper()
+	MatchIrSnapsForTarget(t, DefaultTarget, tests...)
+}
+
+func MatchIrSnapsForTarget(t *testing.T, target string, tests ...string) {
+	t.Helper()
 
 	for _, src := range tests {
-		program, diags := getIr(t, src)
-		for _, diag := range diags {
-			diag.Print()
-		}
-		AssertEq(t, len(diags), 0,
-			fmt.Sprintf("Expected no diagnostics (got %d)", len(diags)))
-		matchSnap(t, src, program.String())
+		program, diags := getIr(t, typechecker.Options{Target: types.TargetFor(target)}, src)
+		AssertNoErrors(t, diags)
+		MatchSnap(t, src, program.String())
 	}
 }
 
@@ -72,21 +79,26 @@ func MatchParserErrorSnaps(t *testing.T, tests ...string) {
 			diag.WriteTo(&diags, false)
 		}
 
-		matchSnap(t, src, diags.String())
+		MatchSnap(t, src, diags.String())
 	}
 }
 
 func MatchTCErrorSnaps(t *testing.T, tests ...string) {
 	t.Helper()
+	MatchTCErrorSnapsWithOptions(t, typechecker.Options{Target: types.TargetFor(DefaultTarget)}, tests...)
+}
+
+func MatchTCErrorSnapsWithOptions(t *testing.T, options typechecker.Options, tests ...string) {
+	t.Helper()
 
 	for _, src := range tests {
-		_, diagnostics := getIr(t, src)
+		_, diagnostics := getIr(t, options, src)
 		var diags bytes.Buffer
 		for _, diag := range diagnostics {
 			diag.WriteTo(&diags, false)
 		}
 
-		matchSnap(t, src, diags.String())
+		MatchSnap(t, src, diags.String())
 	}
 }
 
@@ -94,35 +106,84 @@ func MatchLoweredSnaps(t *testing.T, tests ...string) {
 	t.Helper()
 
 	for _, src := range tests {
-		program, diags := getLowered(t, src)
-		for _, diag := range diags {
-			diag.Print()
-		}
-		AssertEq(t, len(diags), 0,
-			fmt.Sprintf("Expected no diagnostics (got %d)", len(diags)))
-		matchSnap(t, src, program.String())
+		program, diags := Lower(t, DefaultTarget, src)
+		AssertNoErrors(t, diags)
+		MatchSnap(t, src, program.String())
 	}
 }
 
 func MatchLowerErrors(t *testing.T, tests ...string) {
 	t.Helper()
+	MatchLowerErrorsForTarget(t, DefaultTarget, tests...)
+}
+
+func MatchLowerErrorsForTarget(t *testing.T, target string, tests ...string) {
+	t.Helper()
 
 	for _, src := range tests {
-		_, diagnostics := getLowered(t, src)
+		_, diagnostics := Lower(t, target, src)
 		var diags bytes.Buffer
 		for _, diag := range diagnostics {
 			diag.WriteTo(&diags, false)
 		}
 
-		matchSnap(t, src, diags.String())
+		MatchSnap(t, src, diags.String())
 	}
 }
 
-func MatchCodegenSnaps(t *testing.T, tests ...string) {
+func MatchCSnaps(t *testing.T, tests ...string) {
 	t.Helper()
 
 	for _, src := range tests {
-		module := getCode(t, src)
-		matchSnap(t, src, module.String())
+		MatchSnap(t, src, getC(t, src))
+	}
+}
+
+func MatchCErrors(t *testing.T, tests ...string) {
+	t.Helper()
+
+	for _, src := range tests {
+		_, diagnostics := getCWithDiagnostics(t, src)
+		var diags bytes.Buffer
+		for _, diag := range diagnostics {
+			diag.WriteTo(&diags, false)
+		}
+
+		MatchSnap(t, src, diags.String())
+	}
+}
+
+// Compiles a program to C using the C backend, then compiles it along
+// with some more C code and runs it, returning what it printed
+func RunCBackend(t *testing.T, src, cSrc string) string {
+	t.Helper()
+
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("No C compiler found")
+	}
+
+	dir := t.TempDir()
+	generatedPath := filepath.Join(dir, "generated.c")
+	cPath := filepath.Join(dir, "test.c")
+	exePath := filepath.Join(dir, "test")
+	if err := os.WriteFile(generatedPath, []byte(getC(t, src)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cPath, []byte(cSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command(
+		cc, "-std=c11", "-pedantic-errors", generatedPath, cPath, "-o", exePath, "-lm",
+	).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Failed to compile C code: %s\n%s", err, output)
+	}
+
+	output, err = exec.Command(exePath).Output()
+	if err != nil {
+		t.Fatal(err)
 	}
+	return string(output)
 }
@@ -0,0 +1,46 @@
+package typechecker
+
+import (
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/parser/ast"
+	"github.com/gearsdatapacks/libra/text"
+)
+
+// A declaration marked with `@deprecated` or `@todo`, which
+// is reported wherever it is used
+type markedDeclaration struct {
+	name       string
+	location   text.Location
+	deprecated *string
+	todo       *string
+}
+
+// Marked declarations across all modules, keyed by their symbol,
+// or by their function type for methods, which don't have symbols
+var marked = map[any]markedDeclaration{}
+
+func (t *typeChecker) markDeclaration(key any, name string, location text.Location, attributes ast.DeclarationAttributes) {
+	if attributes.DeprecatedMessage == nil && attributes.TodoMessage == nil {
+		return
+	}
+	marked[key] = markedDeclaration{
+		name:       name,
+		location:   location,
+		deprecated: attributes.DeprecatedMessage,
+		todo:       attributes.TodoMessage,
+	}
+}
+
+// Reports a use of a declaration, if it is deprecated or unfinished
+func (t *typeChecker) checkUsage(key any, location text.Location) {
+	decl, ok := marked[key]
+	if !ok {
+		return
+	}
+	if decl.deprecated != nil {
+		t.diagnostics.Report(diagnostics.UsedDeprecated(location, decl.name, decl.deprecated, decl.location))
+	}
+	if decl.todo != nil {
+		t.diagnostics.Report(diagnostics.UsedTodo(location, decl.name, decl.todo, decl.location, t.options.Release))
+	}
+}
@@ -0,0 +1,395 @@
+package typechecker
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/text"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"github.com/gearsdatapacks/libra/type_checker/values"
+)
+
+// A builder is finalised by converting it to the type it builds, or by
+// calling one of its methods which doesn't return another builder. After
+// that, it can't be used again until it is reassigned.
+//
+// Whether a builder has been finalised depends on the path taken to reach
+// it, so this is checked for each function separately, following the
+// control flow of its body.
+func (t *typeChecker) checkBuilders(statements []ir.Statement) {
+	flow := &builderFlow{
+		diagnostics: t.diagnostics,
+		finalised:   finalisedBuilders{},
+		report:      true,
+	}
+	flow.statements(statements)
+}
+
+// The builders which may have been finalised at some point in a function,
+// and where. Builders are identified by their path, such as `builder` or
+// `holder.builder`. A nil map means that point can never be reached.
+type finalisedBuilders map[string]text.Location
+
+func (f finalisedBuilders) merge(other finalisedBuilders) finalisedBuilders {
+	if f == nil {
+		return maps.Clone(other)
+	}
+	result := maps.Clone(f)
+	for path, location := range other {
+		if _, ok := result[path]; !ok {
+			result[path] = location
+		}
+	}
+	return result
+}
+
+// Removes a builder, and any builders stored in its fields
+func (f finalisedBuilders) remove(path string) {
+	for finalised := range f {
+		if finalised == path || strings.HasPrefix(finalised, path+".") {
+			delete(f, finalised)
+		}
+	}
+}
+
+// Returns the builders stored in a variable
+func (f finalisedBuilders) variable(name string) finalisedBuilders {
+	result := finalisedBuilders{}
+	for path, location := range f {
+		if path == name || strings.HasPrefix(path, name+".") {
+			result[path] = location
+		}
+	}
+	return result
+}
+
+// A point which control flow can jump to, like the end of a loop
+type flowTarget struct {
+	// The number of scopes outside the target, which are still
+	// in scope after jumping to it
+	depth  int
+	states finalisedBuilders
+}
+
+type builderFlow struct {
+	diagnostics *diagnostics.Manager
+	finalised   finalisedBuilders
+	// For each scope, the state of the variables it shadows, to
+	// be restored once the scope ends
+	scopes []map[string]finalisedBuilders
+	// Where `break`, `continue` and `yield` jump to
+	breaks, continues, yields []*flowTarget
+	// Loops are checked repeatedly until the state of their builders
+	// stops changing, but only the final pass reports errors
+	report bool
+}
+
+func (f *builderFlow) statements(statements []ir.Statement) {
+	f.scopes = append(f.scopes, map[string]finalisedBuilders{})
+	for _, statement := range statements {
+		f.statement(statement)
+	}
+	f.restore(f.finalised, f.scopes[len(f.scopes)-1])
+	f.scopes = f.scopes[:len(f.scopes)-1]
+}
+
+func (f *builderFlow) statement(statement ir.Statement) {
+	// Code which can't be reached can't use builders
+	if f.finalised == nil {
+		return
+	}
+
+	switch stmt := statement.(type) {
+	case *ir.VariableDeclaration:
+		f.expression(stmt.Value)
+		f.declare(stmt.Symbol.Name)
+	case *ir.ReturnStatement:
+		f.expression(stmt.Value)
+		f.finalised = nil
+	case *ir.BreakStatement:
+		f.expression(stmt.Value)
+		f.jump(f.breaks)
+	case *ir.ContinueStatement:
+		f.jump(f.continues)
+	case *ir.YieldStatement:
+		f.expression(stmt.Value)
+		f.jump(f.yields)
+	case ir.Expression:
+		f.expression(stmt)
+	}
+}
+
+func (f *builderFlow) expression(expression ir.Expression) {
+	if expression == nil || f.finalised == nil {
+		return
+	}
+
+	switch expr := expression.(type) {
+	case *ir.VariableExpression:
+		f.use(expr)
+	case *ir.MemberExpression:
+		if !f.use(expr) {
+			f.expression(expr.Left)
+		}
+
+	case *ir.BinaryExpression:
+		f.expression(expr.Left)
+		// The right side of `&&` and `||` isn't always evaluated
+		id := expr.Operator.Id & ^ir.UntypedBit
+		if id == ir.LogicalAnd || id == ir.LogicalOr {
+			skipped := f.finalised
+			f.finalised = maps.Clone(f.finalised)
+			f.expression(expr.Right)
+			f.finalised = f.finalised.merge(skipped)
+		} else {
+			f.expression(expr.Right)
+		}
+	case *ir.UnaryExpression:
+		f.expression(expr.Operand)
+	case *ir.Conversion:
+		f.expression(expr.Expression)
+		_, fromBuilder := expr.Expression.Type().(*types.Builder)
+		_, toBuilder := expr.To.(*types.Builder)
+		if fromBuilder && !toBuilder {
+			f.finalise(expr.Expression, expr.Location)
+		}
+	case *ir.ArrayExpression:
+		for _, element := range expr.Elements {
+			f.expression(element)
+		}
+	case *ir.IndexExpression:
+		f.expression(expr.Left)
+		f.expression(expr.Index)
+	case *ir.MapExpression:
+		for _, kv := range expr.KeyValues {
+			f.expression(kv.Key)
+			f.expression(kv.Value)
+		}
+	case *ir.Assignment:
+		f.expression(expr.Value)
+		// Reassigning a finalised builder means it can be used again
+		if path, ok := builderPath(expr.Assignee); ok {
+			if f.finalised != nil {
+				f.finalised.remove(path)
+			}
+			if member, ok := expr.Assignee.(*ir.MemberExpression); ok {
+				f.expression(member.Left)
+			}
+		} else {
+			f.expression(expr.Assignee)
+		}
+	case *ir.TupleExpression:
+		for _, value := range expr.Values {
+			f.expression(value)
+		}
+	case *ir.TypeCheck:
+		f.expression(expr.Value)
+	case *ir.FunctionCall:
+		f.expression(expr.Function)
+		for _, arg := range expr.Arguments {
+			f.expression(arg)
+		}
+		// Calling a method of a builder which doesn't return another builder finalises it
+		if member, ok := expr.Function.(*ir.MemberExpression); ok {
+			_, isBuilder := member.Left.Type().(*types.Builder)
+			_, returnsBuilder := expr.ReturnType.(*types.Builder)
+			if isBuilder && !returnsBuilder {
+				f.finalise(member.Left, expr.Location)
+			}
+		}
+	case *ir.StructExpression:
+		for _, field := range expr.Fields {
+			f.expression(field)
+		}
+	case *ir.TupleStructExpression:
+		for _, field := range expr.Fields {
+			f.expression(field)
+		}
+	case *ir.RefExpression:
+		f.expression(expr.Value)
+	case *ir.DerefExpression:
+		f.expression(expr.Value)
+
+	case *ir.Block:
+		yield := f.target(&f.yields)
+		f.statements(expr.Statements)
+		f.endTarget(&f.yields, yield)
+	case *ir.IfExpression:
+		f.ifExpression(expr)
+	case *ir.WhileLoop:
+		f.loop(expr.Condition, nil, expr.Body)
+	case *ir.ForLoop:
+		f.expression(expr.Iterator)
+		f.loop(nil, &expr.Variable.Name, expr.Body)
+
+	// Function literals are checked separately, like other functions
+	case *ir.FunctionExpression:
+		nested := &builderFlow{
+			diagnostics: f.diagnostics,
+			finalised:   finalisedBuilders{},
+			report:      f.report,
+		}
+		nested.statements(expr.Body.Statements)
+	}
+}
+
+func (f *builderFlow) ifExpression(ifExpr *ir.IfExpression) {
+	yield := f.target(&f.yields)
+	f.expression(ifExpr.Condition)
+
+	skipped := maps.Clone(f.finalised)
+	f.statements(ifExpr.Body.Statements)
+	body := f.finalised
+	f.finalised = skipped
+	if ifExpr.ElseBranch != nil {
+		f.statement(ifExpr.ElseBranch)
+	}
+	f.finalised = f.finalised.merge(body)
+
+	f.endTarget(&f.yields, yield)
+}
+
+// A builder finalised in one iteration of a loop can't be used in the next,
+// so loops are checked repeatedly until the builders which may have been
+// finalised at the start of the loop stop changing. Only the final pass
+// reports errors.
+func (f *builderFlow) loop(condition ir.Expression, variable *string, body *ir.Block) {
+	report := f.report
+	f.report = false
+
+	start := f.finalised
+	for {
+		next := start.merge(f.iteration(start, condition, variable, body))
+		if len(next) == len(start) {
+			break
+		}
+		start = next
+	}
+
+	f.report = report
+	f.iteration(start, condition, variable, body)
+}
+
+// Checks one iteration of a loop, returning the state at the end of its
+// body. Afterwards, the current state is the state after leaving the loop.
+func (f *builderFlow) iteration(
+	start finalisedBuilders,
+	condition ir.Expression,
+	variable *string,
+	body *ir.Block,
+) finalisedBuilders {
+	f.finalised = maps.Clone(start)
+	f.expression(condition)
+	// A loop like `while true` can only be left by breaking
+	var exit finalisedBuilders
+	if condition == nil || !isTrue(condition) {
+		exit = maps.Clone(f.finalised)
+	}
+
+	breaks := f.target(&f.breaks)
+	continues := f.target(&f.continues)
+	f.scopes = append(f.scopes, map[string]finalisedBuilders{})
+	if variable != nil {
+		f.declare(*variable)
+	}
+	f.statements(body.Statements)
+	f.restore(f.finalised, f.scopes[len(f.scopes)-1])
+	f.scopes = f.scopes[:len(f.scopes)-1]
+	f.endTarget(&f.continues, continues)
+
+	end := f.finalised
+	f.breaks = f.breaks[:len(f.breaks)-1]
+	f.finalised = exit.merge(breaks.states)
+	return end
+}
+
+func isTrue(condition ir.Expression) bool {
+	if !condition.IsConst() {
+		return false
+	}
+	boolean, ok := condition.ConstValue().(values.BoolValue)
+	return ok && boolean.Value
+}
+
+// Starts a point which control flow can jump to
+func (f *builderFlow) target(targets *[]*flowTarget) *flowTarget {
+	target := &flowTarget{depth: len(f.scopes)}
+	*targets = append(*targets, target)
+	return target
+}
+
+// Ends a jump target, continuing from any of the points which jumped to it
+func (f *builderFlow) endTarget(targets *[]*flowTarget, target *flowTarget) {
+	*targets = (*targets)[:len(*targets)-1]
+	f.finalised = target.states.merge(f.finalised)
+}
+
+func (f *builderFlow) jump(targets []*flowTarget) {
+	if len(targets) != 0 {
+		target := targets[len(targets)-1]
+		state := maps.Clone(f.finalised)
+		// Variables declared after the target go out of scope when jumping to it
+		for i := len(f.scopes) - 1; i >= target.depth; i-- {
+			f.restore(state, f.scopes[i])
+		}
+		target.states = target.states.merge(state)
+	}
+	f.finalised = nil
+}
+
+// Declares a new variable, which may shadow a finalised builder
+func (f *builderFlow) declare(name string) {
+	if f.finalised == nil {
+		return
+	}
+	scope := f.scopes[len(f.scopes)-1]
+	if _, ok := scope[name]; !ok {
+		scope[name] = f.finalised.variable(name)
+	}
+	f.finalised.remove(name)
+}
+
+// Restores the variables shadowed in a scope once it ends
+func (f *builderFlow) restore(state finalisedBuilders, scope map[string]finalisedBuilders) {
+	if state == nil {
+		return
+	}
+	for name, shadowed := range scope {
+		state.remove(name)
+		maps.Copy(state, shadowed)
+	}
+}
+
+// Reports an error if a finalised builder is used, returning whether it was
+func (f *builderFlow) use(expr ir.Expression) bool {
+	path, ok := builderPath(expr)
+	if !ok {
+		return false
+	}
+	finalised, ok := f.finalised[path]
+	if ok && f.report {
+		f.diagnostics.Report(diagnostics.BuilderFinalised(expr.GetLocation(), path, finalised))
+	}
+	return ok
+}
+
+func (f *builderFlow) finalise(builder ir.Expression, location text.Location) {
+	if path, ok := builderPath(builder); ok && f.finalised != nil {
+		f.finalised[path] = location
+	}
+}
+
+// The path used to identify a builder stored in a variable or field
+func builderPath(expr ir.Expression) (string, bool) {
+	switch expr := expr.(type) {
+	case *ir.VariableExpression:
+		return expr.Symbol.Name, true
+	case *ir.MemberExpression:
+		if left, ok := builderPath(expr.Left); ok {
+			return left + "." + expr.Member, true
+		}
+	}
+	return "", false
+}
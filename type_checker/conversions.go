@@ -1,11 +1,24 @@
 package typechecker
 
 import (
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/text"
 	"github.com/gearsdatapacks/libra/type_checker/ir"
 	"github.com/gearsdatapacks/libra/type_checker/types"
 )
 
 func convert(from ir.Expression, to types.Type, maxKind types.CastKind) ir.Expression {
+	// A struct literal is a new value, so it can be used to start building
+	if builder, ok := to.(*types.Builder); ok {
+		if _, ok := from.(*ir.StructExpression); ok && types.Assignable(builder.Struct, from.Type()) {
+			return &ir.Conversion{
+				Location:   from.GetLocation(),
+				Expression: from,
+				To:         to,
+			}
+		}
+	}
+
 	kind := types.Cast(from.Type(), to, maxKind)
 
 	if kind == types.IdentityCast {
@@ -15,6 +28,15 @@ func convert(from ir.Expression, to types.Type, maxKind types.CastKind) ir.Expre
 		return nil
 	}
 
+	// Untyped numbers must be given a concrete type before being stored in a union
+	if union, ok := to.(*types.Union); ok {
+		if num, ok := from.Type().(types.Numeric); ok && num.Untyped() {
+			if variant, _ := union.SelectVariant(num); variant != nil {
+				from = convert(from, variant, maxKind)
+			}
+		}
+	}
+
 	return &ir.Conversion{
 		Location:   from.GetLocation(),
 		Expression: from,
@@ -22,12 +44,29 @@ func convert(from ir.Expression, to types.Type, maxKind types.CastKind) ir.Expre
 	}
 }
 
+// Creates an error for a value which cannot be assigned to a type, explaining
+// the ambiguity if it could be stored in more than one variant of a union
+func notAssignable(location text.Location, expected, actual types.Type) *diagnostics.Diagnostic {
+	if union, ok := expected.(*types.Union); ok {
+		if _, candidates := union.SelectVariant(actual); len(candidates) > 1 {
+			names := make([]string, 0, len(candidates))
+			for _, candidate := range candidates {
+				names = append(names, candidate.String())
+			}
+			return diagnostics.AmbiguousVariant(location, expected, actual, names)
+		}
+	}
+	return diagnostics.NotAssignable(location, expected, actual)
+}
+
 // Converts two numeric types into the same type, following these rules:
 //  1. Identical types get preserved
 //  2. Similar types get upcasted to the higher number of bits
 //  3. If one type can represent all possible values of another, both types get upcasted
 //     to that.
-//  4. If all previous checks fail, the user must explicitly specify the types.
+//  4. Explicit types can only be combined with the same explicit type, or with
+//     untyped numbers that fit in them, and the explicit type is preserved.
+//  5. If all previous checks fail, the user must explicitly specify the types.
 //
 // Examples:
 //
@@ -37,18 +76,56 @@ func convert(from ir.Expression, to types.Type, maxKind types.CastKind) ir.Expre
 //	u32 + i32 -> error (u32 cannot represent negative numbers and i32 cannot represent u32.MAX)
 //	u32 + i64 -> i64 (i64 can represent u32.MAX)
 //	u32 + f16 -> error (u32 cannot represent non-integer numbers and f16 cannot represent u32.MAX)
-func upcastNumbers(a, b types.Numeric) types.Type {
-	// TODO: Add support for explicit types
+//	Metres + 1 -> Metres
+//	Metres + f32 -> error (explicit types must be converted explicitly)
+func upcastNumbers(a, b types.Type) types.Type {
+	aExpl, aExplicit := a.(*types.Explicit)
+	bExpl, bExplicit := b.(*types.Explicit)
+	aNum, _ := numericType(a)
+	bNum, _ := numericType(b)
+
+	if aExplicit && bExplicit {
+		if aExpl.Id == bExpl.Id {
+			return a
+		}
+		return nil
+	}
+	if aExplicit {
+		if bNum.Untyped() && types.Cast(bNum, aNum, types.OperatorCast) != types.NoCast {
+			return a
+		}
+		return nil
+	}
+	if bExplicit {
+		if aNum.Untyped() && types.Cast(aNum, bNum, types.OperatorCast) != types.NoCast {
+			return b
+		}
+		return nil
+	}
+
 	if types.Cast(a, b, types.OperatorCast) != types.NoCast {
-		return combineNumTypes(b, a)
+		return combineNumTypes(bNum, aNum)
 	}
 	if types.Cast(b, a, types.OperatorCast) != types.NoCast {
-		return combineNumTypes(a, b)
+		return combineNumTypes(aNum, bNum)
 	}
 
 	return nil
 }
 
+// Returns the numeric type of a value, looking through explicit types
+func numericType(ty types.Type) (types.Numeric, bool) {
+	for {
+		expl, ok := ty.(*types.Explicit)
+		if !ok {
+			break
+		}
+		ty = expl.Type
+	}
+	num, ok := ty.(types.Numeric)
+	return num, ok
+}
+
 func combineNumTypes(main, other types.Numeric) types.Numeric {
 	if main.Untyped() && other.Untyped() {
 		*main.Downcastable = types.Downcastable{}
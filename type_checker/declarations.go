@@ -57,11 +57,15 @@ func (t *typeChecker) registerFunction(fn *ast.FunctionDeclaration) {
 		IsMut:      false,
 		Type:       fnType,
 		ConstValue: nil,
+		Location:   fn.NameLocation,
 	}
 
 	if fn.MethodOf == nil && fn.MemberOf == nil {
 		if !t.symbols.Register(symbol, fn.Exported) {
 			t.diagnostics.Report(diagnostics.VariableDefined(fn.NameLocation, fn.Name))
+		} else {
+			t.markDeclaration(symbol, fn.Name, fn.NameLocation, fn.Attributes)
+			functionDeclarations[fnType] = functionDeclaration{checker: t, declaration: fn}
 		}
 	}
 }
@@ -74,10 +78,13 @@ func (t *typeChecker) registerTypeDeclaration(typeDec *ast.TypeDeclaration) {
 		ty = &types.Alias{Type: types.Void}
 	}
 	symbol := &symbols.Type{
-		Name: typeDec.Name,
-		Type: ty,
+		Name:     typeDec.Name,
+		Type:     ty,
+		Location: typeDec.Location,
+	}
+	if t.symbols.Register(symbol, typeDec.Exported) {
+		t.markDeclaration(symbol, typeDec.Name, symbol.Location, typeDec.Attributes)
 	}
-	t.symbols.Register(symbol, typeDec.Exported)
 }
 
 func (t *typeChecker) registerStructDeclaration(decl *ast.StructDeclaration) {
@@ -103,7 +110,10 @@ func (t *typeChecker) registerStructDeclaration(decl *ast.StructDeclaration) {
 			fieldOrder := make([]string, 0, len(decl.Body))
 
 			for _, field := range decl.Body {
-				fieldOrder = append(fieldOrder, *field.Name)
+				// Unnamed fields are reported when the body is type checked
+				if field.Name != nil {
+					fieldOrder = append(fieldOrder, *field.Name)
+				}
 			}
 
 			ty = &types.Struct{
@@ -116,11 +126,14 @@ func (t *typeChecker) registerStructDeclaration(decl *ast.StructDeclaration) {
 	}
 
 	symbol := &symbols.Type{
-		Name: decl.Name,
-		Type: ty,
+		Name:     decl.Name,
+		Type:     ty,
+		Location: decl.NameLocation,
 	}
 
-	t.symbols.Register(symbol, decl.Exported)
+	if t.symbols.Register(symbol, decl.Exported) {
+		t.markDeclaration(symbol, decl.Name, symbol.Location, decl.Attributes)
+	}
 }
 
 func (t *typeChecker) registerInterfaceDeclaration(decl *ast.InterfaceDeclaration) {
@@ -130,39 +143,52 @@ func (t *typeChecker) registerInterfaceDeclaration(decl *ast.InterfaceDeclaratio
 			Name:    decl.Name,
 			Methods: map[string]*types.Function{},
 		},
+		Location: decl.Location,
 	}
 
-	t.symbols.Register(symbol, decl.Exported)
+	if t.symbols.Register(symbol, decl.Exported) {
+		t.markDeclaration(symbol, decl.Name, symbol.Location, decl.Attributes)
+	}
 }
 
 func (t *typeChecker) registerUnionDeclaration(decl *ast.UnionDeclaration) {
 	symbol := &symbols.Type{
 		Name: decl.Name,
 		Type: &types.Union{
-			Name:    decl.Name,
-			Members: map[string]types.Type{},
+			Name:     decl.Name,
+			Members:  map[string]types.Type{},
+			Untagged: decl.Untagged,
 		},
+		Location: decl.Location,
 	}
 
-	t.symbols.Register(symbol, decl.Exported)
+	if t.symbols.Register(symbol, decl.Exported) {
+		t.markDeclaration(symbol, decl.Name, symbol.Location, decl.Attributes)
+	}
 }
 
 func (t *typeChecker) registerEnumDeclaration(decl *ast.EnumDeclaration) {
 	symbol := &symbols.Type{
-		Name: decl.Name,
-		Type: types.NewEnum(decl.Name, types.Invalid),
+		Name:     decl.Name,
+		Type:     types.NewEnum(decl.Name, types.Invalid),
+		Location: decl.Location,
 	}
 
-	t.symbols.Register(symbol, decl.Exported)
+	if t.symbols.Register(symbol, decl.Exported) {
+		t.markDeclaration(symbol, decl.Name, symbol.Location, decl.Attributes)
+	}
 }
 
 func (t *typeChecker) registerTagDeclaration(decl *ast.TagDeclaration) {
 	symbol := &symbols.Type{
-		Name: decl.Name,
-		Type: types.NewTag(decl.Name),
+		Name:     decl.Name,
+		Type:     types.NewTag(decl.Name),
+		Location: decl.Location,
 	}
 
-	t.symbols.Register(symbol, decl.Exported)
+	if t.symbols.Register(symbol, decl.Exported) {
+		t.markDeclaration(symbol, decl.Name, symbol.Location, decl.Attributes)
+	}
 }
 
 func (t *typeChecker) typeCheckTypeDeclaration(typeDec *ast.TypeDeclaration) ir.Statement {
@@ -176,12 +202,14 @@ func (t *typeChecker) typeCheckTypeDeclaration(typeDec *ast.TypeDeclaration) ir.
 	if typeDec.Tag != nil {
 		t.addToTag(typeDec.Tag, symbol.Type)
 	}
+	t.addGenerators(typeDec.Generators, typeDec.Name, symbol.Type)
 
 	return &ir.TypeDeclaration{
-		Name:     typeDec.Name,
-		Exported: typeDec.Exported,
-		Type:     symbol.Type,
-		Location: typeDec.Location,
+		Name:          typeDec.Name,
+		Exported:      typeDec.Exported,
+		Documentation: documentation(typeDec.Attributes),
+		Type:          symbol.Type,
+		Location:      typeDec.Location,
 	}
 }
 
@@ -212,7 +240,15 @@ func (t *typeChecker) typeCheckFunctionType(fn *ast.FunctionDeclaration) {
 		}
 	}
 
-	if fn.ReturnType != nil {
+	if fn.ReturnType != nil && isGenerator(fnType) {
+		// The type a generator is run for can be used in the type of the
+		// function it returns, so it is given a type to stand in for it
+		name := *fn.Parameters[0].Name
+		t.enterScope()
+		t.symbols.Register(&symbols.Type{Name: name, Type: types.NewExplicit(name, types.Void)})
+		fnType.ReturnType = t.typeCheckType(fn.ReturnType)
+		t.symbols = t.symbols.Parent
+	} else if fn.ReturnType != nil {
 		fnType.ReturnType = t.typeCheckType(fn.ReturnType)
 	}
 
@@ -231,6 +267,9 @@ func (t *typeChecker) typeCheckFunctionType(fn *ast.FunctionDeclaration) {
 			Function: fnType,
 		}, fn.Exported)
 	}
+	if fn.MethodOf != nil || fn.MemberOf != nil {
+		t.markDeclaration(fnType, fn.Name, fn.NameLocation, fn.Attributes)
+	}
 }
 
 func (t *typeChecker) typeCheckStructDeclaration(decl *ast.StructDeclaration) ir.Statement {
@@ -240,12 +279,14 @@ func (t *typeChecker) typeCheckStructDeclaration(decl *ast.StructDeclaration) ir
 	if decl.Tag != nil {
 		t.addToTag(decl.Tag, ty)
 	}
+	t.addGenerators(decl.Generators, decl.Name, ty)
 
 	return &ir.TypeDeclaration{
-		Name:     decl.Name,
-		Exported: decl.Exported,
-		Type:     ty,
-		Location: decl.Location,
+		Name:          decl.Name,
+		Exported:      decl.Exported,
+		Documentation: documentation(decl.Attributes),
+		Type:          ty,
+		Location:      decl.Location,
 	}
 }
 
@@ -267,6 +308,7 @@ func (t *typeChecker) typeCheckStructBody(
 				Name:     name,
 				Type:     nil,
 				Exported: field.Pub,
+				Builder:  field.Builder,
 			}
 			if field.Type == nil {
 				fields = append(fields, structField)
@@ -286,7 +328,7 @@ func (t *typeChecker) typeCheckStructBody(
 
 		if len(fields) > 0 && fields[len(fields)-1].Type == nil {
 			lastField := body[len(fields)-1]
-			t.diagnostics.ReportMany(diagnostics.LastStructFieldMustHaveType(lastField.Location, nameLocation))
+			t.diagnostics.Report(diagnostics.LastStructFieldMustHaveType(lastField.Location, nameLocation))
 		}
 
 		for i, field := range body {
@@ -330,10 +372,11 @@ func (t *typeChecker) typeCheckInterfaceDeclaration(decl *ast.InterfaceDeclarati
 	}
 
 	return &ir.TypeDeclaration{
-		Name:     decl.Name,
-		Exported: decl.Exported,
-		Type:     ty,
-		Location: decl.Location,
+		Name:          decl.Name,
+		Exported:      decl.Exported,
+		Documentation: documentation(decl.Attributes),
+		Type:          ty,
+		Location:      decl.Location,
 	}
 }
 
@@ -372,10 +415,11 @@ func (t *typeChecker) typeCheckUnionDeclaration(decl *ast.UnionDeclaration) ir.S
 	}
 
 	return &ir.TypeDeclaration{
-		Name:     decl.Name,
-		Exported: decl.Exported,
-		Type:     ty,
-		Location: decl.Location,
+		Name:          decl.Name,
+		Exported:      decl.Exported,
+		Documentation: documentation(decl.Attributes),
+		Type:          ty,
+		Location:      decl.Location,
 	}
 }
 
@@ -396,7 +440,7 @@ func (t *typeChecker) typeCheckEnumDeclaration(decl *ast.EnumDeclaration) ir.Sta
 			expression := t.typeCheckExpression(member.Value)
 			conversion := convert(expression, ty.Underlying, types.ImplicitCast)
 			if conversion == nil {
-				t.diagnostics.Report(diagnostics.NotAssignable(
+				t.diagnostics.Report(notAssignable(
 					member.Value.GetLocation(),
 					ty.Underlying,
 					expression.Type(),
@@ -428,10 +472,11 @@ func (t *typeChecker) typeCheckEnumDeclaration(decl *ast.EnumDeclaration) ir.Sta
 	}
 
 	return &ir.TypeDeclaration{
-		Name:     decl.Name,
-		Exported: decl.Exported,
-		Type:     ty,
-		Location: decl.Location,
+		Name:          decl.Name,
+		Exported:      decl.Exported,
+		Documentation: documentation(decl.Attributes),
+		Type:          ty,
+		Location:      decl.Location,
 	}
 }
 
@@ -445,10 +490,19 @@ func (t *typeChecker) typeCheckTagDeclaration(decl *ast.TagDeclaration) ir.State
 	}
 
 	return &ir.TypeDeclaration{
-		Name:     decl.Name,
-		Exported: decl.Exported,
-		Type:     ty,
-		Location: decl.Location,
+		Name:          decl.Name,
+		Exported:      decl.Exported,
+		Documentation: documentation(decl.Attributes),
+		Type:          ty,
+		Location:      decl.Location,
+	}
+}
+
+func documentation(attributes ast.DeclarationAttributes) ir.Documentation {
+	return ir.Documentation{
+		Text:       attributes.Documentation,
+		Deprecated: attributes.DeprecatedMessage,
+		Todo:       attributes.TodoMessage,
 	}
 }
 
@@ -476,7 +530,7 @@ func (t *typeChecker) typeCheckImport(importStmt *ast.ImportStatement) ir.Statem
 	module, ok := t.subModules[importStmt.Module.ExtraValue]
 
 	if !ok {
-		t.diagnostics.Report(diagnostics.ModuleUndefined(importStmt.Module.Location, importStmt.Module.Value))
+		t.diagnostics.Report(diagnostics.ModuleUndefined(importStmt.Module.Location, importStmt.Module.ExtraValue, t.moduleNames()))
 		return nil
 	}
 
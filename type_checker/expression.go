@@ -16,7 +16,8 @@ import (
 func (t *typeChecker) typeCheckExpression(expression ast.Expression) ir.Expression {
 	expr := t.doTypeCheckExpression(expression)
 	if varExpr, ok := expr.(*ir.VariableExpression); ok && varExpr.Symbol.Type == types.RuntimeType {
-		if unit, ok := varExpr.ConstValue().(values.TypeValue).Type.(*types.UnitStruct); ok {
+		typeValue, isConst := varExpr.ConstValue().(values.TypeValue)
+		if unit, ok := typeValue.Type.(*types.UnitStruct); isConst && ok {
 			expr = &ir.VariableExpression{
 				Location: expr.GetLocation(),
 				Symbol: symbols.Variable{
@@ -26,6 +27,7 @@ func (t *typeChecker) typeCheckExpression(expression ast.Expression) ir.Expressi
 					ConstValue: &values.UnitValue{
 						Name: unit.Name,
 					},
+					Location: varExpr.Symbol.Location,
 				},
 			}
 		}
@@ -97,6 +99,8 @@ func (t *typeChecker) doTypeCheckExpression(expression ast.Expression) ir.Expres
 		return t.typeCheckPointerType(expr)
 	case *ast.OptionType:
 		return t.typeCheckOptionType(expr)
+	case *ast.BuilderType:
+		return t.typeCheckBuilderType(expr)
 
 	case *ast.Block:
 		return t.typeCheckBlock(expr, true)
@@ -115,15 +119,21 @@ func (t *typeChecker) doTypeCheckExpression(expression ast.Expression) ir.Expres
 }
 
 func (t *typeChecker) lookupVariable(name string, location text.Location) ir.Expression {
+	t.symbols.MarkRead(name)
+	return t.variableExpression(name, location)
+}
+
+func (t *typeChecker) variableExpression(name string, location text.Location) ir.Expression {
 	symbol := t.symbols.Lookup(name)
 	if symbol == nil {
-		t.diagnostics.Report(diagnostics.VariableUndefined(location, name))
+		t.diagnostics.Report(diagnostics.VariableUndefined(location, name, t.symbolNames(false)))
 		symbol = &symbols.Variable{
 			Name:  name,
 			IsMut: true,
 			Type:  types.Invalid,
 		}
 	}
+	t.checkUsage(symbol, location)
 	return &ir.VariableExpression{
 		Location: location,
 		Symbol: symbols.Variable{
@@ -131,6 +141,7 @@ func (t *typeChecker) lookupVariable(name string, location text.Location) ir.Exp
 			IsMut:      symbol.Mutable(),
 			Type:       symbol.GetType(),
 			ConstValue: symbol.Value(),
+			Location:   symbol.GetLocation(),
 		},
 	}
 }
@@ -170,8 +181,8 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 	lType := left.Type()
 	rType := right.Type()
 
-	leftNum, leftNumeric := lType.(types.Numeric)
-	rightNum, rightNumeric := rType.(types.Numeric)
+	leftNum, leftNumeric := numericType(lType)
+	rightNum, rightNumeric := numericType(rType)
 
 	lUntyped := leftNumeric && leftNum.Untyped()
 	rUntyped := rightNumeric && rightNum.Untyped()
@@ -188,7 +199,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.LEFT_ANGLE:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -201,7 +212,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.RIGHT_ANGLE:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -213,7 +224,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.LEFT_ANGLE_EQUALS:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -225,7 +236,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.RIGHT_ANGLE_EQUALS:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -245,7 +256,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.DOUBLE_LEFT_ANGLE:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -254,7 +265,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				return
 			} else {
@@ -263,7 +274,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.DOUBLE_RIGHT_ANGLE:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -272,7 +283,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				return
 			} else {
@@ -281,7 +292,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.TRIPLE_RIGHT_ANGLE:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -290,7 +301,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				return
 			} else {
@@ -303,7 +314,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			// TODO: Add a proper error message for this
 			if resultType == nil {
@@ -313,7 +324,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				binOp.Id = ir.AddFloat
 			} else {
@@ -322,7 +333,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.MINUS:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -331,7 +342,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				binOp.Id = ir.SubtractFloat
 			} else {
@@ -340,7 +351,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.STAR:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -349,7 +360,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				binOp.Id = ir.MultiplyFloat
 			} else {
@@ -358,7 +369,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.SLASH:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -371,7 +382,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.PERCENT:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -380,7 +391,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				binOp.Id = ir.ModuloFloat
 			} else {
@@ -389,7 +400,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.DOUBLE_STAR:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -398,7 +409,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				binOp.Id = ir.PowerFloat
 			} else {
@@ -408,7 +419,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 
 	case token.PIPE:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -417,7 +428,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				return
 			} else {
@@ -428,7 +439,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.AMPERSAND:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -437,7 +448,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				return
 			} else {
@@ -446,7 +457,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 		}
 	case token.CARET:
 		if leftNumeric && rightNumeric {
-			resultType := upcastNumbers(leftNum, rightNum)
+			resultType := upcastNumbers(lType, rType)
 
 			if resultType == nil {
 				return
@@ -455,7 +466,7 @@ func getBinaryOperator(op token.Kind, left, right ir.Expression) (lhs, rhs ir.Ex
 			binOp.DataType = resultType
 			lhs = convert(lhs, resultType, types.OperatorCast)
 			rhs = convert(rhs, resultType, types.OperatorCast)
-			num := resultType.(types.Numeric)
+			num := types.Unwrap(resultType).(types.Numeric)
 			if num.Kind == types.NumFloat {
 				return
 			} else {
@@ -510,7 +521,14 @@ func (t *typeChecker) typeCheckPrefixExpression(unExpr *ast.PrefixExpression) ir
 }
 
 func (t *typeChecker) typeCheckPostfixExpression(unExpr *ast.PostfixExpression) ir.Expression {
-	operand := t.typeCheckExpression(unExpr.Operand)
+	var operand ir.Expression
+	ident, isIdent := unExpr.Operand.(*ast.Identifier)
+	if isIdent && (unExpr.Operator == token.DOUBLE_PLUS || unExpr.Operator == token.DOUBLE_MINUS) {
+		// Like `+=`, incrementing a variable doesn't read it
+		operand = t.variableExpression(ident.Name, ident.Location)
+	} else {
+		operand = t.typeCheckExpression(unExpr.Operand)
+	}
 
 	// Don't check for operators with invalid types, to prevent cascading errors
 	if operand.Type() == types.Invalid {
@@ -522,8 +540,13 @@ func (t *typeChecker) typeCheckPostfixExpression(unExpr *ast.PostfixExpression)
 	}
 
 	operator, diag := t.getPostfixOperator(unExpr.Operator, operand)
+	if unExpr.Operator == token.DOUBLE_PLUS || unExpr.Operator == token.DOUBLE_MINUS {
+		t.markWritten(operand)
+	}
 
-	if diag != nil {
+	if diag == diagnostics.ValueImmutablePartial {
+		t.diagnostics.Report(t.suggestMutable(diag.Location(unExpr.Operand.GetLocation()), operand))
+	} else if diag != nil {
 		t.diagnostics.Report(diag.Location(unExpr.Operand.GetLocation()))
 	} else if operator.Id == 0 {
 		t.diagnostics.Report(diagnostics.UnaryOperatorUndefined(unExpr.OperatorLocation, unExpr.Operator.String(), operand.Type()))
@@ -688,7 +711,7 @@ func (t *typeChecker) typeCheckArray(arr *ast.ListLiteral) ir.Expression {
 		}
 		converted := convert(value, elemType, types.OperatorCast)
 		if converted == nil {
-			t.diagnostics.Report(diagnostics.NotAssignable(elem.GetLocation(), elemType, value.Type()))
+			t.diagnostics.Report(notAssignable(elem.GetLocation(), elemType, value.Type()))
 		} else {
 			values = append(values, converted)
 		}
@@ -776,7 +799,7 @@ func (t *typeChecker) typeCheckMap(mapLit *ast.MapLiteral) ir.Expression {
 		}
 		convertedKey := convert(key, keyType, types.OperatorCast)
 		if convertedKey == nil {
-			t.diagnostics.Report(diagnostics.NotAssignable(kv.Key.GetLocation(), keyType, key.Type()))
+			t.diagnostics.Report(notAssignable(kv.Key.GetLocation(), keyType, key.Type()))
 			continue
 		}
 
@@ -786,7 +809,7 @@ func (t *typeChecker) typeCheckMap(mapLit *ast.MapLiteral) ir.Expression {
 		}
 		convertedValue := convert(value, valueType, types.OperatorCast)
 		if convertedValue == nil {
-			t.diagnostics.Report(diagnostics.NotAssignable(kv.Value.GetLocation(), valueType, value.Type()))
+			t.diagnostics.Report(notAssignable(kv.Value.GetLocation(), valueType, value.Type()))
 			continue
 		}
 
@@ -826,7 +849,15 @@ func (t *typeChecker) typeCheckMap(mapLit *ast.MapLiteral) ir.Expression {
 }
 
 func (t *typeChecker) typeCheckAssignment(assignment *ast.AssignmentExpression) ir.Expression {
-	assignee := t.typeCheckExpression(assignment.Assignee)
+	var assignee ir.Expression
+	if ident, ok := assignment.Assignee.(*ast.Identifier); ok {
+		// Assigning to a variable doesn't read it, even with an operator
+		// like `+=`, as the new value is only ever used by later reads
+		assignee = t.variableExpression(ident.Name, ident.Location)
+	} else {
+		assignee = t.typeCheckExpression(assignment.Assignee)
+	}
+	t.markWritten(assignee)
 	value := t.typeCheckExpression(assignment.Value)
 
 	if assignment.Operator.Kind != token.EQUALS {
@@ -847,11 +878,11 @@ func (t *typeChecker) typeCheckAssignment(assignment *ast.AssignmentExpression)
 	if !ir.AssignableExpr(assignee) {
 		t.diagnostics.Report(diagnostics.CannotAssign(assignment.Assignee.GetLocation()))
 	} else if !ir.MutableExpr(assignee) {
-		t.diagnostics.Report(diagnostics.ValueImmutable(assignment.Assignee.GetLocation()))
+		t.diagnostics.Report(t.suggestMutable(diagnostics.ValueImmutable(assignment.Assignee.GetLocation()), assignee))
 	} else {
 		conversion := convert(value, assignee.Type(), types.ImplicitCast)
 		if conversion == nil {
-			t.diagnostics.Report(diagnostics.NotAssignable(assignment.Assignee.GetLocation(), assignee.Type(), value.Type()))
+			t.diagnostics.Report(notAssignable(assignment.Assignee.GetLocation(), assignee.Type(), value.Type()))
 		} else {
 			value = conversion
 		}
@@ -905,6 +936,10 @@ func (t *typeChecker) typeCheckTypeCheck(tc *ast.TypeCheckExpression) ir.Express
 	value := t.typeCheckExpression(tc.Left)
 	ty := t.typeCheckType(tc.Type)
 
+	if types.IsUntaggedUnion(value.Type()) {
+		t.diagnostics.Report(diagnostics.UntaggedTypeCheck(tc.Location, value.Type()))
+	}
+
 	return &ir.TypeCheck{
 		Location: tc.Location,
 		Value:    value,
@@ -927,6 +962,18 @@ func (t *typeChecker) typeCheckFunctionCall(call *ast.FunctionCall) ir.Expressio
 		}
 	}
 
+	if variable, ok := fn.(*ir.VariableExpression); ok && isGenerator(funcType) {
+		t.diagnostics.Report(diagnostics.GeneratorCalled(call.Callee.GetLocation(), variable.Symbol.Name))
+		return &ir.InvalidExpression{
+			Expression: &ir.FunctionCall{
+				Location:   call.GetLocation(),
+				Function:   fn,
+				Arguments:  []ir.Expression{},
+				ReturnType: types.Invalid,
+			},
+		}
+	}
+
 	if len(call.Arguments) != len(funcType.Parameters) {
 		t.diagnostics.Report(diagnostics.WrongNumberArguments(call.Callee.GetLocation(), len(funcType.Parameters), len(call.Arguments)))
 		return &ir.InvalidExpression{
@@ -946,12 +993,14 @@ func (t *typeChecker) typeCheckFunctionCall(call *ast.FunctionCall) ir.Expressio
 		conversion := convert(value, expectedType, types.ImplicitCast)
 		if conversion == nil {
 			args = append(args, value)
-			t.diagnostics.Report(diagnostics.NotAssignable(arg.GetLocation(), expectedType, value.Type()))
+			t.diagnostics.Report(notAssignable(arg.GetLocation(), expectedType, value.Type()))
 		} else {
 			args = append(args, conversion)
 		}
 	}
 
+	t.markMethodCall(fn)
+
 	return &ir.FunctionCall{
 		Location:   call.GetLocation(),
 		Function:   fn,
@@ -973,7 +1022,7 @@ func (t *typeChecker) typeCheckStructExpression(structExpr *ast.StructExpression
 			}
 			field, ok := structTy.Fields[*member.Name]
 			if !ok {
-				t.diagnostics.Report(diagnostics.NoStructMember(member.Location, structTy.Name, *member.Name))
+				t.diagnostics.Report(diagnostics.NoStructMember(member.Location, structTy.Name, *member.Name, unsetFields(structTy, structExpr.Members)))
 				continue
 			}
 			var value ir.Expression
@@ -987,7 +1036,7 @@ func (t *typeChecker) typeCheckStructExpression(structExpr *ast.StructExpression
 			if conversion != nil {
 				value = conversion
 			} else {
-				t.diagnostics.Report(diagnostics.NotAssignable(member.Value.GetLocation(), field.Type, value.Type()))
+				t.diagnostics.Report(notAssignable(member.Value.GetLocation(), field.Type, value.Type()))
 			}
 
 			fields[*member.Name] = value
@@ -1028,7 +1077,7 @@ func (t *typeChecker) typeCheckStructExpression(structExpr *ast.StructExpression
 			if conversion != nil {
 				value = conversion
 			} else {
-				t.diagnostics.Report(diagnostics.NotAssignable(member.Location, field, value.Type()))
+				t.diagnostics.Report(notAssignable(member.Location, field, value.Type()))
 			}
 
 			fields = append(fields, value)
@@ -1050,6 +1099,11 @@ func (t *typeChecker) typeCheckStructExpression(structExpr *ast.StructExpression
 func (t *typeChecker) typeCheckMemberExpression(member *ast.MemberExpression) ir.Expression {
 	left := t.typeCheckExpression(member.Left)
 	ty, diag := ir.Member(left, member.Member)
+	// Variants of untagged unions are read by reinterpreting the union,
+	// so the result is just the underlying type of the variant
+	if variant, ok := ty.(*types.UnionVariant); ok && types.IsUntaggedUnion(left.Type()) {
+		ty = variant.Type
+	}
 
 	memberExpr := &ir.MemberExpression{
 		Location: member.GetLocation(),
@@ -1062,6 +1116,21 @@ func (t *typeChecker) typeCheckMemberExpression(member *ast.MemberExpression) ir
 		return &ir.InvalidExpression{Expression: memberExpr}
 	}
 
+	if left.IsConst() && left.Type() == types.RuntimeType {
+		if typeValue, ok := left.ConstValue().(values.TypeValue); ok {
+			memberExpr.TypeInfo = types.TypeInfoValue(typeValue.Type.(types.Type), member.Member, t.options.Target)
+		}
+	}
+
+	if module, ok := left.Type().(*types.Module); ok {
+		if table, ok := module.Module.(*symbols.Table); ok {
+			t.checkUsage(table.LookupExport(member.Member), member.MemberLocation)
+		}
+	} else if method, ok := ty.(*types.Function); ok {
+		// Methods are marked by their function type
+		t.checkUsage(method, member.MemberLocation)
+	}
+
 	return memberExpr
 }
 
@@ -1167,6 +1236,7 @@ func (t *typeChecker) typeCheckForLoop(loop *ast.ForLoop) ir.Expression {
 		IsMut:      false,
 		Type:       itemType,
 		ConstValue: nil,
+		Location:   loop.VariableLocation,
 	}
 
 	t.enterScope(&symbols.LoopContext{ResultType: types.Void})
@@ -1249,6 +1319,11 @@ func (t *typeChecker) typeCheckFunctionExpression(fn *ast.FunctionExpression) ir
 			IsMut:      param.Mutable,
 			Type:       paramType,
 			ConstValue: nil,
+			Location:   param.TypeOrIdent.Location,
+			Parameter:  true,
+		}
+		if param.Mutable {
+			symbol.Keyword = param.Location
 		}
 		t.symbols.Register(symbol)
 		params = append(params, *param.Name)
@@ -1269,6 +1344,9 @@ func (t *typeChecker) typeCheckFunctionExpression(fn *ast.FunctionExpression) ir
 
 func (t *typeChecker) typeCheckRefExpression(ref *ast.RefExpression) ir.Expression {
 	value := t.typeCheckExpression(ref.Operand)
+	if ref.Mutable {
+		t.markWritten(value)
+	}
 	if ref.Mutable && !ir.MutableExpr(value) {
 		t.diagnostics.Report(diagnostics.MutRefOfNotMut(ref.Location))
 	}
@@ -1315,3 +1393,20 @@ func (t *typeChecker) typeCheckOptionType(opt *ast.OptionType) ir.Expression {
 		},
 	}
 }
+
+func (t *typeChecker) typeCheckBuilderType(builder *ast.BuilderType) ir.Expression {
+	ty := t.typeCheckType(builder.Operand)
+
+	var dataType types.Type = &types.Builder{Struct: ty}
+	if _, ok := types.Unwrap(ty).(*types.Struct); !ok {
+		if ty != types.Invalid {
+			t.diagnostics.Report(diagnostics.NotBuildable(builder.Operand.GetLocation(), ty))
+		}
+		dataType = types.Invalid
+	}
+
+	return &ir.TypeExpression{
+		Location: builder.Location,
+		DataType: dataType,
+	}
+}
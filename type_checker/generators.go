@@ -0,0 +1,685 @@
+package typechecker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/lexer/token"
+	"github.com/gearsdatapacks/libra/parser/ast"
+	"github.com/gearsdatapacks/libra/text"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/symbols"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+	"github.com/gearsdatapacks/libra/type_checker/values"
+)
+
+// A generator produces the declaration of a method for a type at compile time.
+// The generated declaration is then type-checked like any other method.
+type generator struct {
+	method   string
+	generate func(g *methodGenerator) *ast.FunctionDeclaration
+}
+
+var generators = map[string]generator{
+	"derive_eq":    {"eq", generateEq},
+	"derive_hash":  {"hash", generateHash},
+	"derive_debug": {"debug", generateDebug},
+}
+
+type pendingGenerator struct {
+	// The built-in generator to run, if there is no user-defined function
+	generator generator
+	// The user-defined generator function to run
+	function *types.Function
+	typeName string
+	ty       types.Type
+	location text.Location
+}
+
+// A method produced by a generator, along with the constants declared
+// by the generator, which the method's declaration can refer to
+type generatedMethod struct {
+	declaration *ast.FunctionDeclaration
+	scope       []symbols.Symbol
+	// The type of function a user-defined generator was declared to return
+	expected *types.Function
+	// Where the function was returned from
+	location text.Location
+}
+
+// The top-level functions in all modules, so that generators can be
+// found from their function type and run in the module they are defined in
+var functionDeclarations = map[*types.Function]functionDeclaration{}
+
+type functionDeclaration struct {
+	checker     *typeChecker
+	declaration *ast.FunctionDeclaration
+}
+
+// Generators are either built into the compiler, or functions with the
+// signature `fn(Type): fn`, which take the type to generate a method for
+// and return the method. Built-in generators aren't values, so they are
+// only used if there is no function with the same name in scope.
+func (t *typeChecker) addGenerators(exprs []ast.Expression, typeName string, ty types.Type) {
+	for _, expr := range exprs {
+		// The argument is parsed as an expression, so `@gen(derive_eq)` is parenthesised
+		for {
+			paren, ok := expr.(*ast.ParenthesisedExpression)
+			if !ok {
+				break
+			}
+			expr = paren.Expression
+		}
+
+		if ident, ok := expr.(*ast.Identifier); ok && t.symbols.Lookup(ident.Name) == nil {
+			generator, ok := generators[ident.Name]
+			if !ok {
+				t.diagnostics.Report(diagnostics.GeneratorUndefined(ident.Location, ident.Name, t.generatorNames()))
+				continue
+			}
+			t.pendingGenerators = append(t.pendingGenerators, pendingGenerator{
+				generator: generator,
+				typeName:  typeName,
+				ty:        ty,
+				location:  expr.GetLocation(),
+			})
+			continue
+		}
+
+		// Function types are checked later, so generators' signatures
+		// are only checked when they are run
+		value := t.typeCheckExpression(expr)
+		function, ok := value.Type().(*types.Function)
+		if _, declared := functionDeclarations[function]; !ok || !declared {
+			if value.Type() != types.Invalid {
+				t.diagnostics.Report(diagnostics.NotGenerator(expr.GetLocation(), value.Type()))
+			}
+			continue
+		}
+		t.pendingGenerators = append(t.pendingGenerators, pendingGenerator{
+			function: function,
+			typeName: typeName,
+			ty:       ty,
+			location: expr.GetLocation(),
+		})
+	}
+}
+
+// The names of the built-in generators and the functions in scope,
+// which may have been meant when a generator isn't defined
+func (t *typeChecker) generatorNames() []string {
+	names := make([]string, 0, len(generators))
+	for name := range generators {
+		names = append(names, name)
+	}
+	for _, symbol := range t.symbols.Symbols() {
+		if _, ok := symbol.GetType().(*types.Function); ok {
+			names = append(names, symbol.GetName())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Whether a function is a generator, taking a type and returning a function
+func isGenerator(fnType *types.Function) bool {
+	declared, ok := functionDeclarations[fnType]
+	if !ok || len(fnType.Parameters) != 1 || fnType.Parameters[0] != types.RuntimeType {
+		return false
+	}
+	returnType, ok := declared.declaration.ReturnType.(*ast.FunctionExpression)
+	return ok && returnType.Body == nil && declared.declaration.Parameters[0].Name != nil
+}
+
+func (t *typeChecker) runGenerators() {
+	for _, pending := range t.pendingGenerators {
+		var method *generatedMethod
+		if pending.function != nil {
+			method = t.runUserGenerator(pending)
+		} else {
+			g := &methodGenerator{
+				t:        t,
+				typeName: pending.typeName,
+				ty:       pending.ty,
+				location: pending.location,
+				method:   pending.generator.method,
+			}
+			if fn := pending.generator.generate(g); fn != nil {
+				method = &generatedMethod{declaration: fn}
+			}
+		}
+		if method == nil {
+			continue
+		}
+
+		t.generatedMethods = append(t.generatedMethods, *method)
+		t.inGeneratedScope(*method, func() {
+			t.typeCheckFunctionType(method.declaration)
+		})
+		if method.expected != nil {
+			t.checkGeneratedSignature(*method, pending.ty)
+		}
+	}
+}
+
+// Checks that the function returned by a user-defined
+// generator matches the generator's return type
+func (t *typeChecker) checkGeneratedSignature(method generatedMethod, ty types.Type) {
+	fn := method.declaration
+	fnType := t.symbols.LookupMethod(fn.Name, ty, fn.MethodOf == nil)
+	if fnType == nil {
+		return
+	}
+
+	actual := fnType
+	if fn.MethodOf != nil {
+		actual = &types.Function{
+			Parameters: append([]types.Type{ty}, fnType.Parameters...),
+			ReturnType: fnType.ReturnType,
+		}
+	}
+	if !types.Match(method.expected, actual) {
+		t.diagnostics.Report(diagnostics.NotAssignable(method.location, method.expected, actual))
+	}
+}
+
+// Checks part of a generated method in a scope containing the constants
+// declared by its generator. The scope is added to the type's module,
+// so functions it calls must be visible from there.
+func (t *typeChecker) inGeneratedScope(method generatedMethod, check func()) {
+	t.enterScope()
+	for _, symbol := range method.scope {
+		t.symbols.Register(symbol)
+	}
+	check()
+	t.symbols = t.symbols.Parent
+}
+
+// Runs a user-defined generator at compile time, with its parameter
+// bound to the type, and turns the function it returns into a method
+func (t *typeChecker) runUserGenerator(pending pendingGenerator) *generatedMethod {
+	if !isGenerator(pending.function) {
+		t.diagnostics.Report(diagnostics.NotGenerator(pending.location, pending.function))
+		return nil
+	}
+	declared := functionDeclarations[pending.function]
+	decl := declared.declaration
+	if decl.Body == nil {
+		// Reported when the function is checked
+		return nil
+	}
+
+	// The generator runs in the module it is defined in
+	source := declared.checker
+	global := source.symbols
+	source.updateContext()
+	defer func() {
+		source.symbols = global
+		t.updateContext()
+	}()
+
+	source.enterScope()
+	source.symbols.Register(&symbols.Variable{
+		Name:       *decl.Parameters[0].Name,
+		Type:       types.RuntimeType,
+		ConstValue: values.TypeValue{Type: pending.ty},
+	})
+	expected, ok := source.typeCheckType(decl.ReturnType).(*types.Function)
+	if !ok {
+		return nil
+	}
+
+	literal, returned := source.evaluateGenerator(decl.Body.Statements, true)
+	if !returned {
+		t.diagnostics.Report(diagnostics.NoGeneratedFunction(decl.NameLocation))
+	}
+	if literal == nil {
+		return nil
+	}
+
+	// Constants declared by the generator, with inner scopes shadowing outer ones
+	scope := []symbols.Symbol{}
+	seen := map[string]bool{}
+	for table := source.symbols; table != global; table = table.Parent {
+		for _, symbol := range table.Declared() {
+			if !seen[symbol.GetName()] {
+				seen[symbol.GetName()] = true
+				scope = append(scope, symbol)
+			}
+		}
+	}
+
+	fn := &ast.FunctionDeclaration{
+		Location:     pending.location,
+		NameLocation: pending.location,
+		Name:         strings.TrimPrefix(decl.Name, "derive_"),
+		Parameters:   literal.Parameters,
+		ReturnType:   literal.ReturnType,
+		Body:         literal.Body,
+		Attributes:   ast.DeclarationAttributes{},
+	}
+
+	// If the returned function takes the type as its first parameter, it
+	// becomes a method, with that parameter bound to `this`. Otherwise, it
+	// is a static method of the type.
+	if len(expected.Parameters) > 0 && types.Match(expected.Parameters[0], pending.ty) &&
+		len(literal.Parameters) > 0 && literal.Parameters[0].Name != nil {
+		this := literal.Parameters[0]
+		keyword := "let"
+		if this.Mutable {
+			keyword = "mut"
+		}
+		fn.MethodOf = &ast.MethodOf{
+			Mutable: false,
+			Type:    &ast.Identifier{Location: pending.location, Name: pending.typeName},
+		}
+		// Only blocks with a single expression produce a value, so
+		// the original body is returned rather than added to
+		fn.Parameters = literal.Parameters[1:]
+		fn.Body = &ast.Block{
+			Location: literal.Body.Location,
+			Statements: []ast.Statement{
+				&ast.VariableDeclaration{
+					Keyword:      token.New(token.IDENTIFIER, keyword, "", this.Location),
+					NameLocation: this.TypeOrIdent.Location,
+					Name:         *this.Name,
+					Type:         this.Type,
+					Value:        &ast.Identifier{Location: this.TypeOrIdent.Location, Name: "this"},
+				},
+				&ast.ReturnStatement{Location: literal.Body.Location, Value: literal.Body},
+			},
+		}
+	} else {
+		fn.MemberOf = &ast.MemberOf{Location: pending.location, Name: pending.typeName}
+	}
+
+	return &generatedMethod{
+		declaration: fn,
+		scope:       scope,
+		expected:    expected,
+		location:    literal.Location,
+	}
+}
+
+// Runs the statements of a generator, returning the function literal it
+// returns, or nil if there is an error. The second value is whether the
+// statements returned or failed, so that the rest of the generator is
+// skipped. Only the last statement of the generator's body, or of a
+// branch in that position, can implicitly return the function.
+func (t *typeChecker) evaluateGenerator(statements []ast.Statement, tail bool) (*ast.FunctionExpression, bool) {
+	for i, statement := range statements {
+		isLast := tail && i == len(statements)-1
+
+		switch stmt := statement.(type) {
+		case *ast.VariableDeclaration:
+			if stmt.Keyword.Value == "mut" {
+				t.diagnostics.Report(diagnostics.NotEvaluable(stmt.GetLocation()))
+				return nil, true
+			}
+			decl := t.typeCheckVariableDeclaration(stmt).(*ir.VariableDeclaration)
+			if decl.Symbol.ConstValue == nil {
+				if decl.Value.Type() != types.Invalid {
+					t.diagnostics.Report(diagnostics.NotConst(stmt.Value.GetLocation()))
+				}
+				return nil, true
+			}
+
+		case *ast.ReturnStatement:
+			if stmt.Value == nil {
+				t.diagnostics.Report(diagnostics.NoGeneratedFunction(stmt.Location))
+				return nil, true
+			}
+			return t.generatedFunction(stmt.Value), true
+
+		case *ast.IfExpression:
+			literal, returned := t.evaluateIf(stmt, isLast)
+			if returned {
+				return literal, true
+			}
+
+		case *ast.Block:
+			t.enterScope()
+			literal, returned := t.evaluateGenerator(stmt.Statements, isLast)
+			if returned {
+				// The function can refer to constants in this scope
+				return literal, true
+			}
+			t.symbols = t.symbols.Parent
+
+		case ast.Expression:
+			if !isLast {
+				t.diagnostics.Report(diagnostics.NotEvaluable(stmt.GetLocation()))
+				return nil, true
+			}
+			return t.generatedFunction(stmt), true
+
+		default:
+			t.diagnostics.Report(diagnostics.NotEvaluable(statement.GetLocation()))
+			return nil, true
+		}
+	}
+
+	return nil, false
+}
+
+func (t *typeChecker) evaluateIf(ifExpr *ast.IfExpression, tail bool) (*ast.FunctionExpression, bool) {
+	condition := t.typeCheckExpression(ifExpr.Condition)
+	if condition.Type() == types.Invalid {
+		return nil, true
+	}
+	if !types.Assignable(types.Bool, condition.Type()) {
+		t.diagnostics.Report(diagnostics.NotAssignable(ifExpr.Condition.GetLocation(), types.Bool, condition.Type()))
+		return nil, true
+	}
+	if !condition.IsConst() {
+		t.diagnostics.Report(diagnostics.NotConst(ifExpr.Condition.GetLocation()))
+		return nil, true
+	}
+
+	var branch ast.Expression = ifExpr.Body
+	if !condition.ConstValue().(values.BoolValue).Value {
+		branch = ifExpr.ElseBranch
+	}
+
+	switch branch := branch.(type) {
+	case *ast.Block:
+		t.enterScope()
+		literal, returned := t.evaluateGenerator(branch.Statements, tail)
+		if returned {
+			return literal, true
+		}
+		t.symbols = t.symbols.Parent
+		return nil, false
+	case *ast.IfExpression:
+		return t.evaluateIf(branch, tail)
+	default:
+		return nil, false
+	}
+}
+
+// Returns the function literal a generator returns
+func (t *typeChecker) generatedFunction(expr ast.Expression) *ast.FunctionExpression {
+	for {
+		paren, ok := expr.(*ast.ParenthesisedExpression)
+		if !ok {
+			break
+		}
+		expr = paren.Expression
+	}
+
+	literal, ok := expr.(*ast.FunctionExpression)
+	if !ok || literal.Body == nil {
+		t.diagnostics.Report(diagnostics.NoGeneratedFunction(expr.GetLocation()))
+		return nil
+	}
+	return literal
+}
+
+type methodGenerator struct {
+	t        *typeChecker
+	typeName string
+	ty       types.Type
+	location text.Location
+	method   string
+}
+
+type field struct {
+	name  string
+	index int64
+	ty    types.Type
+}
+
+// Returns the fields of the type a method is being generated for, whether
+// they are accessed by index rather than by name, and whether the type
+// supports generated methods at all
+func (g *methodGenerator) fields() ([]field, bool, bool) {
+	switch ty := types.Unwrap(g.ty).(type) {
+	case *types.Struct:
+		fields := []field{}
+		for _, name := range ty.FieldOrder {
+			fields = append(fields, field{name: name, ty: ty.Fields[name].Type})
+		}
+		return fields, false, true
+	case *types.TupleStruct:
+		fields := []field{}
+		for i, fieldType := range ty.Types {
+			fields = append(fields, field{index: int64(i), ty: fieldType})
+		}
+		return fields, true, true
+	case *types.UnitStruct:
+		return []field{}, false, true
+	default:
+		g.cannotGenerate(g.ty)
+		return nil, false, false
+	}
+}
+
+func (g *methodGenerator) cannotGenerate(ty types.Type) {
+	g.t.diagnostics.Report(diagnostics.CannotGenerate(g.location, g.method, ty))
+}
+
+func (g *methodGenerator) declaration(
+	params []ast.Parameter,
+	returnType string,
+	result ast.Expression,
+) *ast.FunctionDeclaration {
+	return &ast.FunctionDeclaration{
+		Location:     g.location,
+		NameLocation: g.location,
+		MethodOf: &ast.MethodOf{
+			Mutable: false,
+			Type:    g.ident(g.typeName),
+		},
+		Name:       g.method,
+		Parameters: params,
+		ReturnType: g.ident(returnType),
+		Body: &ast.Block{
+			Location: g.location,
+			Statements: []ast.Statement{&ast.ReturnStatement{
+				Location: g.location,
+				Value:    result,
+			}},
+		},
+		Attributes: ast.DeclarationAttributes{},
+	}
+}
+
+func (g *methodGenerator) ident(name string) *ast.Identifier {
+	return &ast.Identifier{Location: g.location, Name: name}
+}
+
+func (g *methodGenerator) field(value string, field field, isTuple bool) ast.Expression {
+	if isTuple {
+		return &ast.IndexExpression{
+			Left:     g.ident(value),
+			Location: g.location,
+			Index:    g.integer(field.index),
+		}
+	}
+	return &ast.MemberExpression{
+		Location:       g.location,
+		MemberLocation: g.location,
+		Left:           g.ident(value),
+		Member:         field.name,
+	}
+}
+
+func (g *methodGenerator) methodCall(value ast.Expression, method string, args ...ast.Expression) ast.Expression {
+	return &ast.FunctionCall{
+		Callee: &ast.MemberExpression{
+			Location:       g.location,
+			MemberLocation: g.location,
+			Left:           value,
+			Member:         method,
+		},
+		Arguments: args,
+	}
+}
+
+func (g *methodGenerator) binary(left ast.Expression, op token.Kind, value string, right ast.Expression) ast.Expression {
+	return &ast.BinaryExpression{
+		Left:     left,
+		Operator: token.New(op, value, "", g.location),
+		Right:    right,
+	}
+}
+
+func (g *methodGenerator) integer(value int64) ast.Expression {
+	return &ast.IntegerLiteral{
+		Token: token.New(token.INTEGER, fmt.Sprint(value), "", g.location),
+		Value: value,
+	}
+}
+
+func (g *methodGenerator) string(value string) ast.Expression {
+	return &ast.StringLiteral{
+		Token: token.New(token.STRING, fmt.Sprintf("%q", value), value, g.location),
+		Value: value,
+	}
+}
+
+// fn (T) eq(other: T): bool
+func generateEq(g *methodGenerator) *ast.FunctionDeclaration {
+	fields, isTuple, ok := g.fields()
+	if !ok {
+		return nil
+	}
+
+	var result ast.Expression = &ast.BooleanLiteral{Location: g.location, Value: true}
+	for i, field := range fields {
+		this := g.field("this", field, isTuple)
+		other := g.field("other", field, isTuple)
+
+		var equal ast.Expression
+		switch field.ty.(type) {
+		case types.Numeric, types.PrimaryType:
+			equal = g.binary(this, token.DOUBLE_EQUALS, "==", other)
+		default:
+			equal = g.methodCall(this, "eq", other)
+		}
+
+		if i == 0 {
+			result = equal
+		} else {
+			result = g.binary(result, token.DOUBLE_AMPERSAND, "&&", equal)
+		}
+	}
+
+	other := "other"
+	params := []ast.Parameter{{
+		Location: g.location,
+		TypeOrIdent: ast.TypeOrIdent{
+			Location: g.location,
+			Name:     &other,
+			Type:     g.ident(g.typeName),
+		},
+	}}
+	return g.declaration(params, "bool", result)
+}
+
+// fn (T) hash(): u64
+func generateHash(g *methodGenerator) *ast.FunctionDeclaration {
+	fields, isTuple, ok := g.fields()
+	if !ok {
+		return nil
+	}
+
+	// Field hashes are combined as `hash = hash * 31 + field_hash`
+	var result ast.Expression = g.integer(0)
+	for i, field := range fields {
+		value := g.field("this", field, isTuple)
+
+		var hash ast.Expression
+		switch field.ty {
+		case types.Bool:
+			hash = &ast.CastExpression{Location: g.location, Left: value, Type: g.ident("u64")}
+		case types.String, types.RuntimeType, types.Invalid, types.Never:
+			g.cannotGenerate(field.ty)
+			return nil
+		default:
+			if _, ok := field.ty.(types.Numeric); ok {
+				hash = &ast.CastExpression{Location: g.location, Left: value, Type: g.ident("u64")}
+			} else {
+				hash = g.methodCall(value, "hash")
+			}
+		}
+
+		if i == 0 {
+			result = hash
+		} else {
+			result = g.binary(g.binary(result, token.STAR, "*", g.integer(31)), token.PLUS, "+", hash)
+		}
+	}
+
+	return g.declaration([]ast.Parameter{}, "u64", result)
+}
+
+// fn (T) debug(): string
+func generateDebug(g *methodGenerator) *ast.FunctionDeclaration {
+	fields, isTuple, ok := g.fields()
+	if !ok {
+		return nil
+	}
+
+	if len(fields) == 0 {
+		return g.declaration([]ast.Parameter{}, "string", g.string(g.typeName))
+	}
+
+	var result ast.Expression
+	if isTuple {
+		result = g.string(g.typeName + "(")
+	} else {
+		result = g.string(g.typeName + " { ")
+	}
+
+	for i, field := range fields {
+		label := ""
+		if i != 0 {
+			label = ", "
+		}
+		if !isTuple {
+			label += field.name + ": "
+		}
+		value := g.field("this", field, isTuple)
+
+		var debug ast.Expression
+		switch field.ty {
+		case types.String:
+			debug = g.binary(g.binary(g.string("\""), token.PLUS, "+", value), token.PLUS, "+", g.string("\""))
+		case types.Bool:
+			debug = &ast.IfExpression{
+				Location:  g.location,
+				Condition: value,
+				Body: &ast.Block{
+					Location:   g.location,
+					Statements: []ast.Statement{g.string("true")},
+				},
+				ElseBranch: &ast.Block{
+					Location:   g.location,
+					Statements: []ast.Statement{g.string("false")},
+				},
+			}
+		case types.RuntimeType, types.Invalid, types.Never:
+			g.cannotGenerate(field.ty)
+			return nil
+		default:
+			if _, ok := field.ty.(types.Numeric); ok {
+				debug = &ast.CastExpression{Location: g.location, Left: value, Type: g.ident("string")}
+			} else {
+				debug = g.methodCall(value, "debug")
+			}
+		}
+
+		result = g.binary(g.binary(result, token.PLUS, "+", g.string(label)), token.PLUS, "+", debug)
+	}
+
+	if isTuple {
+		result = g.binary(result, token.PLUS, "+", g.string(")"))
+	} else {
+		result = g.binary(result, token.PLUS, "+", g.string(" }"))
+	}
+
+	return g.declaration([]ast.Parameter{}, "string", result)
+}
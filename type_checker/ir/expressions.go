@@ -311,6 +311,54 @@ func (bo BinaryOperator) String() string {
 	}
 }
 
+// The operator as it is written in the source code
+func (bo BinaryOperator) Symbol() string {
+	switch bo.Id & ^UntypedBit {
+	case LogicalAnd:
+		return "&&"
+	case LogicalOr:
+		return "||"
+	case Less:
+		return "<"
+	case LessEq:
+		return "<="
+	case Greater:
+		return ">"
+	case GreaterEq:
+		return ">="
+	case Equal:
+		return "=="
+	case NotEqual:
+		return "!="
+	case LeftShift:
+		return "<<"
+	case ArithmeticRightShift:
+		return ">>"
+	case LogicalRightShift:
+		return ">>>"
+	case Union, BitwiseOr:
+		return "|"
+	case BitwiseAnd:
+		return "&"
+	case BitwiseXor:
+		return "^"
+	case AddInt, AddFloat, Concat:
+		return "+"
+	case SubtractInt, SubtractFloat:
+		return "-"
+	case MultiplyInt, MultiplyFloat:
+		return "*"
+	case Divide:
+		return "/"
+	case ModuloInt, ModuloFloat:
+		return "%"
+	case PowerInt, PowerFloat:
+		return "**"
+	default:
+		return "<?>"
+	}
+}
+
 func (b BinaryOperator) Type() types.Type {
 	untyped := b.Id&UntypedBit != 0
 	id := b.Id & ^UntypedBit
@@ -441,7 +489,8 @@ func (b *BinaryExpression) Type() types.Type {
 }
 
 func (b *BinaryExpression) IsConst() bool {
-	return b.Left.IsConst() && b.Right.IsConst()
+	// Invalid operators have no value to compute
+	return b.Operator.Id != 0 && b.Left.IsConst() && b.Right.IsConst()
 }
 
 func (b *BinaryExpression) ConstValue() values.ConstValue {
@@ -686,6 +735,28 @@ func (u UnaryOperator) String() string {
 	}
 }
 
+// The operator as it is written in the source code
+func (u UnaryOperator) Symbol() string {
+	switch u.Id & ^UntypedBit {
+	case NegateInt, NegateFloat:
+		return "-"
+	case Identity:
+		return "+"
+	case LogicalNot, CrashError:
+		return "!"
+	case BitwiseNot:
+		return "~"
+	case IncrementInt, IncrementFloat:
+		return "++"
+	case DecrementInt, DecrementFloat:
+		return "--"
+	case PropagateError:
+		return "?"
+	default:
+		return "<?>"
+	}
+}
+
 func (u UnaryOperator) Type() types.Type {
 	untyped := u.Id&UntypedBit != 0
 	id := u.Id & ^UntypedBit
@@ -830,7 +901,8 @@ func (c *Conversion) Type() types.Type {
 }
 
 func (c *Conversion) IsConst() bool {
-	return c.Expression.IsConst()
+	// Untagged unions are reinterpreted at runtime, so cannot be folded
+	return c.Expression.IsConst() && !types.IsUntaggedUnion(c.To)
 }
 
 func (c *Conversion) ConstValue() values.ConstValue {
@@ -838,7 +910,15 @@ func (c *Conversion) ConstValue() values.ConstValue {
 		return nil
 	}
 
-	if n, ok := c.To.(types.Numeric); ok {
+	if types.Unwrap(c.To) == types.String {
+		if _, ok := types.Unwrap(c.Expression.Type()).(types.Numeric); ok {
+			return values.StringValue{
+				Value: values.FormatNumber(c.Expression.ConstValue()),
+			}
+		}
+	}
+
+	if n, ok := types.Unwrap(c.To).(types.Numeric); ok {
 		num := values.NumericValue(c.Expression.ConstValue())
 		if n.Kind == types.NumFloat {
 			return values.FloatValue{
@@ -1171,6 +1251,8 @@ type FunctionCall struct {
 	Function   Expression
 	Arguments  []Expression
 	ReturnType types.Type
+	// Set by the ABI pass of the lowerer
+	Abi *FunctionAbi
 }
 
 func (f *FunctionCall) GetLocation() text.Location {
@@ -1319,6 +1401,9 @@ type MemberExpression struct {
 	Left     Expression
 	Member   string
 	DataType types.Type
+	// Members of a `Type` depend on the target's layout, so their
+	// values are worked out by the type checker
+	TypeInfo values.ConstValue
 }
 
 func (m *MemberExpression) GetLocation() text.Location {
@@ -1343,11 +1428,14 @@ func (m *MemberExpression) Type() types.Type {
 }
 
 func (m *MemberExpression) IsConst() bool {
-	return m.Left.IsConst() && m.Left.ConstValue().Member(m.Member) != nil
+	return m.ConstValue() != nil
 }
 
 func (m *MemberExpression) ConstValue() values.ConstValue {
-	if !m.IsConst() {
+	if m.TypeInfo != nil {
+		return m.TypeInfo
+	}
+	if !m.Left.IsConst() {
 		return nil
 	}
 	return m.Left.ConstValue().Member(m.Member)
@@ -0,0 +1,95 @@
+package ir
+
+// Traverses a statement and all of its children in depth-first order,
+// calling `visit` on each one. If `visit` returns false, the children
+// of that statement are skipped.
+func Inspect(stmt Statement, visit func(Statement) bool) {
+	if stmt == nil || !visit(stmt) {
+		return
+	}
+
+	switch s := stmt.(type) {
+	case *VariableDeclaration:
+		Inspect(s.Value, visit)
+	case *FunctionDeclaration:
+		inspectBlock(s.Body, visit)
+	case *ReturnStatement:
+		Inspect(s.Value, visit)
+	case *BreakStatement:
+		Inspect(s.Value, visit)
+	case *YieldStatement:
+		Inspect(s.Value, visit)
+
+	case *BinaryExpression:
+		Inspect(s.Left, visit)
+		Inspect(s.Right, visit)
+	case *UnaryExpression:
+		Inspect(s.Operand, visit)
+	case *Conversion:
+		Inspect(s.Expression, visit)
+	case *ArrayExpression:
+		for _, element := range s.Elements {
+			Inspect(element, visit)
+		}
+	case *IndexExpression:
+		Inspect(s.Left, visit)
+		Inspect(s.Index, visit)
+	case *MapExpression:
+		for _, kv := range s.KeyValues {
+			Inspect(kv.Key, visit)
+			Inspect(kv.Value, visit)
+		}
+	case *Assignment:
+		Inspect(s.Assignee, visit)
+		Inspect(s.Value, visit)
+	case *TupleExpression:
+		for _, value := range s.Values {
+			Inspect(value, visit)
+		}
+	case *TypeCheck:
+		Inspect(s.Value, visit)
+	case *FunctionCall:
+		Inspect(s.Function, visit)
+		for _, arg := range s.Arguments {
+			Inspect(arg, visit)
+		}
+	case *StructExpression:
+		for _, field := range s.Fields {
+			Inspect(field, visit)
+		}
+	case *TupleStructExpression:
+		for _, field := range s.Fields {
+			Inspect(field, visit)
+		}
+	case *MemberExpression:
+		Inspect(s.Left, visit)
+	case *Block:
+		for _, stmt := range s.Statements {
+			Inspect(stmt, visit)
+		}
+	case *IfExpression:
+		Inspect(s.Condition, visit)
+		inspectBlock(s.Body, visit)
+		Inspect(s.ElseBranch, visit)
+	case *WhileLoop:
+		Inspect(s.Condition, visit)
+		inspectBlock(s.Body, visit)
+	case *ForLoop:
+		Inspect(s.Iterator, visit)
+		inspectBlock(s.Body, visit)
+	case *FunctionExpression:
+		inspectBlock(s.Body, visit)
+	case *RefExpression:
+		Inspect(s.Value, visit)
+	case *DerefExpression:
+		Inspect(s.Value, visit)
+	}
+}
+
+// A nil block would be wrapped in a non-nil interface, so
+// it has to be checked before being passed to `Inspect`
+func inspectBlock(block *Block, visit func(Statement) bool) {
+	if block != nil {
+		Inspect(block, visit)
+	}
+}
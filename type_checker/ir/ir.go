@@ -8,6 +8,7 @@ import (
 	"github.com/gearsdatapacks/libra/diagnostics"
 	"github.com/gearsdatapacks/libra/printer"
 	"github.com/gearsdatapacks/libra/text"
+	"github.com/gearsdatapacks/libra/type_checker/symbols"
 	"github.com/gearsdatapacks/libra/type_checker/types"
 	"github.com/gearsdatapacks/libra/type_checker/values"
 )
@@ -52,6 +53,8 @@ func (p *Package) Print() {
 type Module struct {
 	Name       string
 	Statements []Statement
+	// The module's top-level symbols, used by tools such as the language server
+	Symbols *symbols.Table
 }
 
 func (m *Module) Print(node *printer.Node) {
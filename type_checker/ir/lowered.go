@@ -60,6 +60,38 @@ func (m *LoweredModule) Print(node *printer.Node) {
 	printer.Nodes(node, m.Globals)
 }
 
+// How a parameter or return value is passed between functions
+type PassKind int
+
+const (
+	// Passed as a value of its own type
+	PassDirect PassKind = iota
+	// Passed as a different type of the same size, such as an integer
+	// or a pair of eightbytes, which is stored in the same registers
+	PassCoerced
+	// Passed as a pointer to a copy of the value in memory
+	PassIndirect
+)
+
+type PassInfo struct {
+	Kind PassKind
+	// The type of the value in the program
+	Type types.Type
+	// The type of the value which is actually passed. For indirect
+	// values, this is a pointer to the original type.
+	AbiType types.Type
+	// Whether an indirect value is copied onto the stack by LLVM (the
+	// `byval` attribute), rather than being copied by the caller
+	ByVal bool
+}
+
+// Describes how the arguments and return value of a function are passed,
+// as decided by the ABI pass
+type FunctionAbi struct {
+	Parameters []PassInfo
+	Return     PassInfo
+}
+
 type Label struct {
 	Location text.Location
 	Name string
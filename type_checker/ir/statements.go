@@ -28,14 +28,32 @@ func (v *VariableDeclaration) Print(node *printer.Node) {
 		OptionalNode(v.Value)
 }
 
+// The documentation of a declaration, from its
+// `@doc`, `@deprecated` and `@todo` attributes
+type Documentation struct {
+	Text       string
+	Deprecated *string
+	Todo       *string
+}
+
 type FunctionDeclaration struct {
 	Location   text.Location
 	Name       string
 	Parameters []string
 	Body       *Block
 	Type       *types.Function
-	Exported   bool
-	Extern     *string
+	MethodOf   types.Type
+	// The type a static method is a member of
+	MemberOf      types.Type
+	Exported      bool
+	Documentation Documentation
+	Extern        *string
+	// The module an external function is imported from, on targets like WebAssembly
+	ImportModule *string
+	// The name it is imported as, if different from the name of the function
+	ImportName *string
+	// Set by the ABI pass of the lowerer
+	Abi *FunctionAbi
 }
 
 func (f *FunctionDeclaration) GetLocation() text.Location {
@@ -70,6 +88,24 @@ func (f *FunctionDeclaration) Print(node *printer.Node) {
 		)
 	}
 
+	if f.ImportModule != nil {
+		node.Text(
+			" %simport_module %s%s",
+			node.Colour(colour.Attribute),
+			node.Colour(colour.Name),
+			*f.ImportModule,
+		)
+	}
+
+	if f.ImportName != nil {
+		node.Text(
+			" %simport_name %s%s",
+			node.Colour(colour.Attribute),
+			node.Colour(colour.Name),
+			*f.ImportName,
+		)
+	}
+
 	node.OptionalNode(f.Body)
 }
 
@@ -182,10 +218,11 @@ func (i *ImportStatement) Print(node *printer.Node) {
 }
 
 type TypeDeclaration struct {
-	Location text.Location
-	Name     string
-	Exported bool
-	Type     types.Type
+	Location      text.Location
+	Name          string
+	Exported      bool
+	Documentation Documentation
+	Type          types.Type
 }
 
 func (t *TypeDeclaration) GetLocation() text.Location {
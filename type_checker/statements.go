@@ -60,7 +60,7 @@ func (t *typeChecker) typeCheckVariableDeclaration(varDec *ast.VariableDeclarati
 	if expectedType != nil {
 		conversion := convert(value, expectedType, types.ImplicitCast)
 		if conversion == nil {
-			t.diagnostics.Report(diagnostics.NotAssignable(varDec.Value.GetLocation(), expectedType, value.Type()))
+			t.diagnostics.Report(notAssignable(varDec.Value.GetLocation(), expectedType, value.Type()))
 		} else {
 			value = conversion
 		}
@@ -86,6 +86,12 @@ func (t *typeChecker) typeCheckVariableDeclaration(varDec *ast.VariableDeclarati
 		IsMut:      mutable,
 		Type:       varType,
 		ConstValue: constVal,
+		Location:   varDec.NameLocation,
+	}
+	// Constants can't be made mutable without changing when their
+	// value is known, so they aren't given a keyword to change
+	if !constant {
+		variable.Keyword = varDec.Keyword.Location
 	}
 	if !t.symbols.Register(variable) {
 		t.diagnostics.Report(diagnostics.VariableDefined(varDec.NameLocation, variable.Name))
@@ -99,14 +105,24 @@ func (t *typeChecker) typeCheckVariableDeclaration(varDec *ast.VariableDeclarati
 
 func (t *typeChecker) typeCheckFunctionDeclaration(funcDec *ast.FunctionDeclaration) ir.Statement {
 	var fnType *types.Function
+	var methodOf types.Type
+	var memberOf types.Type
 	if funcDec.MethodOf != nil {
-		fnType = t.symbols.LookupMethod(funcDec.Name, t.typeCheckType(funcDec.MethodOf.Type), false)
+		methodOf = t.typeCheckType(funcDec.MethodOf.Type)
+		fnType = t.symbols.LookupMethod(funcDec.Name, methodOf, false)
 	} else if funcDec.MemberOf != nil {
-		fnType = t.symbols.LookupMethod(funcDec.Name, t.lookupType(funcDec.MemberOf.Name, funcDec.MemberOf.Location), true)
+		memberOf = t.lookupType(funcDec.MemberOf.Name, funcDec.MemberOf.Location)
+		fnType = t.symbols.LookupMethod(funcDec.Name, memberOf, true)
 	} else {
 		fnType = t.symbols.Lookup(funcDec.Name).GetType().(*types.Function)
 	}
 
+	// Generators are only run at compile time, so their
+	// bodies are checked for each type they are used for
+	if funcDec.Body != nil && isGenerator(fnType) {
+		return nil
+	}
+
 	t.enterScope(symbols.FunctionContext{ReturnType: fnType.ReturnType})
 	defer t.exitScope()
 	params := []string{}
@@ -121,16 +137,26 @@ func (t *typeChecker) typeCheckFunctionDeclaration(funcDec *ast.FunctionDeclarat
 			IsMut:      param.Mutable,
 			Type:       fnType.Parameters[i],
 			ConstValue: nil,
+			Location:   param.TypeOrIdent.Location,
+			Parameter:  true,
+		}
+		if param.Mutable {
+			symbol.Keyword = param.Location
+		}
+		// Functions without a body can't use their parameters
+		if funcDec.Body != nil {
+			t.symbols.Register(symbol)
 		}
-		t.symbols.Register(symbol)
 		params = append(params, *param.Name)
 	}
 
 	if funcDec.MethodOf != nil {
+		// Builders are still being initialised, so they can always be modified
+		_, isBuilder := methodOf.(*types.Builder)
 		symbol := &symbols.Variable{
 			Name:       "this",
-			IsMut:      funcDec.MethodOf.Mutable,
-			Type:       t.typeCheckType(funcDec.MethodOf.Type),
+			IsMut:      funcDec.MethodOf.Mutable || isBuilder,
+			Type:       methodOf,
 			ConstValue: nil,
 		}
 		t.symbols.Register(symbol)
@@ -140,6 +166,7 @@ func (t *typeChecker) typeCheckFunctionDeclaration(funcDec *ast.FunctionDeclarat
 
 	if funcDec.Body != nil {
 		body = t.typeCheckBlock(funcDec.Body, false)
+		t.checkBuilders(body.Statements)
 	}
 
 	var extern *string
@@ -147,6 +174,9 @@ func (t *typeChecker) typeCheckFunctionDeclaration(funcDec *ast.FunctionDeclarat
 		if funcDec.Body == nil {
 			t.diagnostics.Report(diagnostics.NoBody(funcDec.NameLocation))
 		}
+		if funcDec.ImportModule != nil || funcDec.ImportName != nil {
+			t.diagnostics.Report(diagnostics.ImportWithoutExtern(funcDec.NameLocation))
+		}
 	} else {
 		if funcDec.Body != nil {
 			t.diagnostics.Report(diagnostics.ExternWithBody(funcDec.NameLocation))
@@ -156,13 +186,18 @@ func (t *typeChecker) typeCheckFunctionDeclaration(funcDec *ast.FunctionDeclarat
 	}
 
 	return &ir.FunctionDeclaration{
-		Name:       funcDec.Name,
-		Parameters: params,
-		Body:       body,
-		Type:       fnType,
-		Exported:   funcDec.Exported,
-		Extern:     extern,
-		Location:   funcDec.NameLocation,
+		Name:          funcDec.Name,
+		Parameters:    params,
+		Body:          body,
+		Type:          fnType,
+		MethodOf:      methodOf,
+		MemberOf:      memberOf,
+		Exported:      funcDec.Exported,
+		Documentation: documentation(funcDec.Attributes),
+		Extern:        extern,
+		ImportModule:  funcDec.ImportModule,
+		ImportName:    funcDec.ImportName,
+		Location:      funcDec.NameLocation,
 	}
 }
 
@@ -195,7 +230,7 @@ func (t *typeChecker) typeCheckReturn(ret *ast.ReturnStatement) ir.Statement {
 	if conversion := convert(value, expectedType, types.ImplicitCast); conversion != nil {
 		value = conversion
 	} else {
-		t.diagnostics.Report(diagnostics.NotAssignable(ret.Value.GetLocation(), expectedType, value.Type()))
+		t.diagnostics.Report(notAssignable(ret.Value.GetLocation(), expectedType, value.Type()))
 	}
 
 	return &ir.ReturnStatement{
@@ -0,0 +1,67 @@
+package typechecker
+
+import (
+	"slices"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/parser/ast"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/symbols"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// The names of the symbols visible from the current scope, which
+// may have been meant when a name can't be found
+func (t *typeChecker) symbolNames(typesOnly bool) []string {
+	names := []string{}
+	for _, symbol := range t.symbols.Symbols() {
+		if typesOnly && symbol.GetType() != types.RuntimeType {
+			continue
+		}
+		names = append(names, symbol.GetName())
+	}
+	return names
+}
+
+func (t *typeChecker) moduleNames() []string {
+	names := []string{}
+	for name := range t.subModules {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// The fields of a struct which haven't been given a value in a struct
+// expression, as a misspelled field is most likely one of those
+func unsetFields(structTy *types.Struct, members []ast.StructMember) []string {
+	names := []string{}
+	for _, name := range structTy.FieldOrder {
+		set := slices.ContainsFunc(members, func(member ast.StructMember) bool {
+			return member.Name != nil && *member.Name == name
+		})
+		if !set {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Suggests making the variable an expression modifies mutable,
+// when it is declared as immutable
+func (t *typeChecker) suggestMutable(diagnostic *diagnostics.Diagnostic, expr ir.Expression) *diagnostics.Diagnostic {
+	switch e := expr.(type) {
+	case *ir.IndexExpression:
+		return t.suggestMutable(diagnostic, e.Left)
+	case *ir.MemberExpression:
+		return t.suggestMutable(diagnostic, e.Left)
+	case *ir.VariableExpression:
+		variable, ok := t.symbols.Lookup(e.Symbol.Name).(*symbols.Variable)
+		if !ok || variable.IsMut || variable.Location.File == nil ||
+			(!variable.Parameter && variable.Keyword.File == nil) {
+			return diagnostic
+		}
+		return diagnostic.WithFix(diagnostics.MakeMutable(variable.Name, variable.Keyword, variable.Location))
+	}
+	return diagnostic
+}
@@ -3,6 +3,7 @@ package symbols
 import (
 	"github.com/gearsdatapacks/libra/colour"
 	"github.com/gearsdatapacks/libra/printer"
+	"github.com/gearsdatapacks/libra/text"
 	"github.com/gearsdatapacks/libra/type_checker/types"
 	"github.com/gearsdatapacks/libra/type_checker/values"
 )
@@ -12,6 +13,7 @@ type Symbol interface {
 	Value() values.ConstValue
 	GetName() string
 	Mutable() bool
+	GetLocation() text.Location
 }
 
 type Variable struct {
@@ -19,6 +21,13 @@ type Variable struct {
 	IsMut      bool
 	Type       types.Type
 	ConstValue values.ConstValue
+	// Where the variable was declared. Variables created
+	// by the compiler don't have a location.
+	Location text.Location
+	// The keyword the variable was declared with, or `mut` before a
+	// parameter, used to suggest changing whether it is mutable
+	Keyword   text.Location
+	Parameter bool
 }
 
 func (v *Variable) Value() values.ConstValue {
@@ -37,6 +46,10 @@ func (v *Variable) Mutable() bool {
 	return v.IsMut
 }
 
+func (v *Variable) GetLocation() text.Location {
+	return v.Location
+}
+
 func (v *Variable) Print(node *printer.Node) {
 	node.
 		Text(
@@ -53,6 +66,8 @@ func (v *Variable) Print(node *printer.Node) {
 type Type struct {
 	Name string
 	Type types.Type
+	// Where the type was declared. Built-in types don't have a location.
+	Location text.Location
 }
 
 func (t *Type) Value() values.ConstValue {
@@ -71,6 +86,10 @@ func (*Type) Mutable() bool {
 	return false
 }
 
+func (t *Type) GetLocation() text.Location {
+	return t.Location
+}
+
 type Method struct {
 	MethodOf types.Type
 	Static   bool
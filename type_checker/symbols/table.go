@@ -2,6 +2,8 @@ package symbols
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/gearsdatapacks/libra/type_checker/types"
 )
@@ -9,12 +11,21 @@ import (
 type Table struct {
 	Parent  *Table
 	symbols map[string]Symbol
+	// How each symbol declared in this scope has been used
+	usage   map[string]*Usage
 	Context any
 }
 
+// How a symbol has been used, which is used to warn about unused code
+type Usage struct {
+	Read    bool
+	Written bool
+}
+
 func New() *Table {
 	t := &Table{
 		symbols: map[string]Symbol{},
+		usage:   map[string]*Usage{},
 		Context: &globalContext{
 			methods:         map[string][]*Method{},
 			exportedMethods: map[string][]*Method{},
@@ -29,6 +40,7 @@ func (t *Table) Child() *Table {
 	return &Table{
 		Parent:  t,
 		symbols: map[string]Symbol{},
+		usage:   map[string]*Usage{},
 	}
 }
 
@@ -36,6 +48,7 @@ func (t *Table) ChildWithContext(context any) *Table {
 	return &Table{
 		Parent:  t,
 		symbols: map[string]Symbol{},
+		usage:   map[string]*Usage{},
 		Context: context,
 	}
 }
@@ -45,6 +58,7 @@ func (t *Table) Register(symbol Symbol, exported ...bool) bool {
 		return false
 	}
 	t.symbols[symbol.GetName()] = symbol
+	t.usage[symbol.GetName()] = &Usage{}
 
 	if len(exported) > 0 && exported[0] {
 		context := t.Context.(*globalContext)
@@ -65,6 +79,50 @@ func (t *Table) Lookup(name string) Symbol {
 	return nil
 }
 
+// Records that the value of a symbol is used
+func (t *Table) MarkRead(name string) {
+	if usage := t.usageOf(name); usage != nil {
+		usage.Read = true
+	}
+}
+
+// Records that a symbol is assigned to or modified
+func (t *Table) MarkWritten(name string) {
+	if usage := t.usageOf(name); usage != nil {
+		usage.Written = true
+	}
+}
+
+// Returns how a symbol visible from this scope has been used
+func (t *Table) Usage(name string) Usage {
+	if usage := t.usageOf(name); usage != nil {
+		return *usage
+	}
+	return Usage{}
+}
+
+func (t *Table) usageOf(name string) *Usage {
+	for table := t; table != nil; table = table.Parent {
+		if _, ok := table.symbols[name]; ok {
+			return table.usage[name]
+		}
+	}
+	return nil
+}
+
+// Returns the symbols declared in this scope, not including its
+// parents, in the order they appear in the source code
+func (t *Table) Declared() []Symbol {
+	declared := make([]Symbol, 0, len(t.symbols))
+	for _, symbol := range t.symbols {
+		declared = append(declared, symbol)
+	}
+	slices.SortFunc(declared, func(a, b Symbol) int {
+		return a.GetLocation().Span.Start - b.GetLocation().Span.Start
+	})
+	return declared
+}
+
 func (t *Table) LookupExport(name string) Symbol {
 	symbol, ok := t.globalScope().Context.(*globalContext).exports[name]
 	if ok {
@@ -81,6 +139,68 @@ func (t *Table) LookupExportType(name string) types.Type {
 	return nil
 }
 
+// Returns every symbol which is visible from this scope
+func (t *Table) Symbols() []Symbol {
+	visible := map[string]Symbol{}
+	for table := t; table != nil; table = table.Parent {
+		for name, symbol := range table.symbols {
+			if _, shadowed := visible[name]; !shadowed {
+				visible[name] = symbol
+			}
+		}
+	}
+
+	symbols := make([]Symbol, 0, len(visible))
+	for _, symbol := range visible {
+		symbols = append(symbols, symbol)
+	}
+	slices.SortFunc(symbols, func(a, b Symbol) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	return symbols
+}
+
+// Returns the symbols which are exported from this table's module
+func (t *Table) Exports() []Symbol {
+	exports := []Symbol{}
+	for _, symbol := range t.globalScope().Context.(*globalContext).exports {
+		exports = append(exports, symbol)
+	}
+	slices.SortFunc(exports, func(a, b Symbol) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	return exports
+}
+
+func (t *Table) ExportNames() []string {
+	names := []string{}
+	for _, export := range t.Exports() {
+		names = append(names, export.GetName())
+	}
+	return names
+}
+
+// Whether this table's module exports any methods
+func (t *Table) ExportsMethods() bool {
+	return len(t.globalScope().Context.(*globalContext).exportedMethods) != 0
+}
+
+// Returns the names of the methods which can be called on a type
+func (t *Table) MethodNames(methodOf types.Type, static bool) []string {
+	context := t.globalScope().Context.(*globalContext)
+	names := []string{}
+	for name, methods := range context.methods {
+		for _, method := range methods {
+			if method.Static == static && types.Match(method.MethodOf, methodOf) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
 func (t *Table) globalScope() *Table {
 	if t.Parent == nil {
 		return t
@@ -137,17 +257,21 @@ func (t *Table) Extend(other *Table) {
 
 func (t *Table) registerGlobals() {
 	for _, n := range []int{8, 16, 32, 64} {
-		t.Register(&Type{fmt.Sprintf("i%d", n), types.Int(n)})
-		t.Register(&Type{fmt.Sprintf("u%d", n), types.Uint(n)})
+		t.Register(&Type{Name: fmt.Sprintf("i%d", n), Type: types.Int(n)})
+		t.Register(&Type{Name: fmt.Sprintf("u%d", n), Type: types.Uint(n)})
 		if n != 8 {
-			t.Register(&Type{fmt.Sprintf("f%d", n), types.Float(n)})
+			t.Register(&Type{Name: fmt.Sprintf("f%d", n), Type: types.Float(n)})
 		}
 	}
 
-	t.Register(&Type{"bool", types.Bool})
-	t.Register(&Type{"string", types.String})
-	t.Register(&Type{"void", types.Void})
-	t.Register(&Type{"Type", types.RuntimeType})
-	t.Register(&Type{"never", types.Never})
-	t.Register(&Type{"Error", &types.ErrorTag})
+	t.Register(&Type{Name: "bool", Type: types.Bool})
+	t.Register(&Type{Name: "string", Type: types.String})
+	t.Register(&Type{Name: "void", Type: types.Void})
+	t.Register(&Type{Name: "Type", Type: types.RuntimeType})
+	t.Register(&Type{Name: "never", Type: types.Never})
+	t.Register(&Type{Name: "Error", Type: &types.ErrorTag})
+	t.Register(&Type{Name: "TypeKind", Type: types.TypeKindEnum})
+	t.Register(&Type{Name: "TypeField", Type: types.TypeField})
+	t.Register(&Type{Name: "TypeVariant", Type: types.TypeVariant})
+	t.Register(&Type{Name: "TypeMethod", Type: types.TypeMethod})
 }
@@ -21,19 +21,23 @@ const (
 )
 
 type typeChecker struct {
-	diagnostics *diagnostics.Manager
-	module      *module.Module
-	symbols     *symbols.Table
-	subModules  map[string]*typeChecker
-	stage       tcStage
+	diagnostics       *diagnostics.Manager
+	module            *module.Module
+	options           Options
+	symbols           *symbols.Table
+	subModules        map[string]*typeChecker
+	stage             tcStage
+	pendingGenerators []pendingGenerator
+	generatedMethods  []generatedMethod
 }
 
 var mods = map[string]*typeChecker{}
 
-func new(mod *module.Module, diagnostics *diagnostics.Manager) *typeChecker {
+func new(mod *module.Module, options Options, diagnostics *diagnostics.Manager) *typeChecker {
 	t := &typeChecker{
 		diagnostics: diagnostics,
 		module:      mod,
+		options:     options,
 		symbols:     symbols.New(),
 		subModules:  map[string]*typeChecker{},
 		stage:       tcNone,
@@ -45,7 +49,7 @@ func new(mod *module.Module, diagnostics *diagnostics.Manager) *typeChecker {
 			t.subModules[name] = mod
 			continue
 		}
-		mods[subMod.Path] = new(subMod, diagnostics)
+		mods[subMod.Path] = new(subMod, options, diagnostics)
 		t.subModules[name] = mods[subMod.Path]
 	}
 	return t
@@ -60,8 +64,22 @@ func (t *typeContext) Id() uint {
 	return t.id
 }
 
-func TypeCheck(mod *module.Module, manager diagnostics.Manager) (*ir.Package, diagnostics.Manager) {
-	t := new(mod, &manager)
+// Settings which affect how a package is checked
+type Options struct {
+	// The target which types are laid out for
+	Target types.TargetInfo
+	// Whether the program is being built for release. Unfinished
+	// declarations, marked with `@todo`, can't be used in release builds.
+	Release bool
+}
+
+func TypeCheck(mod *module.Module, options Options, manager diagnostics.Manager) (*ir.Package, diagnostics.Manager) {
+	// Modules may have changed since they were last type checked,
+	// for example when the language server checks a file being edited
+	mods = map[string]*typeChecker{}
+	marked = map[any]markedDeclaration{}
+	functionDeclarations = map[*types.Function]functionDeclaration{}
+	t := new(mod, options, &manager)
 
 	pkg := &ir.Package{
 		Modules: map[string]*ir.Module{},
@@ -117,6 +135,7 @@ func (t *typeChecker) typeCheckImports(pkg *ir.Package) {
 		pkg.Modules[t.module.Path] = &ir.Module{
 			Name:       t.module.Name,
 			Statements: []ir.Statement{},
+			Symbols:    t.symbols,
 		}
 	}
 	module := pkg.Modules[t.module.Path]
@@ -175,6 +194,8 @@ func (t *typeChecker) typeCheckFunctions() {
 			}
 		}
 	}
+	// Generators are functions, so they can only be run once their types are known
+	t.runGenerators()
 }
 
 func (t *typeChecker) typeCheckStatements(pkg *ir.Package) {
@@ -198,6 +219,16 @@ func (t *typeChecker) typeCheckStatements(pkg *ir.Package) {
 			}
 		}
 	}
+	// Statements at the top level are run in order, like in a function
+	t.checkBuilders(module.Statements)
+
+	for _, method := range t.generatedMethods {
+		t.inGeneratedScope(method, func() {
+			module.Statements = append(module.Statements, t.typeCheckFunctionDeclaration(method.declaration))
+		})
+	}
+
+	t.reportUnusedDeclarations()
 }
 
 func (t *typeChecker) enterScope(context ...any) {
@@ -209,5 +240,6 @@ func (t *typeChecker) enterScope(context ...any) {
 }
 
 func (t *typeChecker) exitScope() {
+	t.reportUnusedVariables()
 	t.symbols = t.symbols.Parent
 }
@@ -1,9 +1,15 @@
 package typechecker_test
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/gearsdatapacks/libra/module"
 	utils "github.com/gearsdatapacks/libra/test_utils"
+	typechecker "github.com/gearsdatapacks/libra/type_checker"
+	"github.com/gearsdatapacks/libra/type_checker/types"
 )
 
 func TestIntegerLiteral(t *testing.T) {
@@ -141,6 +147,8 @@ func TestCastExpression(t *testing.T) {
 		"1.6 -> f32",
 		"true -> bool",
 		"false -> i32",
+		"(-3) -> string",
+		"1.5 -> string",
 	)
 }
 
@@ -367,6 +375,16 @@ let int2 = 92 -> Int.other`,
 mut circle = Shape.Circle { cx: 10, cy: 31, r: 5 }
 mut rectangle = Shape.Rectangle { x: 0, y: 0, w: 10, h: 5 }
 circle = rectangle`,
+
+		`@untagged
+union Bits { int: i32, float: f32 }
+let bits: Bits = 1.5 -> f32
+let int_bits = bits.int`,
+
+		`union Number { u8, i16, f32, f64 }
+mut num: Number = 7
+num = 1.5
+num = 300`,
 	)
 }
 
@@ -453,6 +471,116 @@ let next_day = (index + 1) -> WeekDay`,
 	)
 }
 
+func TestGenerators(t *testing.T) {
+	utils.MatchIrSnaps(t,
+		"@gen(derive_eq)\nstruct Point { x, y: i32 }",
+		"@gen(derive_hash)\nstruct Flags { bool, u8 }",
+		"@gen(derive_debug)\nstruct Name { first, last: string }",
+		`@gen(derive_eq)
+struct Empty
+let equal = Empty.eq(Empty)`,
+		"let derive_eq = 1\n@gen(derive_eq)\nstruct Size { w, h: i32 }",
+		"@gen(derive_debug)\nstruct Vec { x: i32, y: f32 }",
+		`fn derive_describe(ty: Type): fn(ty): string {
+	const name = "point"
+	if name == "point" {
+		return fn(value: ty): string { name }
+	}
+	return fn(_value: ty): string { "other" }
+}
+
+@gen(derive_describe)
+struct Point { x, y: i32 }
+let description = Point { x: 1, y: 2 }.describe()`,
+		`fn derive_origin(ty: Type): fn(): ty {
+	return fn(): ty { ty { x: 0, y: 0 } }
+}
+
+@gen(derive_origin)
+struct Point { x, y: i32 }
+let origin = Point.origin()`,
+	)
+}
+
+func TestBuilders(t *testing.T) {
+	utils.MatchIrSnaps(t,
+		`struct Counter { ~count: i32, total: i32 }
+fn (~Counter) add(): ~Counter { this.count = this.count + 1; this }
+fn (~Counter) finish(): Counter { this.total = this.count; this }
+let counter: ~Counter = Counter { count: 0, total: 0 }
+let finished = counter.add().finish()`,
+		`struct Point { x, y: f32 }
+let builder: ~Point = Point { x: 1, y: 2 }
+let point: Point = builder`,
+		`struct Items { ~list: i32[], len: i32 }
+fn (~Items) finish(): Items { this }
+fn Items.new(): ~Items { return Items { list: [], len: 0 } }
+fn first(): Items { let builder = Items.new(); builder.finish() }
+fn second(): Items { let builder = Items.new(); builder.finish() }`,
+		`struct Items { ~list: i32[], len: i32 }
+fn (~Items) finish(): Items { this }
+fn build(done: bool): Items {
+  mut builder: ~Items = Items { list: [], len: 0 }
+  if done {
+    let _items = builder.finish()
+    builder = Items { list: [], len: 1 }
+  } else {
+    let builder: ~Items = Items { list: [], len: 2 }
+    let _items = builder.finish()
+  }
+  builder.finish()
+}`,
+	)
+}
+
+func TestExplicitNumbers(t *testing.T) {
+	utils.MatchIrSnaps(t,
+		"explicit type Metres = f32; let distance: Metres = 10; distance + 5",
+		"explicit type Id = u32; let a: Id = 1; let b: Id = 2; a < b",
+	)
+}
+
+func TestTypeInfo(t *testing.T) {
+	utils.MatchIrSnaps(t,
+		"let size = i32.size",
+		"struct Point { x, y: f32 }; let name = Point.name; let align = Point.align",
+		"fn name_of(ty: Type): string { return ty.name }",
+		"fn is_struct(ty: Type): bool { return ty.kind == TypeKind.Struct }",
+		"@untagged\nunion Mixed { a: i64, b: u8[12] }\nlet size = Mixed.size\nlet align = Mixed.align",
+	)
+}
+
+func TestTargetSizes(t *testing.T) {
+	tests := []string{
+		"struct Handle { ptr: *i32, id: i32 }; let size = Handle.size",
+		"struct Pair { a: i32, b: i64 }; let size = Pair.size; let align = Pair.align",
+		"let size = string.size",
+	}
+
+	utils.MatchIrSnapsForTarget(t, "i686-unknown-linux-gnu", tests...)
+	utils.MatchIrSnapsForTarget(t, "wasm32-unknown-unknown", tests...)
+}
+
+func TestTargetFor(t *testing.T) {
+	tests := []types.TargetInfo{
+		{Triple: "x86_64-unknown-linux-gnu", PointerSize: 8, MaxAlignment: 8},
+		{Triple: "i686-unknown-linux-gnu", PointerSize: 4, MaxAlignment: 4},
+		{Triple: "i686-pc-windows-msvc", PointerSize: 4, MaxAlignment: 8},
+		{Triple: "wasm32-unknown-unknown", PointerSize: 4, MaxAlignment: 8},
+		{Triple: "mipsel-unknown-linux-gnu", PointerSize: 4, MaxAlignment: 8},
+		{Triple: "mips-unknown-linux-gnu", PointerSize: 4, MaxAlignment: 8, BigEndian: true},
+		{Triple: "powerpc-unknown-linux-gnu", PointerSize: 4, MaxAlignment: 8, BigEndian: true},
+		{Triple: "powerpc64le-unknown-linux-gnu", PointerSize: 8, MaxAlignment: 8},
+		{Triple: "powerpc64-unknown-linux-gnu", PointerSize: 8, MaxAlignment: 8, BigEndian: true},
+		{Triple: "s390x-unknown-linux-gnu", PointerSize: 8, MaxAlignment: 8, BigEndian: true},
+		{Triple: "aarch64_be-unknown-linux-gnu", PointerSize: 8, MaxAlignment: 8, BigEndian: true},
+	}
+
+	for _, expected := range tests {
+		utils.AssertEq(t, types.TargetFor(expected.Triple), expected)
+	}
+}
+
 func TestNumConversions(t *testing.T) {
 	utils.MatchIrSnaps(t,
 		"let byte: u8 = 10.0",
@@ -552,5 +680,128 @@ fn add(a, b: i32): i32 {
 "fn not_extern(): f32",
 "mut u: u32 = 3; mut i: i32 = 21; u + i",
 "mut u: u32 = 3; mut f: f16 = 2.1; u + f",
-	)
+"@gen(10)\nstruct Foo",
+"@gen(derive_eqq)\nstruct Foo",
+"@gen(i32)\nstruct Foo",
+`fn derive_zero(ty: Type): fn(): i32 {
+	mut zero = 0
+	return fn(): i32 { zero }
+}
+@gen(derive_zero)
+struct Foo`,
+`fn zero(): i32 { 0 }
+fn derive_zero(ty: Type): fn(): i32 {
+	let value = zero()
+	return fn(): i32 { value }
+}
+@gen(derive_zero)
+struct Foo`,
+`fn derive_zero(ty: Type): fn(): i32 {
+	const value = 0
+}
+@gen(derive_zero)
+struct Foo`,
+`fn derive_zero(ty: Type): fn(): i32 {
+	return fn(): string { "zero" }
+}
+@gen(derive_zero)
+struct Foo`,
+`fn derive_zero(_ty: Type): fn(): i32 {
+	return fn(): i32 { 0 }
+}
+let zero = derive_zero(i32)`,
+"@gen(derive_hash)\ntype Items = i32[]",
+"let not_struct: ~i32 = 1",
+`struct Items { ~list: i32[], len: i32 }
+let items = Items { list: [1, 2], len: 2 }
+let list = items.list`,
+`struct Items { ~list: i32[], len: i32 }
+fn (~Items) finish(): Items { this }
+let builder: ~Items = Items { list: [], len: 0 }
+let items = builder.finish()
+builder.finish()`,
+`struct Point { x, y: i32 }
+let point = Point { x: 1, y: 2 }
+let builder: ~Point = point`,
+`struct Point { x, y: i32 }
+let builder: ~Point = Point { x: 1, y: 2 }
+let point: Point = builder
+let x = builder.x`,
+`struct Items { ~list: i32[], len: i32 }
+fn (~Items) finish(): Items { this }
+fn build(done: bool): i32 {
+  let builder: ~Items = Items { list: [], len: 0 }
+  if done {
+    let _items = builder.finish()
+  }
+  builder.len
+}`,
+`struct Items { ~list: i32[], len: i32 }
+fn (~Items) finish(): Items { this }
+fn build(count: i32) {
+  let builder: ~Items = Items { list: [], len: 0 }
+  mut i = 0
+  while i < count {
+    let _items = builder.finish()
+    i += 1
+  }
+}`,
+"union Tie { i8, u8 }\nlet tie: Tie = 7",
+"explicit type Metres = f32; let distance: Metres = 10; let f: f32 = 1.5; distance + f",
+"@untagged\nunion Bits { int: i32, float: f32 }\nlet bits: Bits = 1 -> i32\nlet is_int = bits is i32",
+"fn f(ty: Type) { let fields = ty.field_count }",
+"@import_module env\nfn imported() {}",
+"@import_name imported_fn\nfn imported() {}",
+"@deprecated Use add\nfn plus(a, b: i32): i32 { a + b }\nlet sum = plus(1, 2)",
+"@deprecated\nstruct Old { x: i32 }\nlet old = Old { x: 1 }",
+"struct Point { x, y: i32 }\n@todo Check for overflow\nfn (Point) sum(): i32 { this.x + this.y }\nlet sum = Point { x: 1, y: 2 }.sum()",
+"struct Point { x: i32 }\n@todo\nfn Point.zero(): Point { Point { x: 0 } }\nlet zero = Point.zero()",
+		"fn main() { let unused = 1 }",
+		"fn main() { mut written = 1; written = 2 }",
+		"fn main() { mut value = 1; let copy = value; copy }",
+		"fn main() { mut list = [1, 2, 3]; list[0] = 4; let _ignored = 1 }",
+		"fn add(a, b: i32): i32 { a }\nfn main() { add(1, 2) }",
+		"fn helper() {}\nfn _hidden() {}\nfn main() {}",
+		"struct Unused\ntype Alias = i32\nstruct Used\nfn main() { let _value = Used }",
+		"let value = 1; let copy = valeu",
+		"struct Point { x, y: i32 }; let point: Piont = Point { x: 1, y: 2 }",
+		"struct Point { x, y: i32 }; let p = Point { x: 1, y: 2 }; let z = p.Y",
+		"struct Point { x, y: i32 }; let p = Point { x: 1, z: 2 }",
+		"enum Colour { Red, Green }; let colour = Colour.Gren",
+		"fn bump(count: i32) { count = count + 1 }",
+		"fn main() { let total = 0; total += 1 }",
+		"fn scale(mut factor: f32): f32 { factor }",
+		"fn main() { mut total = 0; total += 1; total++ }",
+		"fn main() { for item in [1, 2] {} }",
+	)
+}
+
+func TestReleaseDiagnostics(t *testing.T) {
+	options := typechecker.Options{Target: types.TargetFor(utils.DefaultTarget), Release: true}
+	utils.MatchTCErrorSnapsWithOptions(t, options,
+		"@todo\nfn unfinished() {}\nunfinished()",
+		"@todo Add fields\nstruct Unfinished\nlet value = Unfinished",
+	)
+}
+
+func TestUnusedImports(t *testing.T) {
+	dir := t.TempDir()
+	utils.AssertEq(t, os.Mkdir(filepath.Join(dir, "maths"), 0755), nil)
+	utils.AssertEq(t, os.WriteFile(filepath.Join(dir, "maths", "maths.lb"),
+		[]byte("pub fn add(a, b: i32): i32 { a + b }\npub fn sub(a, b: i32): i32 { a - b }\npub fn mul(a, b: i32): i32 { a * b }"), 0644), nil)
+	utils.AssertEq(t, os.WriteFile(filepath.Join(dir, "main.lb"),
+		[]byte("import \"maths\"\nimport \"maths\" as m\nimport { add, sub } from \"maths\"\n\nfn main() { let _sum = add(m.mul(2, 3), 1) }"), 0644), nil)
+
+	mod, diags := module.LoadWithOverlay(filepath.Join(dir, "main.lb"), module.Overlay{})
+	utils.AssertEq(t, len(diags), 0)
+	_, diags = typechecker.TypeCheck(mod, typechecker.Options{Target: types.TargetFor("x86_64-unknown-linux-gnu")}, diags)
+
+	messages := []string{}
+	for _, diag := range diags {
+		messages = append(messages, diag.Message)
+	}
+	utils.AssertEq(t, strings.Join(messages, "\n"), strings.Join([]string{
+		`Module "maths" is imported, but never used`,
+		`"sub" is imported, but never used`,
+	}, "\n"))
 }
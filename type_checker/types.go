@@ -31,7 +31,7 @@ func (t *typeChecker) typeFromExpr(expr ir.Expression, location text.Location) t
 func (t *typeChecker) lookupType(name string, location text.Location) types.Type {
 	symbol := t.symbols.Lookup(name)
 	if symbol == nil {
-		t.diagnostics.Report(diagnostics.UndefinedType(location, name))
+		t.diagnostics.Report(diagnostics.UndefinedType(location, name, t.symbolNames(true)))
 		return types.Invalid
 	}
 	if symbol.GetType() != types.RuntimeType {
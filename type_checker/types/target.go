@@ -0,0 +1,59 @@
+package types
+
+import (
+	"strings"
+)
+
+// Properties of the machine being compiled for, which affect the layout of types
+type TargetInfo struct {
+	Triple string
+	// The size of a pointer, in bytes
+	PointerSize int
+	// The largest alignment a primitive type has, in bytes. On some 32-bit
+	// targets, 8-byte values are only 4-byte aligned.
+	MaxAlignment int
+	// Whether the most significant byte of a value is stored first
+	BigEndian bool
+}
+
+func TargetFor(triple string) TargetInfo {
+	arch, _, _ := strings.Cut(triple, "-")
+	target := TargetInfo{
+		Triple:       triple,
+		PointerSize:  8,
+		MaxAlignment: 8,
+	}
+
+	switch arch {
+	case "i386", "i486", "i586", "i686":
+		target.PointerSize = 4
+		// Windows aligns 8-byte values to 8 bytes, even on 32-bit x86
+		if !strings.Contains(triple, "windows") {
+			target.MaxAlignment = 4
+		}
+	case "wasm32", "riscv32", "arm", "armv7", "thumbv7em", "mipsel":
+		target.PointerSize = 4
+	case "armeb", "mips", "powerpc", "sparc":
+		target.PointerSize = 4
+		target.BigEndian = true
+	case "aarch64_be", "mips64", "powerpc64", "s390x", "sparcv9", "sparc64":
+		target.BigEndian = true
+	}
+
+	return target
+}
+
+// An unsigned integer type the same size as a pointer
+func Usize(target TargetInfo) Type {
+	return Uint(target.PointerSize * 8)
+}
+
+func (t TargetInfo) IsWasm() bool {
+	arch, _, _ := strings.Cut(t.Triple, "-")
+	return arch == "wasm32" || arch == "wasm64"
+}
+
+// Whether the target is WebAssembly running under the WebAssembly System Interface
+func (t TargetInfo) IsWasi() bool {
+	return t.IsWasm() && strings.Contains(t.Triple, "wasi")
+}
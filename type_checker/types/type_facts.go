@@ -35,3 +35,8 @@ func IsString(ty Type) bool {
 	pt, ok := Unwrap(ty).(PrimaryType)
 	return ok && pt == String
 }
+
+func IsUntaggedUnion(ty Type) bool {
+	union, ok := Unwrap(ty).(*Union)
+	return ok && union.Untagged
+}
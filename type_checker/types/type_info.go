@@ -0,0 +1,244 @@
+package types
+
+import (
+	"github.com/gearsdatapacks/libra/type_checker/values"
+)
+
+// Values of type `Type` are handles into a table of type information, which
+// is emitted for each type used at runtime. These are the types of the
+// entries in that table, and the members which can be read from a `Type`.
+
+type TypeKind int
+
+const (
+	KindOther TypeKind = iota
+	KindVoid
+	KindBool
+	KindInt
+	KindUint
+	KindFloat
+	KindString
+	KindType
+	KindArray
+	KindList
+	KindMap
+	KindTuple
+	KindStruct
+	KindUnion
+	KindEnum
+	KindPointer
+	KindFunction
+	KindInterface
+	KindOption
+	KindResult
+)
+
+var kindNames = []string{
+	"Other",
+	"Void",
+	"Bool",
+	"Int",
+	"Uint",
+	"Float",
+	"String",
+	"Type",
+	"Array",
+	"List",
+	"Map",
+	"Tuple",
+	"Struct",
+	"Union",
+	"Enum",
+	"Pointer",
+	"Function",
+	"Interface",
+	"Option",
+	"Result",
+}
+
+var TypeKindEnum = func() *Enum {
+	enum := NewEnum("TypeKind", I32)
+	for kind, name := range kindNames {
+		enum.Members[name] = values.IntValue{Value: int64(kind)}
+	}
+	return enum
+}()
+
+var TypeField = infoStruct("TypeField",
+	StructField{Name: "name", Type: String},
+	StructField{Name: "type", Type: RuntimeType},
+	StructField{Name: "offset", Type: Int(64)},
+)
+
+var TypeVariant = infoStruct("TypeVariant",
+	StructField{Name: "name", Type: String},
+	StructField{Name: "type", Type: RuntimeType},
+)
+
+var TypeMethod = infoStruct("TypeMethod",
+	StructField{Name: "name", Type: String},
+	StructField{Name: "type", Type: RuntimeType},
+)
+
+// The layout of each entry in the type-info table
+var TypeInfo = infoStruct("TypeInfo",
+	StructField{Name: "name", Type: String},
+	StructField{Name: "size", Type: Int(64)},
+	StructField{Name: "align", Type: Int(64)},
+	StructField{Name: "kind", Type: TypeKindEnum},
+	StructField{Name: "fields", Type: &ListType{ElemType: TypeField}},
+	StructField{Name: "variants", Type: &ListType{ElemType: TypeVariant}},
+	StructField{Name: "methods", Type: &ListType{ElemType: TypeMethod}},
+)
+
+func infoStruct(name string, fields ...StructField) *Struct {
+	struc := &Struct{
+		Name:       name,
+		Fields:     map[string]StructField{},
+		FieldOrder: []string{},
+	}
+	for _, field := range fields {
+		field.Exported = true
+		struc.Fields[field.Name] = field
+		struc.FieldOrder = append(struc.FieldOrder, field.Name)
+	}
+	return struc
+}
+
+func KindOf(ty Type) TypeKind {
+	switch ty := Unwrap(ty).(type) {
+	case PrimaryType:
+		switch ty {
+		case Bool:
+			return KindBool
+		case String:
+			return KindString
+		case RuntimeType:
+			return KindType
+		}
+	case Numeric:
+		switch ty.Kind {
+		case NumInt:
+			return KindInt
+		case NumUint:
+			return KindUint
+		case NumFloat:
+			return KindFloat
+		}
+	case *UnitStruct:
+		if ty == Void {
+			return KindVoid
+		}
+		return KindStruct
+	case *Struct, *TupleStruct:
+		return KindStruct
+	case *ArrayType:
+		return KindArray
+	case *ListType:
+		return KindList
+	case *MapType:
+		return KindMap
+	case *TupleType:
+		return KindTuple
+	case *Union, *InlineUnion:
+		return KindUnion
+	case *Enum:
+		return KindEnum
+	case *Pointer:
+		return KindPointer
+	case *Function:
+		return KindFunction
+	case *Interface:
+		return KindInterface
+	case *Option:
+		return KindOption
+	case *Result:
+		return KindResult
+	}
+	return KindOther
+}
+
+// The alignment of a type in bytes, following the natural alignment used by C
+func Alignment(ty Type, target TargetInfo) int {
+	switch ty := Unwrap(ty).(type) {
+	case *Struct:
+		align := 1
+		for _, name := range ty.FieldOrder {
+			align = maxInt(align, Alignment(ty.Fields[name].Type, target))
+		}
+		return align
+	case *TupleStruct:
+		return maxAlignment(ty.Types, target)
+	case *TupleType:
+		return maxAlignment(ty.Types, target)
+	case *ArrayType:
+		return Alignment(ty.ElemType, target)
+	case *Union:
+		types := make([]Type, 0, len(ty.Members))
+		for _, member := range ty.Members {
+			types = append(types, member)
+		}
+		return maxAlignment(types, target)
+	case *Enum:
+		return Alignment(ty.Underlying, target)
+	}
+
+	size := ty.byteSize(target)
+	if size <= 0 {
+		return 1
+	}
+	return minInt(size, target.MaxAlignment)
+}
+
+func maxAlignment(types []Type, target TargetInfo) int {
+	align := 1
+	for _, ty := range types {
+		align = maxInt(align, Alignment(ty, target))
+	}
+	return align
+}
+
+// The offset of each field in a sequence of fields, with padding
+// inserted so that each field is correctly aligned
+func FieldOffsets(fields []Type, target TargetInfo) []int {
+	offsets := make([]int, 0, len(fields))
+	offset := 0
+	for _, field := range fields {
+		offset = alignTo(offset, Alignment(field, target))
+		offsets = append(offsets, offset)
+		offset += field.byteSize(target)
+	}
+	return offsets
+}
+
+// The size of a sequence of fields, including padding at the end so
+// that the next value in an array is also aligned
+func aggregateSize(fields []Type, target TargetInfo) int {
+	if len(fields) == 0 {
+		return 0
+	}
+	offsets := FieldOffsets(fields, target)
+	last := len(fields) - 1
+	return alignTo(offsets[last]+fields[last].byteSize(target), maxAlignment(fields, target))
+}
+
+func alignTo(offset, align int) int {
+	return (offset + align - 1) / align * align
+}
+
+// The compile-time value of a member of a `Type`, if it can be known
+func TypeInfoValue(ty Type, member string, target TargetInfo) values.ConstValue {
+	switch member {
+	case "name":
+		return values.StringValue{Value: ty.String()}
+	case "size":
+		return values.IntValue{Value: int64(ty.byteSize(target))}
+	case "align":
+		return values.IntValue{Value: int64(Alignment(ty, target))}
+	case "kind":
+		return values.IntValue{Value: int64(KindOf(ty))}
+	default:
+		// Lists have to be built at runtime
+		return nil
+	}
+}
@@ -4,20 +4,19 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"slices"
 
 	"github.com/gearsdatapacks/libra/colour"
 	"github.com/gearsdatapacks/libra/diagnostics"
 	"github.com/gearsdatapacks/libra/printer"
 	"github.com/gearsdatapacks/libra/type_checker/values"
-	"tinygo.org/x/go-llvm"
 )
 
 type Type interface {
 	printer.Printable
 	String() string
-	ToLlvm(llvm.Context) llvm.Type
 	valid(Type) bool
-	byteSize() int
+	byteSize(TargetInfo) int
 }
 
 var Context interface {
@@ -72,6 +71,8 @@ func Member(left Type, member string, constVal ...values.ConstValue) (Type, *dia
 			static, diag := sm.staticMember(member)
 			if diag == nil {
 				return static, nil
+			} else if field, ok := TypeInfo.Fields[member]; ok {
+				return field.Type, nil
 			} else {
 				return Invalid, diag
 			}
@@ -80,7 +81,19 @@ func Member(left Type, member string, constVal ...values.ConstValue) (Type, *dia
 		return method, nil
 	}
 
-	if hasMember, ok := Unwrap(left).(hasMembers); ok {
+	if left == RuntimeType {
+		if field, ok := TypeInfo.Fields[member]; ok {
+			return field.Type, nil
+		}
+	}
+
+	// Check the type itself first, as some containers (like builders)
+	// expose different members to the type they wrap
+	hasMember, ok := left.(hasMembers)
+	if !ok {
+		hasMember, ok = Unwrap(left).(hasMembers)
+	}
+	if ok {
 		ty, diag := hasMember.member(member)
 		if diag == nil {
 			return ty, nil
@@ -88,7 +101,42 @@ func Member(left Type, member string, constVal ...values.ConstValue) (Type, *dia
 		return Invalid, diag
 	}
 
-	return Invalid, diagnostics.NoMember(left, member)
+	return Invalid, diagnostics.NoMember(left, member, MemberNames(left))
+}
+
+// Lists the names of the fields of a type, or other members which can
+// be accessed with `.`, in the order they were declared where possible.
+// Methods are stored in the symbol table, so they are not included.
+func MemberNames(ty Type) []string {
+	switch ty := ty.(type) {
+	case *Struct:
+		return ty.FieldOrder
+	case *Builder:
+		if struc, ok := Unwrap(ty.Struct).(*Struct); ok {
+			return struc.FieldOrder
+		}
+		return nil
+	case *Pointer:
+		return MemberNames(ty.Underlying)
+	case *Interface:
+		return sortedKeys(ty.Methods)
+	case *Union:
+		return sortedKeys(ty.Members)
+	}
+
+	if _, ok := ty.(container); ok {
+		return MemberNames(Unwrap(ty))
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
 }
 
 func ToReal(ty Type) Type {
@@ -116,12 +164,12 @@ func Unwrap(ty Type) Type {
 	return ty
 }
 
-func ByteSize(ty Type) int {
-	return ty.byteSize()
+func ByteSize(ty Type, target TargetInfo) int {
+	return ty.byteSize(target)
 }
 
-func BitSize(ty Type) int {
-	return ty.byteSize() * 8
+func BitSize(ty Type, target TargetInfo) int {
+	return ty.byteSize(target) * 8
 }
 
 func bitsToBytes(bits int) int {
@@ -227,25 +275,7 @@ func (pt PrimaryType) GetEnumValue(
 	return values.StringValue{Value: name}, nil
 }
 
-func (pt PrimaryType) ToLlvm(context llvm.Context) llvm.Type {
-	switch pt {
-	case Invalid:
-		panic("Type should not be invalid at this point")
-	case Bool:
-		return context.Int1Type()
-	case String:
-		// TODO: Use proper strings, not cstrings
-		return llvm.PointerType(context.Int8Type(), 0)
-	case RuntimeType:
-		panic("TODO: Runtime types")
-	case Never:
-		panic("TODO: Never types")
-	default:
-		panic("Unreachable")
-	}
-}
-
-func (pt PrimaryType) byteSize() int {
+func (pt PrimaryType) byteSize(target TargetInfo) int {
 	switch pt {
 	case Bool:
 		return 1
@@ -254,10 +284,10 @@ func (pt PrimaryType) byteSize() int {
 	case Never:
 		return 0
 	case RuntimeType:
-		panic("TODO: Size of RuntimeType")
+		return target.PointerSize
 	case String:
 		// TODO: Make this not a cstring
-		return 8
+		return target.PointerSize
 	default:
 		panic("Unreachable")
 	}
@@ -318,6 +348,13 @@ func maxInt(a, b int) int {
 	return b
 }
 
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 func minUintWidth(u uint64) int {
 	if u <= math.MaxUint8 {
 		return 8
@@ -498,6 +535,11 @@ func (n Numeric) MaxValue() float64 {
 }
 
 func (from Numeric) castTo(t Type) CastKind {
+	// Numbers can be formatted as strings, e.g. for debug printing
+	if t == String {
+		return ExplicitCast
+	}
+
 	to, ok := t.(Numeric)
 	if !ok {
 		return NoCast
@@ -545,51 +587,7 @@ func (v Numeric) GetEnumValue(
 	return values.IntValue{Value: last.Value + 1}, nil
 }
 
-func (n Numeric) ToLlvm(context llvm.Context) llvm.Type {
-	switch n.Kind {
-	case NumFloat:
-		switch n.BitWidth {
-		case 16:
-			panic("TODO: F16 types")
-		case 32:
-			return context.FloatType()
-		case 64:
-			return context.DoubleType()
-		default:
-			panic("Invalid float bit-width")
-		}
-	case NumInt:
-		switch n.BitWidth {
-		case 8:
-			return context.Int8Type()
-		case 16:
-			return context.Int16Type()
-		case 32:
-			return context.Int32Type()
-		case 64:
-			return context.Int64Type()
-		default:
-			panic("Invalid int bit-width")
-		}
-	case NumUint:
-		switch n.BitWidth {
-		case 8:
-			return context.Int8Type()
-		case 16:
-			return context.Int16Type()
-		case 32:
-			return context.Int32Type()
-		case 64:
-			return context.Int64Type()
-		default:
-			panic("Invalid uint bit-width")
-		}
-	default:
-		panic("unreachable")
-	}
-}
-
-func (n Numeric) byteSize() int {
+func (n Numeric) byteSize(TargetInfo) int {
 	return bitsToBytes(n.BitWidth)
 }
 
@@ -628,13 +626,9 @@ func (l *ListType) Item() Type {
 	return l.ElemType
 }
 
-func (*ListType) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (l *ListType) byteSize() int {
+func (l *ListType) byteSize(target TargetInfo) int {
 	// len + cap + ptr
-	return 24
+	return 3 * target.PointerSize
 }
 
 type ArrayType struct {
@@ -699,12 +693,8 @@ func (a *ArrayType) Item() Type {
 	return a.ElemType
 }
 
-func (*ArrayType) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (a *ArrayType) byteSize() int {
-	return a.Length * a.ElemType.byteSize()
+func (a *ArrayType) byteSize(target TargetInfo) int {
+	return a.Length * a.ElemType.byteSize(target)
 }
 
 type MapType struct {
@@ -745,13 +735,9 @@ func (m *MapType) Item() Type {
 	return &TupleType{Types: []Type{m.KeyType, m.ValueType}}
 }
 
-func (*MapType) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (m *MapType) byteSize() int {
+func (m *MapType) byteSize(target TargetInfo) int {
 	// len + cap + ptr
-	return 24
+	return 3 * target.PointerSize
 }
 
 type TupleType struct {
@@ -811,16 +797,8 @@ func (a *TupleType) indexBy(t Type, constVals []values.ConstValue) (Type, *diagn
 	return a.Types[index], nil
 }
 
-func (*TupleType) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (t *TupleType) byteSize() int {
-	size := 0
-	for _, ty := range t.Types {
-		size += ty.byteSize()
-	}
-	return size
+func (t *TupleType) byteSize(target TargetInfo) int {
+	return aggregateSize(t.Types, target)
 }
 
 type Function struct {
@@ -875,13 +853,9 @@ func (fn *Function) valid(other Type) bool {
 	return Match(fn.ReturnType, function.ReturnType)
 }
 
-func (*Function) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (*Function) byteSize() int {
+func (*Function) byteSize(target TargetInfo) int {
 	// Just a pointer
-	return 8
+	return target.PointerSize
 }
 
 type Alias struct {
@@ -900,6 +874,7 @@ type StructField struct {
 	Name     string
 	Type     Type
 	Exported bool
+	Builder  bool
 }
 
 func (s StructField) Print(node *printer.Node) {
@@ -915,6 +890,11 @@ func (s StructField) Print(node *printer.Node) {
 			" %spub",
 			node.Colour(colour.Attribute),
 		).
+		TextIf(
+			s.Builder,
+			" %sbuilder",
+			node.Colour(colour.Attribute),
+		).
 		Node(s.Type)
 }
 
@@ -969,29 +949,28 @@ func (s *Struct) valid(other Type) bool {
 }
 
 func (s *Struct) member(member string) (Type, *diagnostics.Partial) {
+	return s.fieldType(member, false)
+}
+
+func (s *Struct) fieldType(member string, building bool) (Type, *diagnostics.Partial) {
 	if field, ok := s.Fields[member]; ok {
 		if s.ModuleId != Context.Id() && !field.Exported {
 			return Invalid, diagnostics.FieldPrivate(s, member)
 		}
+		if field.Builder && !building {
+			return Invalid, diagnostics.BuilderOnlyField(s, member)
+		}
 		return field.Type, nil
 	}
-	return Invalid, diagnostics.NoMember(s, member)
+	return Invalid, diagnostics.NoMember(s, member, MemberNames(s))
 }
 
-func (s *Struct) ToLlvm(context llvm.Context) llvm.Type {
-	types := make([]llvm.Type, 0, len(s.FieldOrder))
+func (s *Struct) byteSize(target TargetInfo) int {
+	fields := make([]Type, 0, len(s.FieldOrder))
 	for _, name := range s.FieldOrder {
-		types = append(types, s.Fields[name].Type.ToLlvm(context))
-	}
-	return llvm.StructType(types, false)
-}
-
-func (s *Struct) byteSize() int {
-	size := 0
-	for _, field := range s.Fields {
-		size += field.Type.byteSize()
+		fields = append(fields, s.Fields[name].Type)
 	}
-	return size
+	return aggregateSize(fields, target)
 }
 
 type TupleStruct struct {
@@ -1050,16 +1029,8 @@ func (a *TupleStruct) indexBy(t Type, constVals []values.ConstValue) (Type, *dia
 	return a.Types[index], nil
 }
 
-func (*TupleStruct) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (t *TupleStruct) byteSize() int {
-	size := 0
-	for _, ty := range t.Types {
-		size += ty.byteSize()
-	}
-	return size
+func (t *TupleStruct) byteSize(target TargetInfo) int {
+	return aggregateSize(t.Types, target)
 }
 
 type Interface struct {
@@ -1108,22 +1079,18 @@ func (i *Interface) member(member string) (Type, *diagnostics.Partial) {
 	if ty, ok := i.Methods[member]; ok {
 		return ty, nil
 	}
-	return Invalid, diagnostics.NoMember(i, member)
-}
-
-func (*Interface) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
+	return Invalid, diagnostics.NoMember(i, member, MemberNames(i))
 }
 
-func (*Interface) byteSize() int {
+func (*Interface) byteSize(TargetInfo) int {
 	panic("TODO")
 }
 
-// TODO: Untagged unions
 type Union struct {
-	Name    string
-	Id      int
-	Members map[string]Type
+	Name     string
+	Id       int
+	Members  map[string]Type
+	Untagged bool
 }
 
 var unionId = 0
@@ -1148,7 +1115,8 @@ func (u *Union) Print(node *printer.Node) {
 		node.Colour(colour.NodeName),
 		node.Colour(colour.Name),
 		u.Name,
-	)
+	).
+		TextIf(u.Untagged, " %suntagged", node.Colour(colour.Attribute))
 
 	printer.Map(node, u.Members)
 }
@@ -1156,34 +1124,82 @@ func (u *Union) Print(node *printer.Node) {
 func (u *Union) valid(other Type) bool {
 	if union, ok := other.(*Union); ok {
 		return u.Id == union.Id
-	} else {
-		canAssign := false
-		for _, ty := range u.Members {
-			if expl, ok := ty.(*Explicit); (ok && Assignable(expl.Type, other)) || Assignable(ty, other) {
-				// TODO: Prevent ambiguity of untyped numbers when assigned to a union
-				if canAssign {
-					// TODO: Make a proper error message for this
-					return false
-				}
-				canAssign = true
-			}
+	}
+	variant, _ := u.SelectVariant(other)
+	return variant != nil
+}
+
+// Finds the member of the union which a value of the given type is stored as.
+// If no member is suitable, or the choice is ambiguous, nil is returned along
+// with the members which could have been chosen.
+//
+// Untyped numbers can be assigned to many different members, so they are
+// given the member of their default type if possible, or otherwise the
+// smallest member which can hold their value.
+func (u *Union) SelectVariant(ty Type) (Type, []Type) {
+	candidates := []Type{}
+	for _, name := range u.MemberNames() {
+		member := u.Members[name]
+		if expl, ok := member.(*Explicit); (ok && Assignable(expl.Type, ty)) || Assignable(member, ty) {
+			candidates = append(candidates, member)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], candidates
+	}
+
+	num, ok := ty.(Numeric)
+	if len(candidates) == 0 || !ok || !num.Untyped() {
+		return nil, candidates
+	}
+
+	exact := []Type{}
+	for _, member := range candidates {
+		if memberNum, ok := Unwrap(member).(Numeric); ok &&
+			memberNum.Kind == num.Kind && memberNum.BitWidth == num.BitWidth {
+			exact = append(exact, member)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], exact
+	} else if len(exact) > 1 {
+		return nil, exact
+	}
+
+	smallest := []Type{}
+	smallestWidth := 0
+	for _, member := range candidates {
+		memberNum, ok := Unwrap(member).(Numeric)
+		if !ok {
+			continue
+		}
+		if len(smallest) == 0 || memberNum.BitWidth < smallestWidth {
+			smallest = []Type{member}
+			smallestWidth = memberNum.BitWidth
+		} else if memberNum.BitWidth == smallestWidth {
+			smallest = append(smallest, member)
 		}
-		return canAssign
 	}
+	if len(smallest) == 1 {
+		return smallest[0], smallest
+	} else if len(smallest) > 1 {
+		return nil, smallest
+	}
+	return nil, candidates
 }
 
 func (u *Union) member(member string) (Type, *diagnostics.Partial) {
 	if ty, ok := u.Members[member]; ok {
 		return ty, nil
 	}
-	return nil, diagnostics.NoVariant(u.Name, member)
+	return nil, diagnostics.NoVariant(u.Name, member, sortedKeys(u.Members))
 }
 
 func (u *Union) staticMember(member string) (Type, *diagnostics.Partial) {
 	if _, ok := u.Members[member]; ok {
 		return RuntimeType, nil
 	}
-	return nil, diagnostics.NoVariant(u.Name, member)
+	return nil, diagnostics.NoVariant(u.Name, member, sortedKeys(u.Members))
 }
 
 func (u *Union) StaticMemberValue(member string) values.ConstValue {
@@ -1200,17 +1216,28 @@ func (to *Union) castFrom(from Type) CastKind {
 	return NoCast
 }
 
-func (*Union) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
+func (u *Union) MemberNames() []string {
+	names := make([]string, 0, len(u.Members))
+	for name := range u.Members {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
 }
 
-func (u *Union) byteSize() int {
-	if len(u.Members) > 255 {
-		panic("TODO: More than 1-bit tags")
-	}
+func (u *Union) byteSize(target TargetInfo) int {
 	size := 0
 	for _, member := range u.Members {
-		size = maxInt(size, member.byteSize())
+		size = maxInt(size, member.byteSize(target))
+	}
+	if u.Untagged {
+		// Like in C, the size is padded to the alignment
+		// of the most strictly aligned member
+		return alignTo(size, Alignment(u, target))
+	}
+
+	if len(u.Members) > 255 {
+		panic("TODO: More than 1-bit tags")
 	}
 	// Add one for the tag size
 	return size + 1
@@ -1273,10 +1300,6 @@ func (to *UnionVariant) castFrom(from Type) CastKind {
 	return NoCast
 }
 
-func (*UnionVariant) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
 type InlineUnion struct {
 	Types []Type
 }
@@ -1338,17 +1361,13 @@ func MakeUnion(a, b Type) Type {
 	}
 }
 
-func (*InlineUnion) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (u *InlineUnion) byteSize() int {
+func (u *InlineUnion) byteSize(target TargetInfo) int {
 	if len(u.Types) > 255 {
 		panic("TODO: More than 1-bit tags")
 	}
 	size := 0
 	for _, member := range u.Types {
-		size = maxInt(size, member.byteSize())
+		size = maxInt(size, member.byteSize(target))
 	}
 	// Add one for the tag size
 	return size + 1
@@ -1358,6 +1377,7 @@ type Module struct {
 	Name   string
 	Module interface {
 		LookupExportType(string) Type
+		ExportNames() []string
 	}
 }
 
@@ -1382,14 +1402,10 @@ func (m *Module) member(member string) (Type, *diagnostics.Partial) {
 	if ty := m.Module.LookupExportType(member); ty != nil {
 		return ty, nil
 	}
-	return Invalid, diagnostics.NoMember(m, member)
-}
-
-func (*Module) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
+	return Invalid, diagnostics.NoMember(m, member, m.Module.ExportNames())
 }
 
-func (m *Module) byteSize() int {
+func (m *Module) byteSize(TargetInfo) int {
 	panic("TODO")
 }
 
@@ -1433,13 +1449,8 @@ func (p *Pointer) member(member string) (Type, *diagnostics.Partial) {
 	return Member(p.Underlying, member)
 }
 
-func (p *Pointer) ToLlvm(context llvm.Context) llvm.Type {
-	return llvm.PointerType(p.Underlying.ToLlvm(context), 0)
-}
-
-func (p *Pointer) byteSize() int {
-	// TODO: target-specific pointer size
-	return 8
+func (p *Pointer) byteSize(target TargetInfo) int {
+	return target.PointerSize
 }
 
 type Explicit struct {
@@ -1535,11 +1546,7 @@ func (u *UnitStruct) valid(other Type) bool {
 	return false
 }
 
-func (*UnitStruct) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (*UnitStruct) byteSize() int {
+func (*UnitStruct) byteSize(TargetInfo) int {
 	return 0
 }
 
@@ -1588,10 +1595,6 @@ func (t *Tag) valid(other Type) bool {
 	return false
 }
 
-func (*Tag) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
 func (to *Tag) castFrom(from Type) CastKind {
 	if Assignable(to, from) {
 		return IdentityCast
@@ -1599,7 +1602,7 @@ func (to *Tag) castFrom(from Type) CastKind {
 	return NoCast
 }
 
-func (*Tag) byteSize() int {
+func (*Tag) byteSize(TargetInfo) int {
 	panic("TODO")
 }
 
@@ -1635,12 +1638,8 @@ func (r *Result) valid(other Type) bool {
 	return Assignable(r.OkType, other)
 }
 
-func (*Result) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (r *Result) byteSize() int {
-	return maxInt(ErrorTag.byteSize(), r.OkType.byteSize()) + 1
+func (r *Result) byteSize(target TargetInfo) int {
+	return maxInt(ErrorTag.byteSize(target), r.OkType.byteSize(target)) + 1
 }
 
 type Option struct {
@@ -1670,13 +1669,57 @@ func (r *Option) valid(other Type) bool {
 	return Assignable(r.SomeType, other)
 }
 
-func (*Option) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
+func (o *Option) byteSize(target TargetInfo) int {
+	// void is zero-size so it's always the size of the some type
+	return o.SomeType.byteSize(target) + 1
 }
 
-func (o *Option) byteSize() int {
-	// void is zero-size so it's always the size of the some type
-	return o.byteSize() + 1
+// A struct which is still being built. It can access builder-only fields,
+// and implicitly converts to the underlying type, which finalises it.
+// Finished structs can't be converted back into builders; only struct
+// literals can be used to start building.
+type Builder struct {
+	Struct Type
+}
+
+func (b *Builder) String() string {
+	return "~" + b.Struct.String()
+}
+
+func (b *Builder) Print(node *printer.Node) {
+	node.
+		Text(
+			"%sBUILDER_TYPE",
+			node.Colour(colour.NodeName),
+		).
+		Node(b.Struct)
+}
+
+func (b *Builder) valid(other Type) bool {
+	builder, ok := other.(*Builder)
+	return ok && Match(b.Struct, builder.Struct)
+}
+
+func (b *Builder) member(member string) (Type, *diagnostics.Partial) {
+	if struc, ok := Unwrap(b.Struct).(*Struct); ok {
+		return struc.fieldType(member, true)
+	}
+	return Invalid, diagnostics.NoMember(b, member, MemberNames(b))
+}
+
+func (b *Builder) unwrap() Type {
+	return Unwrap(b.Struct)
+}
+
+func (from *Builder) castTo(to Type) CastKind {
+	if Assignable(to, from.Struct) {
+		return ImplicitCast
+	}
+	return NoCast
+}
+
+func (b *Builder) byteSize(target TargetInfo) int {
+	return b.Struct.byteSize(target)
 }
 
 type Enum struct {
@@ -1735,7 +1778,7 @@ func (e *Enum) staticMember(member string) (Type, *diagnostics.Partial) {
 	if _, ok := e.Members[member]; ok {
 		return e, nil
 	}
-	return nil, diagnostics.NoEnumMember(e.Name, member)
+	return nil, diagnostics.NoEnumMember(e.Name, member, sortedKeys(e.Members))
 }
 
 func (e *Enum) StaticMemberValue(member string) values.ConstValue {
@@ -1756,12 +1799,8 @@ func (to *Enum) castFrom(from Type) CastKind {
 	return NoCast
 }
 
-func (*Enum) ToLlvm(llvm.Context) llvm.Type {
-	panic("TODO")
-}
-
-func (*Enum) byteSize() int {
-	panic("TODO")
+func (e *Enum) byteSize(target TargetInfo) int {
+	return e.Underlying.byteSize(target)
 }
 
 type pseudo interface {
@@ -0,0 +1,153 @@
+package typechecker
+
+import (
+	"strings"
+
+	"github.com/gearsdatapacks/libra/diagnostics"
+	"github.com/gearsdatapacks/libra/parser/ast"
+	"github.com/gearsdatapacks/libra/type_checker/ir"
+	"github.com/gearsdatapacks/libra/type_checker/symbols"
+	"github.com/gearsdatapacks/libra/type_checker/types"
+)
+
+// Records that the variable an expression refers to is modified,
+// for example by assigning to one of its fields
+func (t *typeChecker) markWritten(expr ir.Expression) {
+	switch e := expr.(type) {
+	case *ir.VariableExpression:
+		t.symbols.MarkWritten(e.Symbol.Name)
+	case *ir.IndexExpression:
+		t.markWritten(e.Left)
+	case *ir.MemberExpression:
+		t.markWritten(e.Left)
+	}
+}
+
+// Unused names can be prefixed with an underscore to silence warnings
+func ignoreUnused(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// Reports the variables and parameters in the current scope which
+// are never read, and mutable ones which are never modified
+func (t *typeChecker) reportUnusedVariables() {
+	for _, symbol := range t.symbols.Declared() {
+		variable, ok := symbol.(*symbols.Variable)
+		// Variables created by the compiler, like `this`, don't have a location
+		if !ok || variable.Location.File == nil || ignoreUnused(variable.Name) {
+			continue
+		}
+
+		usage := t.symbols.Usage(variable.Name)
+		if !usage.Read && !usage.Written {
+			t.diagnostics.Report(diagnostics.UnusedVariable(variable.Location, variable.Name))
+		} else if !usage.Read {
+			t.diagnostics.Report(diagnostics.UnreadVariable(variable.Location, variable.Name))
+		} else if variable.IsMut && !usage.Written {
+			fix := diagnostics.MakeImmutable(variable.Name, variable.Keyword, variable.Location, variable.Parameter)
+			t.diagnostics.Report(diagnostics.UnnecessaryMut(variable.Location, variable.Name, fix))
+		}
+	}
+}
+
+// Reports the imports, private functions and private types of a
+// module which are never used. Like in the scopes of functions,
+// variables declared at the top level are local to the module, but
+// they aren't checked as they may be used by the program's `main`.
+func (t *typeChecker) reportUnusedDeclarations() {
+	unused := func(name string) bool {
+		return !ignoreUnused(name) && !t.symbols.Usage(name).Read
+	}
+
+	for _, file := range t.module.Files {
+		for _, statement := range file.Ast.Statements {
+			switch stmt := statement.(type) {
+			case *ast.FunctionDeclaration:
+				// External functions may be called from other languages
+				if stmt.MethodOf == nil && stmt.MemberOf == nil && !stmt.Exported &&
+					stmt.Extern == nil && stmt.Name != "main" && unused(stmt.Name) {
+					t.diagnostics.Report(diagnostics.UnusedFunction(stmt.NameLocation, stmt.Name))
+				}
+
+			// Types in a tag can be used through the tag, so they are never reported
+			case *ast.TypeDeclaration:
+				if !stmt.Exported && stmt.Tag == nil && unused(stmt.Name) {
+					t.diagnostics.Report(diagnostics.UnusedType(stmt.Location, stmt.Name))
+				}
+			case *ast.StructDeclaration:
+				if !stmt.Exported && stmt.Tag == nil && unused(stmt.Name) {
+					t.diagnostics.Report(diagnostics.UnusedType(stmt.NameLocation, stmt.Name))
+				}
+			case *ast.UnionDeclaration:
+				if !stmt.Exported && stmt.Tag == nil && unused(stmt.Name) {
+					t.diagnostics.Report(diagnostics.UnusedType(stmt.Location, stmt.Name))
+				}
+			case *ast.EnumDeclaration:
+				if !stmt.Exported && stmt.Tag == nil && unused(stmt.Name) {
+					t.diagnostics.Report(diagnostics.UnusedType(stmt.Location, stmt.Name))
+				}
+			case *ast.InterfaceDeclaration:
+				if !stmt.Exported && unused(stmt.Name) {
+					t.diagnostics.Report(diagnostics.UnusedType(stmt.Location, stmt.Name))
+				}
+			case *ast.TagDeclaration:
+				if !stmt.Exported && unused(stmt.Name) {
+					t.diagnostics.Report(diagnostics.UnusedType(stmt.Location, stmt.Name))
+				}
+
+			case *ast.ImportStatement:
+				t.reportUnusedImport(stmt, unused)
+			}
+		}
+	}
+}
+
+func (t *typeChecker) reportUnusedImport(importStmt *ast.ImportStatement, unused func(string) bool) {
+	module, ok := t.subModules[importStmt.Module.ExtraValue]
+	if !ok {
+		return
+	}
+
+	if importStmt.All {
+		// Methods don't need to be named to be used,
+		// so their uses can't be tracked
+		if module.symbols.ExportsMethods() {
+			return
+		}
+		for _, export := range module.symbols.Exports() {
+			if !unused(export.GetName()) {
+				return
+			}
+		}
+		t.diagnostics.Report(diagnostics.UnusedImport(importStmt.Location, module.module.Name))
+	} else if importStmt.Symbols != nil {
+		for _, symbol := range importStmt.Symbols {
+			if module.symbols.LookupExport(symbol.Name) != nil && unused(symbol.Name) {
+				t.diagnostics.Report(diagnostics.UnusedImportedSymbol(symbol.Location, symbol.Name))
+			}
+		}
+	} else {
+		name := module.module.Name
+		if importStmt.Alias != nil {
+			name = *importStmt.Alias
+		}
+		if unused(name) {
+			t.diagnostics.Report(diagnostics.UnusedImport(importStmt.Location, module.module.Name))
+		}
+	}
+}
+
+// Methods may take a mutable reference to their receiver, so calling
+// a method on a variable is assumed to modify it
+func (t *typeChecker) markMethodCall(callee ir.Expression) {
+	member, ok := callee.(*ir.MemberExpression)
+	if !ok {
+		return
+	}
+	if _, isModule := member.Left.Type().(*types.Module); isModule {
+		return
+	}
+	if _, isFunction := member.DataType.(*types.Function); isFunction {
+		t.markWritten(member.Left)
+	}
+}
@@ -3,6 +3,8 @@ package values
 import (
 	"hash/fnv"
 	"math"
+	"strconv"
+	"strings"
 
 	"github.com/gearsdatapacks/libra/colour"
 	"github.com/gearsdatapacks/libra/printer"
@@ -292,6 +294,8 @@ func NumericValue(v ConstValue) float64 {
 		return val.Value
 	case IntValue:
 		return float64(val.Value)
+	case UintValue:
+		return float64(val.Value)
 	case BoolValue:
 		if val.Value {
 			return 1
@@ -301,3 +305,22 @@ func NumericValue(v ConstValue) float64 {
 		panic("Not a numeric value")
 	}
 }
+
+// Formats a number the same way it would be written in source code
+func FormatNumber(v ConstValue) string {
+	switch val := v.(type) {
+	case IntValue:
+		return strconv.FormatInt(val.Value, 10)
+	case UintValue:
+		return strconv.FormatUint(val.Value, 10)
+	case FloatValue:
+		formatted := strconv.FormatFloat(val.Value, 'g', -1, 64)
+		// Whole numbers are written with a decimal point, so they read as floats
+		if !strings.ContainsAny(formatted, ".eIN") {
+			formatted += ".0"
+		}
+		return formatted
+	default:
+		panic("Not a numeric value")
+	}
+}